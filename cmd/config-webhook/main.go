@@ -0,0 +1,60 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"net/http"
+	"time"
+
+	logger "github.com/containers/nri-plugins/pkg/log"
+)
+
+var (
+	log = logger.Default()
+)
+
+func main() {
+	var (
+		listen   string
+		certFile string
+		keyFile  string
+	)
+
+	flag.StringVar(&listen, "listen", ":8443",
+		"address to serve admission requests on")
+	flag.StringVar(&certFile, "tls-cert-file", "/etc/config-webhook/tls/tls.crt",
+		"TLS certificate to serve admission requests with")
+	flag.StringVar(&keyFile, "tls-key-file", "/etc/config-webhook/tls/tls.key",
+		"TLS private key to serve admission requests with")
+	flag.Parse()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/validate", serveValidate)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	srv := &http.Server{
+		Addr:              listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Infof("serving admission requests on %s...", listen)
+	if err := srv.ListenAndServeTLS(certFile, keyFile); err != nil {
+		log.Fatalf("failed to serve admission requests: %v", err)
+	}
+}
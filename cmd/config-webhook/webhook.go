@@ -0,0 +1,119 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1"
+)
+
+const (
+	// maxRequestSize is the maximum size of an admission review we accept.
+	maxRequestSize = 3 * 1024 * 1024
+)
+
+// newConfig returns a new, empty configuration object of the given kind.
+func newConfig(kind string) (cfgapi.Validator, bool) {
+	switch kind {
+	case "BalloonsPolicy":
+		return &cfgapi.BalloonsPolicy{}, true
+	case "TopologyAwarePolicy":
+		return &cfgapi.TopologyAwarePolicy{}, true
+	case "TemplatePolicy":
+		return &cfgapi.TemplatePolicy{}, true
+	}
+	return nil, false
+}
+
+// validate validates the object of an admission request.
+func validate(req *admissionv1.AdmissionRequest) error {
+	if req.Operation == admissionv1.Delete {
+		return nil
+	}
+
+	cfg, ok := newConfig(req.Kind.Kind)
+	if !ok {
+		return fmt.Errorf("unsupported kind %s", req.Kind)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(req.Object.Raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", req.Kind.Kind, err)
+	}
+
+	return cfg.Validate()
+}
+
+// review returns the response to an admission review request.
+func review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	rpl := &admissionv1.AdmissionResponse{
+		UID:     req.UID,
+		Allowed: true,
+	}
+
+	if err := validate(req); err != nil {
+		log.Infof("rejecting %s %s/%s: %v", req.Kind.Kind, req.Namespace, req.Name, err)
+		rpl.Allowed = false
+		rpl.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: err.Error(),
+			Reason:  metav1.StatusReasonInvalid,
+			Code:    http.StatusUnprocessableEntity,
+		}
+	}
+
+	return rpl
+}
+
+// serveValidate handles admission review requests.
+func serveValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, "failed to read request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ar := &admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(data, ar); err != nil {
+		http.Error(w, "failed to decode admission review: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ar.Request == nil {
+		http.Error(w, "admission review without a request", http.StatusBadRequest)
+		return
+	}
+
+	ar.Response = review(ar.Request)
+	ar.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ar); err != nil {
+		log.Errorf("failed to send admission review response: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestServeValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		kind    string
+		op      admissionv1.Operation
+		object  string
+		allowed bool
+	}{
+		{
+			name: "valid balloons policy",
+			kind: "BalloonsPolicy",
+			object: `{"spec": {
+			  "reservedResources": {"cpu": "cpuset:0"},
+			  "availableResources": {"cpu": "cpuset:0-7"},
+			  "balloonTypes": [
+			    {"name": "a", "minCPUs": 1, "maxCPUs": 2,
+			     "matchExpressions": [{"key": "name", "operator": "Equals", "values": ["a"]}]},
+			    {"name": "b"}
+			  ]}}`,
+			allowed: true,
+		},
+		{
+			name: "duplicate balloon type names",
+			kind: "BalloonsPolicy",
+			object: `{"spec": {
+			  "reservedResources": {"cpu": "1"},
+			  "balloonTypes": [{"name": "a"}, {"name": "a"}]}}`,
+		},
+		{
+			name: "minCPUs exceeding maxCPUs",
+			kind: "BalloonsPolicy",
+			object: `{"spec": {
+			  "reservedResources": {"cpu": "1"},
+			  "balloonTypes": [{"name": "a", "minCPUs": 4, "maxCPUs": 2}]}}`,
+		},
+		{
+			name: "invalid match expression",
+			kind: "BalloonsPolicy",
+			object: `{"spec": {
+			  "reservedResources": {"cpu": "1"},
+			  "balloonTypes": [{"name": "a",
+			    "matchExpressions": [{"key": "name", "operator": "Equals", "values": ["a", "b"]}]}]}}`,
+		},
+		{
+			name:   "unknown field",
+			kind:   "BalloonsPolicy",
+			object: `{"spec": {"reservedResources": {"cpu": "1"}, "balloonType": []}}`,
+		},
+		{
+			name:    "valid topology-aware policy",
+			kind:    "TopologyAwarePolicy",
+			object:  `{"spec": {"reservedResources": {"cpu": "750m"}}}`,
+			allowed: true,
+		},
+		{
+			name: "reserved CPUs outside available ones",
+			kind: "TopologyAwarePolicy",
+			object: `{"spec": {
+			  "availableResources": {"cpu": "cpuset:2-7"},
+			  "reservedResources": {"cpu": "cpuset:0-1"}}}`,
+		},
+		{
+			name:   "missing CPU reservation",
+			kind:   "TopologyAwarePolicy",
+			object: `{"spec": {"reservedResources": {}}}`,
+		},
+		{
+			name:   "malformed template policy reservation",
+			kind:   "TemplatePolicy",
+			object: `{"spec": {"reservedResources": {"cpu": "cpuset:x"}}}`,
+		},
+		{
+			name:    "deletion",
+			kind:    "TopologyAwarePolicy",
+			op:      admissionv1.Delete,
+			object:  `{}`,
+			allowed: true,
+		},
+		{
+			name:   "unsupported kind",
+			kind:   "Pod",
+			object: `{}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			op := tc.op
+			if op == "" {
+				op = admissionv1.Create
+			}
+			ar := &admissionv1.AdmissionReview{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "admission.k8s.io/v1",
+					Kind:       "AdmissionReview",
+				},
+				Request: &admissionv1.AdmissionRequest{
+					UID:       "test-uid",
+					Kind:      metav1.GroupVersionKind{Group: "config.nri", Version: "v1alpha1", Kind: tc.kind},
+					Operation: op,
+					Object:    runtime.RawExtension{Raw: []byte(tc.object)},
+				},
+			}
+			data, err := json.Marshal(ar)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			serveValidate(w, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(data)))
+			require.Equal(t, http.StatusOK, w.Code)
+
+			rpl := &admissionv1.AdmissionReview{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), rpl))
+			require.NotNil(t, rpl.Response)
+			require.Equal(t, ar.Request.UID, rpl.Response.UID)
+			require.Equal(t, tc.allowed, rpl.Response.Allowed)
+			if !tc.allowed {
+				require.NotEmpty(t, rpl.Response.Result.Message)
+			}
+		})
+	}
+}
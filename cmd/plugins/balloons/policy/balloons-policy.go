@@ -18,7 +18,9 @@ import (
 	"fmt"
 	"math"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"time"
 
 	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/policy/balloons"
 	"github.com/containers/nri-plugins/pkg/cpuallocator"
@@ -27,12 +29,14 @@ import (
 	"github.com/containers/nri-plugins/pkg/resmgr/cache"
 	cpucontrol "github.com/containers/nri-plugins/pkg/resmgr/control/cpu"
 	"github.com/containers/nri-plugins/pkg/resmgr/events"
+	"github.com/containers/nri-plugins/pkg/resmgr/introspect"
 	libmem "github.com/containers/nri-plugins/pkg/resmgr/lib/memory"
 	policy "github.com/containers/nri-plugins/pkg/resmgr/policy"
 	"github.com/containers/nri-plugins/pkg/utils"
 	"github.com/containers/nri-plugins/pkg/utils/cpuset"
 	idset "github.com/intel/goresctrl/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -46,9 +50,9 @@ const (
 	// hideHyperthreadsKey is a pod annotation key for pod/container-specific hyperthread allowance.
 	hideHyperthreadsKey = "hide-hyperthreads." + kubernetes.ResmgrKeyNamespace
 	// reservedBalloonDefName is the name in the reserved balloon definition.
-	reservedBalloonDefName = "reserved"
+	reservedBalloonDefName = cfgapi.ReservedBalloonDefName
 	// defaultBalloonDefName is the name in the default balloon definition.
-	defaultBalloonDefName = "default"
+	defaultBalloonDefName = cfgapi.DefaultBalloonDefName
 	// NoLimit value denotes no limit being set.
 	NoLimit = 0
 	// virtDevReservedCpus is the name of a virtual device close to
@@ -63,6 +67,18 @@ const (
 	// virtDevPCores is the name of a virtual device close to
 	// high performance cores.
 	virtDevPCores = "performance cores"
+	// balloonZoneType is the type of balloon instances in topology zones.
+	balloonZoneType = "balloon"
+	// exportBalloon is the exported variable for the name of the balloon.
+	exportBalloon = "BALLOON"
+	// exportBalloonType is the exported variable for the type of the balloon.
+	exportBalloonType = "BALLOON_TYPE"
+	// exportBalloonCpus is the exported variable for the CPUs of the balloon.
+	exportBalloonCpus = "BALLOON_CPUS"
+	// exportBalloonMems is the exported variable for the memory nodes of the balloon.
+	exportBalloonMems = "BALLOON_MEMS"
+	// exportSharedIdleCpus is the exported variable for the shared idle CPUs.
+	exportSharedIdleCpus = "SHARED_IDLE_CPUS"
 )
 
 // balloons contains configuration and runtime attributes of the balloons policy
@@ -81,6 +97,7 @@ type balloons struct {
 
 	cpuAllocator cpuallocator.CPUAllocator // CPU allocator used by the policy
 	memAllocator *libmem.Allocator         // memory allocator used by the policy
+	resizer      *dynamicResizer           // usage-driven balloon resizing, if enabled
 }
 
 // Balloon contains attributes of a balloon instance
@@ -109,6 +126,7 @@ type Balloon struct {
 	Groups       map[string]int
 	cpuTreeAlloc *cpuTreeAllocator
 	memTypeMask  libmem.TypeMask
+	usage        *balloonUsage
 }
 
 var log logger.Logger = logger.NewLogger("policy")
@@ -208,6 +226,7 @@ func (p *balloons) Description() string {
 // Start prepares this policy for accepting allocation/release requests.
 func (p *balloons) Start() error {
 	log.Info("%s policy started", PolicyName)
+	p.startDynamicResizing()
 	return nil
 }
 
@@ -232,25 +251,17 @@ func (p *balloons) Sync(add []cache.Container, del []cache.Container) error {
 
 // AllocateResources is a resource allocation request for this policy.
 func (p *balloons) AllocateResources(c cache.Container) error {
-	if c.PreserveCpuResources() {
-		log.Infof("not handling resources of container %s, preserving CPUs %q and memory %q", c.PrettyName(), c.GetCpusetCpus(), c.GetCpusetMems())
+	if p.preservesContainer(c) {
 		return nil
 	}
 
-	if p.bpoptions.Preserve != nil {
-		rule, err := p.bpoptions.Preserve.MatchContainer(c)
-		if err != nil {
-			log.Errorf("error in matching container %s to preserve conditions: %s", c, err)
-		} else if rule != "" {
-			log.Debugf("preserve container %s due to matching %s", c, rule)
-			return nil
-		}
-	}
-
 	log.Debug("allocating resources for container %s (request %d mCPU, limit %d mCPU)...",
 		c.PrettyName(),
 		p.containerRequestedMilliCpus(c.GetID()),
 		p.containerLimitedMilliCpus(c.GetID()))
+	if hints := c.GetTopologyHints(); len(hints) > 0 {
+		log.Debug("- topology hints of container %s: %v", c.PrettyName(), hints)
+	}
 	bln, err := p.allocateBalloon(c)
 	if err != nil {
 		return balloonsError("balloon allocation for container %s failed: %w", c.PrettyName(), err)
@@ -268,7 +279,7 @@ func (p *balloons) AllocateResources(c cache.Container) error {
 	// would mean no CPU pinning and balloon's containers would
 	// run on any CPUs.
 	if bln.AvailMilliCpus() < max(1, reqMilliCpus) {
-		if err := p.resizeBalloon(bln, max(1, reqMilliCpus)); err != nil {
+		if err := p.resizeBalloon(bln, max(1, reqMilliCpus), p.containerHintCpus(c)); err != nil {
 			return balloonsError("resizing balloon %s failed: %w", bln.PrettyName(), err)
 		}
 	}
@@ -279,6 +290,155 @@ func (p *balloons) AllocateResources(c cache.Container) error {
 	return nil
 }
 
+// preservesContainer returns true if the policy does not handle the CPUs
+// and memory of the container.
+func (p *balloons) preservesContainer(c cache.Container) bool {
+	if c.PreserveCpuResources() {
+		log.Infof("not handling resources of container %s, preserving CPUs %q and memory %q", c.PrettyName(), c.GetCpusetCpus(), c.GetCpusetMems())
+		return true
+	}
+
+	if p.bpoptions.Preserve != nil {
+		rule, err := p.bpoptions.Preserve.MatchContainer(c)
+		if err != nil {
+			log.Errorf("error in matching container %s to preserve conditions: %s", c, err)
+		} else if rule != "" {
+			log.Debugf("preserve container %s due to matching %s", c, rule)
+			return true
+		}
+	}
+
+	return false
+}
+
+// AdoptResources assigns a running container to a balloon of its type
+// with the current CPUs of the container. If necessary, a balloon is
+// inflated with free CPUs, or a new balloon is created, to match them.
+func (p *balloons) AdoptResources(c cache.Container) error {
+	if p.preservesContainer(c) {
+		return nil
+	}
+
+	cpus, err := cpuset.Parse(c.GetCpusetCpus())
+	if err != nil || cpus.IsEmpty() {
+		return balloonsError("invalid cpuset %q of container %s", c.GetCpusetCpus(), c.PrettyName())
+	}
+
+	blnDef, match, err := p.matchBalloonDef(c, nil)
+	if err != nil {
+		return balloonsError("failed to choose balloon type for container %s: %w", c.PrettyName(), err)
+	}
+	if blnDef == nil {
+		return balloonsError("no applicable balloon type found for container %s", c.PrettyName())
+	}
+
+	bln, err := p.adoptBalloon(c, blnDef, cpus)
+	if err != nil {
+		return balloonsError("cannot adopt CPUs %q of container %s: %w", cpus, c.PrettyName(), err)
+	}
+
+	d := cache.NewDecision(PolicyName)
+	d.Match = match
+	d.Choose(bln.PrettyName(), "adopted current CPUs "+cpus.String())
+	d.Reason = "balloon type " + blnDef.Name + ", adopted on restart"
+	c.SetDecision(d)
+
+	p.assignContainer(c, bln)
+	if log.DebugEnabled() {
+		log.Debug(p.dumpBalloon(bln))
+	}
+	return nil
+}
+
+// adoptBalloon returns a balloon of the given type which runs a container
+// on exactly the given CPUs. It looks for a balloon which already has some
+// of the CPUs, inflating it with the rest if they are free. If there is no
+// such balloon, it creates a new balloon with the CPUs if they are free.
+func (p *balloons) adoptBalloon(c cache.Container, blnDef *BalloonDef, cpus cpuset.CPUSet) (*Balloon, error) {
+	reqMilliCpus := p.containerRequestedMilliCpus(c.GetID())
+
+	// pinnedCpus returns the CPUs the container would be pinned to in a balloon.
+	pinnedCpus := func(bln *Balloon, blnCpus, idleCpus cpuset.CPUSet) cpuset.CPUSet {
+		pinnable := blnCpus.Union(idleCpus)
+		if runWithoutHyperthreads(c, bln) {
+			return p.cpuTree.system().SingleThreadForCPUs(pinnable)
+		}
+		return pinnable
+	}
+
+	for _, bln := range p.balloonsByDef(blnDef) {
+		if bln.Cpus.Intersection(cpus).IsEmpty() {
+			continue
+		}
+		addCpus := cpus.Difference(bln.Cpus).Difference(bln.SharedIdleCpus)
+		if !addCpus.IsSubsetOf(p.freeCpus) {
+			continue
+		}
+		newCpus := bln.Cpus.Union(addCpus)
+		if bln.Def.MaxCpus > NoLimit && newCpus.Size() > bln.Def.MaxCpus {
+			continue
+		}
+		if newCpus.Size()*1000-p.requestedMilliCpus(bln) < reqMilliCpus {
+			continue
+		}
+		if !pinnedCpus(bln, newCpus, bln.SharedIdleCpus.Difference(addCpus)).Equals(cpus) {
+			continue
+		}
+		if !addCpus.IsEmpty() {
+			log.Debugf("- inflating balloon %s with CPUs %q", bln.PrettyName(), addCpus)
+			p.forgetCpuClass(bln)
+			p.freeCpus = p.freeCpus.Difference(addCpus)
+			bln.Cpus = newCpus
+			if err := p.useCpuClass(bln); err != nil {
+				log.Warnf("failed to apply CPU class to balloon %s: %v", bln.PrettyName(), err)
+			}
+			p.updatePinning(p.shareIdleCpus(cpuset.New(), addCpus)...)
+		}
+		return bln, nil
+	}
+
+	if !cpus.IsSubsetOf(p.freeCpus) {
+		return nil, balloonsError("CPUs are not free for a new %q balloon", blnDef.Name)
+	}
+	if cpus.Size() < blnDef.MinCpus || (blnDef.MaxCpus > NoLimit && cpus.Size() > blnDef.MaxCpus) {
+		return nil, balloonsError("%d CPUs do not fit %q balloon MinCpus %d, MaxCpus %d",
+			cpus.Size(), blnDef.Name, blnDef.MinCpus, blnDef.MaxCpus)
+	}
+	if cpus.Size()*1000 < reqMilliCpus {
+		return nil, balloonsError("%d CPUs are not enough for a request of %d mCPU",
+			cpus.Size(), reqMilliCpus)
+	}
+	instance, err := p.freeBalloonInstance(blnDef)
+	if err != nil {
+		return nil, err
+	}
+	memTypeMask, _ := memTypeMaskFromStringList(blnDef.MemoryTypes)
+	bln := &Balloon{
+		Def:            blnDef,
+		Instance:       instance,
+		Groups:         make(map[string]int),
+		PodIDs:         make(map[string][]string),
+		Cpus:           cpus,
+		SharedIdleCpus: cpuset.New(),
+		Mems:           p.closestMems(cpus),
+		cpuTreeAlloc:   p.newCpuTreeAllocator(blnDef),
+		memTypeMask:    memTypeMask,
+	}
+	if !pinnedCpus(bln, cpus, cpuset.New()).Equals(cpus) {
+		return nil, balloonsError("CPUs do not match the hyperthreads of a %q balloon", blnDef.Name)
+	}
+
+	log.Debugf("- creating balloon %s with CPUs %q", bln.PrettyName(), cpus)
+	p.freeCpus = p.freeCpus.Difference(cpus)
+	p.balloons = append(p.balloons, bln)
+	if err := p.useCpuClass(bln); err != nil {
+		log.Warnf("failed to apply CPU class to balloon %s: %v", bln.PrettyName(), err)
+	}
+	p.updatePinning(p.shareIdleCpus(cpuset.New(), cpus)...)
+
+	return bln, nil
+}
+
 // ReleaseResources is a resource release request for this policy.
 func (p *balloons) ReleaseResources(c cache.Container) error {
 	log.Debug("releasing container %s...", c.PrettyName())
@@ -290,7 +450,7 @@ func (p *balloons) ReleaseResources(c cache.Container) error {
 		if bln.ContainerCount() == 0 {
 			// Deflate the balloon completely before
 			// freeing it.
-			if err := p.resizeBalloon(bln, 0); err != nil {
+			if err := p.resizeBalloon(bln, 0, nil); err != nil {
 				log.Warnf("failed to deflate balloon %s: %v", bln.PrettyName(), err)
 			}
 			log.Debug("all containers removed, free balloon allocation %s", bln.PrettyName())
@@ -298,8 +458,13 @@ func (p *balloons) ReleaseResources(c cache.Container) error {
 		} else {
 			// Make sure that the balloon will have at
 			// least 1 CPU to run remaining containers.
-			if err := p.resizeBalloon(bln, max(1, p.requestedMilliCpus(bln))); err != nil {
-				return balloonsError("resizing balloon %s failed: %w", bln.PrettyName(), err)
+			// Balloons resized by usage are only deflated
+			// by usage, to not undo usage-driven growth.
+			reqMilliCpus := max(1, p.requestedMilliCpus(bln))
+			if bln.Def.DynamicResizing == nil || bln.AvailMilliCpus() < reqMilliCpus {
+				if err := p.resizeBalloon(bln, reqMilliCpus, nil); err != nil {
+					return balloonsError("resizing balloon %s failed: %w", bln.PrettyName(), err)
+				}
 			}
 		}
 	} else {
@@ -315,19 +480,188 @@ func (p *balloons) UpdateResources(c cache.Container) error {
 }
 
 // HandleEvent handles policy-specific events.
-func (p *balloons) HandleEvent(*events.Policy) (bool, error) {
-	log.Debug("(not) handling event...")
+func (p *balloons) HandleEvent(e *events.Policy) (bool, error) {
+	switch e.Type {
+	case DynamicResizingTick:
+		return p.resizeByUsage(time.Now()), nil
+	case events.Rebalance:
+		return true, p.rebalance()
+	}
+	log.Debug("(not) handling event %s...", e.Type)
 	return false, nil
 }
 
 // ExportResourceData provides resource data to export for the container.
 func (p *balloons) ExportResourceData(c cache.Container) map[string]string {
-	return nil
+	bln := p.balloonByContainer(c)
+	if bln == nil {
+		return nil
+	}
+
+	data := map[string]string{
+		exportBalloon:     bln.PrettyName(),
+		exportBalloonType: bln.Def.Name,
+		exportBalloonCpus: bln.Cpus.String(),
+		exportBalloonMems: bln.Mems.String(),
+	}
+	if bln.SharedIdleCpus.Size() > 0 {
+		data[exportSharedIdleCpus] = bln.SharedIdleCpus.String()
+	}
+
+	return data
 }
 
 // GetTopologyZones returns the policy/pool data for 'topology zone' CRDs.
-func (b *balloons) GetTopologyZones() []*policy.TopologyZone {
-	return nil
+func (p *balloons) GetTopologyZones() []*policy.TopologyZone {
+	if p.cpuTree == nil {
+		return nil
+	}
+
+	zones := []*policy.TopologyZone{}
+
+	// Export the system, package, die and NUMA node levels of the
+	// CPU tree as parent zones for balloon instances.
+	if err := p.cpuTree.DepthFirstWalk(func(t *cpuTreeNode) error {
+		if t.level.Value() > CPUTopologyLevel(CPUTopologyLevelNuma).Value() {
+			return WalkSkipChildren
+		}
+		zone := &policy.TopologyZone{
+			Name: t.name,
+			Type: string(t.level),
+		}
+		if t.parent != nil {
+			zone.Parent = t.parent.name
+		}
+
+		cpus := t.cpus.Intersection(p.allowed)
+		free := t.cpus.Intersection(p.freeCpus)
+		mems := p.closestMems(cpus)
+		zone.Resources = []*policy.ZoneResource{
+			p.cpuZoneResource(cpus.Size()*1000, cpus.Size()*1000, free.Size()*1000),
+			p.memZoneResource(mems),
+		}
+		zone.Attributes = []*policy.ZoneAttribute{
+			{
+				Name:  policy.CPUsetAttribute,
+				Value: cpus.String(),
+			},
+			{
+				Name:  policy.MemsetAttribute,
+				Value: mems.String(),
+			},
+		}
+		if reserved := cpus.Intersection(p.reserved); !reserved.IsEmpty() {
+			zone.Attributes = append(zone.Attributes, &policy.ZoneAttribute{
+				Name:  policy.ReservedCPUsAttribute,
+				Value: reserved.String(),
+			})
+		}
+		if isolated := cpus.Intersection(p.options.System.Isolated()); !isolated.IsEmpty() {
+			zone.Attributes = append(zone.Attributes, &policy.ZoneAttribute{
+				Name:  policy.IsolatedCPUsAttribute,
+				Value: isolated.String(),
+			})
+		}
+
+		zones = append(zones, zone)
+		return nil
+	}); err != nil && err != WalkSkipChildren && err != WalkStop {
+		log.Warnf("failed to walk CPU tree: %v", err)
+	}
+
+	// Export every balloon instance under the smallest topology
+	// element (up to NUMA node) which contains all its CPUs.
+	for _, bln := range p.balloons {
+		zone := &policy.TopologyZone{
+			Name: bln.PrettyName(),
+			Type: balloonZoneType,
+		}
+		if parent := p.cpuTree.SmallestCommonNode(bln.Cpus, CPUTopologyLevelNuma); parent != nil {
+			zone.Parent = parent.name
+		}
+
+		avail := max(0, p.freeMilliCpus(bln))
+		zone.Resources = []*policy.ZoneResource{
+			p.cpuZoneResource(bln.AvailMilliCpus(), bln.AvailMilliCpus(), avail),
+			p.memZoneResource(bln.Mems),
+		}
+		zone.Attributes = []*policy.ZoneAttribute{
+			{
+				Name:  policy.BalloonTypeAttribute,
+				Value: bln.Def.Name,
+			},
+			{
+				Name:  policy.CPUsetAttribute,
+				Value: bln.Cpus.String(),
+			},
+			{
+				Name:  policy.MemsetAttribute,
+				Value: bln.Mems.String(),
+			},
+			{
+				Name:  policy.SharedIdleCPUsAttribute,
+				Value: bln.SharedIdleCpus.String(),
+			},
+		}
+		if bln.Def.CpuClass != "" {
+			zone.Attributes = append(zone.Attributes, &policy.ZoneAttribute{
+				Name:  policy.CPUClassAttribute,
+				Value: bln.Def.CpuClass,
+			})
+		}
+
+		zones = append(zones, zone)
+	}
+
+	return zones
+}
+
+// GetBalloons returns the balloons for introspection.
+func (p *balloons) GetBalloons() []*introspect.Pool {
+	return p.GetPools()
+}
+
+// GetPools returns the balloons for introspection.
+func (p *balloons) GetPools() []*introspect.Pool {
+	pools := make([]*introspect.Pool, 0, len(p.balloons))
+	for _, bln := range p.balloons {
+		pool := &introspect.Pool{
+			Name:         bln.PrettyName(),
+			Type:         bln.Def.Name,
+			CPUs:         bln.Cpus.String(),
+			SharedCPUs:   bln.SharedIdleCpus.String(),
+			ReservedCPUs: bln.Cpus.Intersection(p.reserved).String(),
+			IsolatedCPUs: bln.Cpus.Intersection(p.options.System.Isolated()).String(),
+			Mems:         bln.Mems.String(),
+			Containers:   bln.ContainerIDs(),
+		}
+		sort.Strings(pool.Containers)
+		pools = append(pools, pool)
+	}
+	return pools
+}
+
+// cpuZoneResource returns a CPU topology zone resource with the given amounts.
+func (p *balloons) cpuZoneResource(capacity, allocatable, available int) *policy.ZoneResource {
+	return &policy.ZoneResource{
+		Name:        policy.CPUResource,
+		Capacity:    *resource.NewMilliQuantity(int64(capacity), resource.DecimalSI),
+		Allocatable: *resource.NewMilliQuantity(int64(allocatable), resource.DecimalSI),
+		Available:   *resource.NewMilliQuantity(int64(available), resource.DecimalSI),
+	}
+}
+
+// memZoneResource returns a memory topology zone resource for the given nodes.
+func (p *balloons) memZoneResource(mems idset.IDSet) *policy.ZoneResource {
+	zone := libmem.NewNodeMask(mems.Members()...)
+	capacity := p.memAllocator.ZoneCapacity(zone)
+	available := p.memAllocator.ZoneFree(zone)
+	return &policy.ZoneResource{
+		Name:        policy.MemoryResource,
+		Capacity:    *resource.NewQuantity(capacity, resource.DecimalSI),
+		Allocatable: *resource.NewQuantity(capacity, resource.DecimalSI),
+		Available:   *resource.NewQuantity(available, resource.DecimalSI),
+	}
 }
 
 // balloonByContainer returns a balloon that contains a container.
@@ -399,16 +733,23 @@ func (p *balloons) balloonDefByName(defName string) *BalloonDef {
 	return nil
 }
 
-func (p *balloons) chooseBalloonDef(c cache.Container) (*BalloonDef, error) {
+// matchBalloonDef chooses the balloon type for a container. It also
+// returns a description of the rule which selected the balloon type.
+// If a decision is given, the checked annotations and rules are also
+// recorded in it.
+func (p *balloons) matchBalloonDef(c cache.Container, d *cache.Decision) (*BalloonDef, string, error) {
 	log.Debugf("choosing balloon type for container %s...", c.PrettyName())
 	// Case 1: BalloonDef is defined by annotation.
 	if blnDefName, ok := c.GetEffectiveAnnotation(balloonKey); ok {
+		if d != nil {
+			d.AddAnnotation(balloonKey, blnDefName)
+		}
 		blnDef := p.balloonDefByName(blnDefName)
 		if blnDef == nil {
-			return nil, balloonsError("no balloon for annotation %q", blnDefName)
+			return nil, "", balloonsError("no balloon for annotation %q", blnDefName)
 		}
 		log.Debugf("- annotation %q found, using balloon type %q", balloonKey, blnDefName)
-		return blnDef, nil
+		return blnDef, fmt.Sprintf("annotation %s=%s", balloonKey, blnDefName), nil
 	}
 
 	for _, blnDef := range p.bpoptions.BalloonDefs {
@@ -416,22 +757,31 @@ func (p *balloons) chooseBalloonDef(c cache.Container) (*BalloonDef, error) {
 		for _, expr := range blnDef.MatchExpressions {
 			log.Debugf("- checking expression %s of balloon type %q against container %s...",
 				expr.String(), blnDef.Name, c.PrettyName())
-			if expr.Evaluate(c) {
+			matches := expr.Evaluate(c)
+			if d != nil {
+				d.AddExpression(fmt.Sprintf("balloon type %s: %s", blnDef.Name, expr.String()), matches)
+			}
+			if matches {
 				log.Debugf("  => matches")
-				return blnDef, nil
+				return blnDef, "expression " + expr.String(), nil
 			}
 		}
 
 		// Case 3: BalloonDef is defined by the namespace.
-		if namespaceMatches(c.GetNamespace(), blnDef.Namespaces) {
+		matches := namespaceMatches(c.GetNamespace(), blnDef.Namespaces)
+		if d != nil && len(blnDef.Namespaces) > 0 {
+			d.AddExpression(fmt.Sprintf("balloon type %s: namespace %s in %v",
+				blnDef.Name, c.GetNamespace(), blnDef.Namespaces), matches)
+		}
+		if matches {
 			log.Debugf("- namespace %q matches namespaces of balloon type %q", c.GetNamespace(), blnDef.Name)
-			return blnDef, nil
+			return blnDef, fmt.Sprintf("namespace %s in %v", c.GetNamespace(), blnDef.Namespaces), nil
 		}
 	}
 
 	log.Debugf("- no match found, using default balloon type %q", defaultBalloonDefName)
 	// Case 4: Fallback to the default balloon.
-	return p.defaultBalloonDef, nil
+	return p.defaultBalloonDef, "no match, default balloon type", nil
 }
 
 func (p *balloons) containerRequestedMilliCpus(contID string) int {
@@ -567,13 +917,56 @@ func (p *balloons) forgetCpuClass(bln *Balloon) {
 	}
 }
 
-func (p *balloons) newBalloon(blnDef *BalloonDef, confCpus bool) (*Balloon, error) {
+// newBalloon creates a new balloon instance from a balloon definition.
+// If hintCpus are given, the initial CPUs of the balloon are
+// preferably allocated close to them.
+func (p *balloons) newBalloon(blnDef *BalloonDef, confCpus bool, hintCpus map[string]cpuset.CPUSet) (*Balloon, error) {
 	var cpus cpuset.CPUSet
 	var err error
+	freeInstance, err := p.freeBalloonInstance(blnDef)
+	if err != nil {
+		return nil, err
+	}
+	cpuTreeAlloc := p.newCpuTreeAllocator(blnDef)
+
+	// Allocate CPUs
+	addFromCpus, _, err := cpuTreeAlloc.withCloseCpuSets(hintCpus).ResizeCpus(cpuset.New(), p.freeCpus, blnDef.MinCpus)
+	if err != nil {
+		return nil, balloonsError("failed to choose a cpuset for allocating MinCpus: %d from free cpus %q", blnDef.MinCpus, p.freeCpus)
+	}
+	cpus, err = p.cpuAllocator.AllocateCpus(&addFromCpus, blnDef.MinCpus, blnDef.AllocatorPriority.Value().Option())
+	if err != nil {
+		return nil, balloonsError("could not allocate minCpus (%d) for balloon %s[%d]: %w", blnDef.MinCpus, blnDef.Name, freeInstance, err)
+	}
+	p.freeCpus = p.freeCpus.Difference(cpus)
+	memTypeMask, _ := memTypeMaskFromStringList(blnDef.MemoryTypes)
+	bln := &Balloon{
+		Def:            blnDef,
+		Instance:       freeInstance,
+		Groups:         make(map[string]int),
+		PodIDs:         make(map[string][]string),
+		Cpus:           cpus,
+		SharedIdleCpus: cpuset.New(),
+		Mems:           p.closestMems(cpus),
+		cpuTreeAlloc:   cpuTreeAlloc,
+		memTypeMask:    memTypeMask,
+	}
+	if confCpus {
+		if err = p.useCpuClass(bln); err != nil {
+			log.Errorf("failed to apply CPU configuration to new balloon %s[%d] (cpus: %s): %w", blnDef.Name, freeInstance, cpus, err)
+			return nil, err
+		}
+	}
+	return bln, nil
+}
+
+// freeBalloonInstance returns the first unused instance index for a new
+// balloon of a balloon definition.
+func (p *balloons) freeBalloonInstance(blnDef *BalloonDef) (int, error) {
 	blnsOfDef := p.balloonsByDef(blnDef)
 	// Allowed to create new balloon instance from blnDef?
 	if blnDef.MaxBalloons > NoLimit && blnDef.MaxBalloons <= len(blnsOfDef) {
-		return nil, balloonsError("cannot create new %q balloon, MaxBalloons limit (%d) reached", blnDef.Name, blnDef.MaxBalloons)
+		return 0, balloonsError("cannot create new %q balloon, MaxBalloons limit (%d) reached", blnDef.Name, blnDef.MaxBalloons)
 	}
 	// Find the first unused balloon instance index.
 	freeInstance := 0
@@ -589,6 +982,12 @@ func (p *balloons) newBalloon(blnDef *BalloonDef, confCpus bool) (*Balloon, erro
 			break
 		}
 	}
+	return freeInstance, nil
+}
+
+// newCpuTreeAllocator returns a CPU tree allocator for a balloon of a
+// balloon definition.
+func (p *balloons) newCpuTreeAllocator(blnDef *BalloonDef) *cpuTreeAllocator {
 	// Configure cpuTreeAllocator for this balloon. The reserved
 	// balloon always prefers to be close to the virtual device
 	// that is close to ReservedResources CPUs. All other balloon
@@ -611,37 +1010,7 @@ func (p *balloons) newBalloon(blnDef *BalloonDef, confCpus bool) (*Balloon, erro
 	if blnDef.PreferSpreadOnPhysicalCores != nil {
 		allocatorOptions.preferSpreadOnPhysicalCores = *blnDef.PreferSpreadOnPhysicalCores
 	}
-	cpuTreeAlloc := p.cpuTree.NewAllocator(allocatorOptions)
-
-	// Allocate CPUs
-	addFromCpus, _, err := cpuTreeAlloc.ResizeCpus(cpuset.New(), p.freeCpus, blnDef.MinCpus)
-	if err != nil {
-		return nil, balloonsError("failed to choose a cpuset for allocating MinCpus: %d from free cpus %q", blnDef.MinCpus, p.freeCpus)
-	}
-	cpus, err = p.cpuAllocator.AllocateCpus(&addFromCpus, blnDef.MinCpus, blnDef.AllocatorPriority.Value().Option())
-	if err != nil {
-		return nil, balloonsError("could not allocate minCpus (%d) for balloon %s[%d]: %w", blnDef.MinCpus, blnDef.Name, freeInstance, err)
-	}
-	p.freeCpus = p.freeCpus.Difference(cpus)
-	memTypeMask, _ := memTypeMaskFromStringList(blnDef.MemoryTypes)
-	bln := &Balloon{
-		Def:            blnDef,
-		Instance:       freeInstance,
-		Groups:         make(map[string]int),
-		PodIDs:         make(map[string][]string),
-		Cpus:           cpus,
-		SharedIdleCpus: cpuset.New(),
-		Mems:           p.closestMems(cpus),
-		cpuTreeAlloc:   cpuTreeAlloc,
-		memTypeMask:    memTypeMask,
-	}
-	if confCpus {
-		if err = p.useCpuClass(bln); err != nil {
-			log.Errorf("failed to apply CPU configuration to new balloon %s[%d] (cpus: %s): %w", blnDef.Name, freeInstance, cpus, err)
-			return nil, err
-		}
-	}
-	return bln, nil
+	return p.cpuTree.NewAllocator(allocatorOptions)
 }
 
 // deleteBalloon removes an empty balloon.
@@ -675,12 +1044,21 @@ func (p *balloons) fillableBalloonInstances(blnDef *BalloonDef, fm FillMethod, c
 	switch fm {
 	case FillNewBalloon, FillNewBalloonMust:
 		// Choosing an existing balloon without containers is
-		// preferred over instantiating a new balloon.
+		// preferred over instantiating a new balloon. Among
+		// them, prefer the one closest to topology hints.
+		hintCpus := p.containerHintCpus(c)
+		var emptyBln *Balloon
 		for _, bln := range p.balloonsByDef(blnDef) {
-			if len(bln.PodIDs) == 0 {
-				return []*Balloon{bln}, nil
+			if len(bln.PodIDs) != 0 {
+				continue
+			}
+			if emptyBln == nil || hintScore(hintCpus, bln.Cpus) > hintScore(hintCpus, emptyBln.Cpus) {
+				emptyBln = bln
 			}
 		}
+		if emptyBln != nil {
+			return []*Balloon{emptyBln}, nil
+		}
 		// Creating a new balloon and placing a container
 		// (even a best effort one) to it always requires at
 		// least one CPU. Make sure this is doable.
@@ -691,7 +1069,7 @@ func (p *balloons) fillableBalloonInstances(blnDef *BalloonDef, fm FillMethod, c
 			}
 			return nil, nil
 		}
-		newBln, err := p.newBalloon(blnDef, false)
+		newBln, err := p.newBalloon(blnDef, false, hintCpus)
 		if err != nil {
 			if fm == FillNewBalloonMust {
 				return nil, err
@@ -811,7 +1189,12 @@ func namespaceMatches(namespace string, patterns []string) bool {
 
 // allocateBalloon returns a balloon allocated for a container.
 func (p *balloons) allocateBalloon(c cache.Container) (*Balloon, error) {
-	blnDef, err := p.chooseBalloonDef(c)
+	d := cache.NewDecision(PolicyName)
+	d.SetHints(c.GetTopologyHints())
+	d.Request = fmt.Sprintf("request %d mCPU, limit %d mCPU",
+		p.containerRequestedMilliCpus(c.GetID()), p.containerLimitedMilliCpus(c.GetID()))
+
+	blnDef, match, err := p.matchBalloonDef(c, d)
 	if err != nil {
 		return nil, err
 	}
@@ -819,19 +1202,31 @@ func (p *balloons) allocateBalloon(c cache.Container) (*Balloon, error) {
 		return nil, balloonsError("no applicable balloon type found")
 	}
 
-	bln, err := p.allocateBalloonOfDef(blnDef, c)
+	bln, err := p.allocateBalloonOfDef(blnDef, c, d)
+	if err == nil && bln == nil {
+		err = balloonsError("no suitable balloon instance available")
+	}
 	if err != nil {
+		if blnDef.MaxBalloons > NoLimit && len(p.balloonsByDef(blnDef)) >= blnDef.MaxBalloons {
+			p.options.SendPodEvent(c, events.Warning, events.BalloonLimitReached,
+				"MaxBalloons limit (%d) of balloon type %q reached",
+				blnDef.MaxBalloons, blnDef.Name)
+		}
 		return nil, err
 	}
-	if bln == nil {
-		return nil, balloonsError("no suitable balloon instance available")
-	}
+
+	d.Match = match
+	d.Reason = fmt.Sprintf("balloon type %s by %s, %s", blnDef.Name, match, d.Reason)
+	c.SetDecision(d)
+
 	return bln, nil
 }
 
 // allocateBalloonOfDef returns a balloon instantiated from a
 // definition for a container.
-func (p *balloons) allocateBalloonOfDef(blnDef *BalloonDef, c cache.Container) (*Balloon, error) {
+// If a decision is given, the considered balloon instances are recorded
+// in it.
+func (p *balloons) allocateBalloonOfDef(blnDef *BalloonDef, c cache.Container, d *cache.Decision) (*Balloon, error) {
 	fillChain := []FillMethod{}
 	if blnDef.GroupBy != "" {
 		fillChain = append(fillChain, FillSameGroup)
@@ -847,6 +1242,7 @@ func (p *balloons) allocateBalloonOfDef(blnDef *BalloonDef, c cache.Container) (
 	} else {
 		fillChain = append(fillChain, FillBalanced, FillBalancedInflate, FillNewBalloon)
 	}
+	hintCpus := p.containerHintCpus(c)
 	for _, fillMethod := range fillChain {
 		blns, err := p.fillableBalloonInstances(blnDef, fillMethod, c)
 		if err != nil {
@@ -865,25 +1261,70 @@ func (p *balloons) allocateBalloonOfDef(blnDef *BalloonDef, c cache.Container) (
 		// all best efforts to a balloon that has least CPU
 		// reservations on it.
 
-		// Choose the balloon with the most free CPUs. If
-		// there are equally good candidates, choose the one
-		// with the lowest number of containers assigned.
+		// Choose the balloon closest to the topology hints
+		// of the container. If there are equally good
+		// candidates, choose the one with the most free
+		// CPUs, and then the one with the lowest number of
+		// containers assigned.
+		bestHints, _ := largest(len(blns), func(i int) int {
+			return hintScore(hintCpus, blns[i].Cpus)
+		})
 		largestBy := p.freeMilliCpus
 		if fillMethod == FillBalancedInflate {
 			largestBy = p.maxFreeMilliCpus
 		}
-		mostRoom, _ := largest(len(blns), func(i int) int {
-			return largestBy(blns[i])
+		mostRoom, _ := largest(len(bestHints), func(i int) int {
+			return largestBy(blns[bestHints[i]])
 		})
 		leastContainers, _ := largest(len(mostRoom), func(i int) int {
-			return -blns[mostRoom[i]].ContainerCount()
+			return -blns[bestHints[mostRoom[i]]].ContainerCount()
 		})
-		bestBln := blns[mostRoom[leastContainers[0]]]
+		bestBln := blns[bestHints[mostRoom[leastContainers[0]]]]
+		if d != nil {
+			p.recordBalloonCandidates(d, blns, bestHints, mostRoom, leastContainers, hintCpus, largestBy)
+			d.Choose(bestBln.PrettyName(), fmt.Sprintf("fill method %s", fillMethod))
+		}
 		return bestBln, nil
 	}
 	return nil, nil
 }
 
+// recordBalloonCandidates records balloon instances considered for a
+// container, with the reasons for rejecting all but the best one.
+func (p *balloons) recordBalloonCandidates(d *cache.Decision, blns []*Balloon,
+	bestHints, mostRoom, leastContainers []int,
+	hintCpus map[string]cpuset.CPUSet, freeMilliCpus func(*Balloon) int) {
+	rank := make([]int, len(blns))
+	for _, i := range bestHints {
+		rank[i] = 1
+	}
+	for _, i := range mostRoom {
+		rank[bestHints[i]] = 2
+	}
+	for _, i := range leastContainers {
+		rank[bestHints[mostRoom[i]]] = 3
+	}
+	best := bestHints[mostRoom[leastContainers[0]]]
+
+	for i, bln := range blns {
+		score := fmt.Sprintf("hint score %d, free %d mCPU, %d containers",
+			hintScore(hintCpus, bln.Cpus), freeMilliCpus(bln), bln.ContainerCount())
+		reason := ""
+		switch {
+		case i == best:
+		case rank[i] == 0:
+			reason = "worse topology hint match"
+		case rank[i] == 1:
+			reason = "less free CPU"
+		case rank[i] == 2:
+			reason = "more containers"
+		default:
+			reason = "tie, another balloon came first"
+		}
+		d.AddCandidate(bln.PrettyName(), score, reason)
+	}
+}
+
 // dumpBalloon dumps balloon contents in detail.
 func (p *balloons) dumpBalloon(bln *Balloon) string {
 	conts := []string{}
@@ -1005,17 +1446,33 @@ func (p *balloons) Reconfigure(newCfg interface{}) error {
 		return err
 	}
 	log.Info("config updated successfully")
+	p.reassignContainers()
+	return nil
+}
+
+// rebalance recreates balloons and reassigns all containers to them.
+func (p *balloons) rebalance() error {
+	log.Info("rebalancing balloons")
+	if err := p.setConfig(p.bpoptions); err != nil {
+		return balloonsError("rebalancing failed: %w", err)
+	}
+	p.reassignContainers()
+	return nil
+}
+
+// reassignContainers assigns all containers to balloons after (re)creating them.
+func (p *balloons) reassignContainers() {
 	if err := p.Sync(p.cch.GetContainers(), p.cch.GetContainers()); err != nil {
 		log.Warnf("failed to sync containers: %v", err)
 	}
-	return nil
+	p.startDynamicResizing()
 }
 
 // applyBalloonDef creates user-defined balloons or reconfigures built-in
 // balloons according to the blnDef. Does not initialize balloon CPUs.
 func (p *balloons) applyBalloonDef(balloons *[]*Balloon, blnDef *BalloonDef, freeCpus *cpuset.CPUSet) error {
 	for blnIdx := 0; blnIdx < blnDef.MinBalloons; blnIdx++ {
-		newBln, err := p.newBalloon(blnDef, false)
+		newBln, err := p.newBalloon(blnDef, false, nil)
 		if err != nil {
 			return err
 		}
@@ -1059,6 +1516,15 @@ func (p *balloons) validateConfig(bpoptions *BalloonsOptions) error {
 					blnDef.Name, blnDef.MaxBalloons)
 			}
 		}
+		if dr := blnDef.DynamicResizing; dr != nil {
+			if blnDef.Name == reservedBalloonDefName {
+				return balloonsError("dynamicResizing is not supported in balloon type %q", blnDef.Name)
+			}
+			if *dr.ShrinkUtilization >= dr.GrowUtilization {
+				return balloonsError("dynamicResizing shrinkUtilization (%d) >= growUtilization (%d) in balloon type %q",
+					*dr.ShrinkUtilization, dr.GrowUtilization, blnDef.Name)
+			}
+		}
 		if blnDef.PreferIsolCpus && blnDef.ShareIdleCpusInSame != "" {
 			log.Warn("WARNING: using PreferIsolCpus with ShareIdleCpusInSame is highly discouraged")
 		}
@@ -1333,7 +1799,8 @@ func (p *balloons) closestMems(cpus cpuset.CPUSet) idset.IDSet {
 }
 
 // resizeBalloon changes the CPUs allocated for a balloon, if allowed.
-func (p *balloons) resizeBalloon(bln *Balloon, newMilliCpus int) error {
+// If hintCpus are given, new CPUs are preferably allocated close to them.
+func (p *balloons) resizeBalloon(bln *Balloon, newMilliCpus int, hintCpus map[string]cpuset.CPUSet) error {
 	oldCpuCount := bln.Cpus.Size()
 	newCpuCount := (newMilliCpus + 999) / 1000
 	if bln.Def.MaxCpus > NoLimit && newCpuCount > bln.Def.MaxCpus {
@@ -1357,7 +1824,7 @@ func (p *balloons) resizeBalloon(bln *Balloon, newMilliCpus int) error {
 	}()
 	if cpuCountDelta > 0 {
 		// Inflate the balloon.
-		addFromCpus, _, err := bln.cpuTreeAlloc.ResizeCpus(bln.Cpus, p.freeCpus, cpuCountDelta)
+		addFromCpus, _, err := bln.cpuTreeAlloc.withCloseCpuSets(hintCpus).ResizeCpus(bln.Cpus, p.freeCpus, cpuCountDelta)
 		if err != nil {
 			return balloonsError("resize/inflate: failed to choose a cpuset for allocating additional %d CPUs: %w", cpuCountDelta, err)
 		}
@@ -1501,6 +1968,34 @@ func (p *balloons) assignContainer(c cache.Container, bln *Balloon) {
 	bln.PodIDs[podID] = append(bln.PodIDs[podID], c.GetID())
 	bln.updateGroups(c, 1)
 	p.updatePinning(bln)
+	p.useContainerClasses(c, bln)
+}
+
+// useContainerClasses assigns a container to the RDT and block I/O classes
+// of its balloon, unless the classes of the container are set by annotations.
+// If the balloon type has no class, the container is assigned to the default
+// class, the one of its pod QoS class.
+func (p *balloons) useContainerClasses(c cache.Container, bln *Balloon) {
+	if _, ok := c.GetEffectiveAnnotation(cache.RDTClassKey); !ok {
+		class := bln.Def.RdtClass
+		if class == "" {
+			class = cache.RDTClassPodQoS
+		}
+		if class != c.GetRDTClass() {
+			log.Debug("  - assigning %s to RDT class %q", c.PrettyName(), class)
+			c.SetRDTClass(class)
+		}
+	}
+	if _, ok := c.GetEffectiveAnnotation(cache.BlockIOClassKey); !ok {
+		class := bln.Def.BlockioClass
+		if class == "" {
+			class = string(c.GetQOSClass())
+		}
+		if class != c.GetBlockIOClass() {
+			log.Debug("  - assigning %s to block I/O class %q", c.PrettyName(), class)
+			c.SetBlockIOClass(class)
+		}
+	}
 }
 
 // dismissContainer removes a container from a balloon
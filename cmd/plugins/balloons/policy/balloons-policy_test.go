@@ -15,9 +15,48 @@
 package balloons
 
 import (
+	"path/filepath"
 	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/policy/balloons"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache/cachetest"
+	"github.com/containers/nri-plugins/pkg/resmgr/policy"
+	system "github.com/containers/nri-plugins/pkg/sysfs"
+	"github.com/containers/nri-plugins/pkg/utils"
 )
 
+// newTestPolicy sets up a balloons policy with the given configuration
+// on a 20-CPU, single NUMA node desktop system. The cache of the policy
+// is created with cachetest.NewCache.
+func newTestPolicy(t *testing.T, cfg *BalloonsOptions) (*balloons, cache.Cache) {
+	dir := t.TempDir()
+	tarball := filepath.Join("..", "..", "topology-aware", "policy", "testdata", "sysfs.tar.bz2")
+	require.NoError(t, utils.UncompressTbz2(tarball, dir))
+
+	system.SetSysRoot(filepath.Join(dir, "sysfs", "desktop"))
+	t.Cleanup(func() { system.SetSysRoot("") })
+
+	sys, err := system.DiscoverSystem()
+	require.NoError(t, err)
+
+	if cfg.ReservedResources == nil {
+		cfg.ReservedResources = cfgapi.Constraints{cfgapi.CPU: "cpuset:0"}
+	}
+
+	cch := cachetest.NewCache(t)
+	p := New().(*balloons)
+	require.NoError(t, p.Setup(&policy.BackendOptions{
+		Cache:  cch,
+		System: sys,
+		Config: cfg,
+	}))
+
+	return p, cch
+}
+
 func TestChangesBalloons(t *testing.T) {
 	tcases := []struct {
 		name          string
@@ -94,3 +133,60 @@ func TestChangesBalloons(t *testing.T) {
 		})
 	}
 }
+
+func TestExportResourceData(t *testing.T) {
+	p, cch := newTestPolicy(t, &BalloonsOptions{
+		BalloonDefs: []*BalloonDef{
+			{
+				Name:                "exported",
+				Namespaces:          []string{"default"},
+				MinCpus:             2,
+				ShareIdleCpusInSame: CPUTopologyLevelSystem,
+			},
+		},
+	})
+
+	c, _ := cachetest.AddContainer(t, cch, "ctr0", nil)
+	require.Nil(t, p.ExportResourceData(c), "unallocated container")
+
+	require.NoError(t, p.AllocateResources(c))
+	bln := p.balloonByContainer(c)
+	require.NotNil(t, bln)
+	require.Equal(t, 2, bln.Cpus.Size())
+	require.NotZero(t, bln.SharedIdleCpus.Size())
+
+	require.Equal(t, map[string]string{
+		"BALLOON":          "exported[0]",
+		"BALLOON_TYPE":     "exported",
+		"BALLOON_CPUS":     bln.Cpus.String(),
+		"BALLOON_MEMS":     "0",
+		"SHARED_IDLE_CPUS": bln.SharedIdleCpus.String(),
+	}, p.ExportResourceData(c))
+}
+
+func TestUseContainerClasses(t *testing.T) {
+	p, cch := newTestPolicy(t, &BalloonsOptions{
+		BalloonDefs: []*BalloonDef{
+			{
+				Name:         "classified",
+				RdtClass:     "gold",
+				BlockioClass: "slowreader",
+			},
+			{
+				Name: "unclassified",
+			},
+		},
+	})
+
+	c, _ := cachetest.AddContainer(t, cch, "ctr0", nil)
+	defaultBlockIOClass := string(c.GetQOSClass())
+
+	p.useContainerClasses(c, &Balloon{Def: p.balloonDefByName("classified")})
+	require.Equal(t, "gold", c.GetRDTClass())
+	require.Equal(t, "slowreader", c.GetBlockIOClass())
+
+	// Classes of a previous balloon are reset to the defaults.
+	p.useContainerClasses(c, &Balloon{Def: p.balloonDefByName("unclassified")})
+	require.Equal(t, cache.RDTClassPodQoS, c.GetRDTClass())
+	require.Equal(t, defaultBlockIOClass, c.GetBlockIOClass())
+}
@@ -251,6 +251,27 @@ func (t *cpuTreeNode) CpuLocations(cpus cpuset.CPUSet) [][]string {
 	return names
 }
 
+// SmallestCommonNode returns the deepest node in a CPU tree that
+// contains all given CPUs, looking no deeper than the given topology
+// level. Returns nil if the tree does not contain all the CPUs, and
+// the node itself if the set of CPUs is empty.
+func (t *cpuTreeNode) SmallestCommonNode(cpus cpuset.CPUSet, maxLevel CPUTopologyLevel) *cpuTreeNode {
+	if cpus.Size() == 0 {
+		return t
+	}
+	var found *cpuTreeNode
+	if err := t.DepthFirstWalk(func(tn *cpuTreeNode) error {
+		if tn.level.Value() > maxLevel.Value() || !cpus.IsSubsetOf(tn.cpus) {
+			return WalkSkipChildren
+		}
+		found = tn
+		return nil
+	}); err != nil && err != WalkSkipChildren && err != WalkStop {
+		log.Warnf("failed to walk CPU tree: %v", err)
+	}
+	return found
+}
+
 // NewCpuTreeFromSystem returns the root node of the topology tree
 // constructed from the underlying system.
 func NewCpuTreeFromSystem() (*cpuTreeNode, error) {
@@ -460,6 +481,37 @@ func (t *cpuTreeNode) NewAllocator(options cpuTreeAllocatorOptions) *cpuTreeAllo
 	return ta
 }
 
+// withCloseCpuSets returns a copy of the allocator that, in addition
+// to its own options, prefers allocating CPUs from the given named
+// cpusets. These preferences are applied after the preferred devices
+// of the original allocator. Returns the allocator itself if there
+// are no cpusets to prefer.
+func (ta *cpuTreeAllocator) withCloseCpuSets(closeCpuSets map[string]cpuset.CPUSet) *cpuTreeAllocator {
+	if len(closeCpuSets) == 0 {
+		return ta
+	}
+	names := make([]string, 0, len(closeCpuSets))
+	for name := range closeCpuSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	newTa := &cpuTreeAllocator{
+		options:           ta.options,
+		root:              ta.root,
+		cacheCloseCpuSets: make(map[string][]cpuset.CPUSet, len(ta.cacheCloseCpuSets)+len(names)),
+	}
+	for dev, cpusets := range ta.cacheCloseCpuSets {
+		newTa.cacheCloseCpuSets[dev] = cpusets
+	}
+	newTa.options.preferCloseToDevices = append([]string{}, ta.options.preferCloseToDevices...)
+	for _, name := range names {
+		newTa.options.preferCloseToDevices = append(newTa.options.preferCloseToDevices, name)
+		newTa.cacheCloseCpuSets[name] = []cpuset.CPUSet{closeCpuSets[name]}
+	}
+	return newTa
+}
+
 // sorterAllocate implements an "is-less-than" callback that helps
 // sorting a slice of cpuTreeNodeAttributes. The first item in the
 // sorted list contains an optimal CPU tree node for allocating new
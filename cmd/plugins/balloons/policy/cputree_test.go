@@ -767,3 +767,103 @@ func TestSplitLevel(t *testing.T) {
 		t.Logf("newRoot:\n%s\n", newRoot.PrettyPrint())
 	}
 }
+
+func TestSmallestCommonNode(t *testing.T) {
+	tree, _ := newCpuTreeFromInt5([5]int{2, 2, 2, 4, 2})
+	tcases := []struct {
+		name     string
+		cpus     cpuset.CPUSet
+		maxLevel CPUTopologyLevel
+		expected string
+	}{
+		{
+			name:     "empty cpuset",
+			cpus:     cpuset.New(),
+			maxLevel: CPUTopologyLevelNuma,
+			expected: "system",
+		},
+		{
+			name:     "cpus in a single NUMA node",
+			cpus:     cpuset.New(0, 1, 7),
+			maxLevel: CPUTopologyLevelNuma,
+			expected: "p0d0n0",
+		},
+		{
+			name:     "cpus in a single core, stop at NUMA node",
+			cpus:     cpuset.New(8, 9),
+			maxLevel: CPUTopologyLevelNuma,
+			expected: "p0d0n1",
+		},
+		{
+			name:     "cpus in two NUMA nodes of a die",
+			cpus:     cpuset.New(0, 8),
+			maxLevel: CPUTopologyLevelNuma,
+			expected: "p0d0",
+		},
+		{
+			name:     "cpus in two dies of a package",
+			cpus:     cpuset.New(0, 16),
+			maxLevel: CPUTopologyLevelNuma,
+			expected: "p0",
+		},
+		{
+			name:     "cpus in two packages",
+			cpus:     cpuset.New(0, 63),
+			maxLevel: CPUTopologyLevelNuma,
+			expected: "system",
+		},
+		{
+			name:     "cpus in a single NUMA node, stop at package",
+			cpus:     cpuset.New(0, 1),
+			maxLevel: CPUTopologyLevelPackage,
+			expected: "p0",
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := tree.SmallestCommonNode(tc.cpus, tc.maxLevel)
+			if node == nil {
+				t.Fatalf("expected node %q, got nil", tc.expected)
+			}
+			if node.name != tc.expected {
+				t.Errorf("expected node %q, got %q", tc.expected, node.name)
+			}
+		})
+	}
+	t.Run("cpus not in tree", func(t *testing.T) {
+		if node := tree.SmallestCommonNode(cpuset.New(64), CPUTopologyLevelNuma); node != nil {
+			t.Errorf("expected nil, got %q", node.name)
+		}
+	})
+}
+
+func TestWithCloseCpuSets(t *testing.T) {
+	tree, csit := newCpuTreeFromInt5([5]int{2, 2, 2, 4, 2})
+	allCpus := tree.Cpus()
+	ta := tree.NewAllocator(cpuTreeAllocatorOptions{})
+
+	if ta.withCloseCpuSets(nil) != ta {
+		t.Errorf("expected the same allocator without close cpusets")
+	}
+
+	// Prefer CPUs in the second package, first die, second NUMA node.
+	hinted := ta.withCloseCpuSets(map[string]cpuset.CPUSet{
+		"/sys/devices/pci0000:80/0000:80:01.0": cpuset.New(40, 41, 42, 43, 44, 45, 46, 47),
+	})
+	if hinted == ta {
+		t.Fatalf("expected a new allocator with close cpusets")
+	}
+	addFrom, _, err := hinted.ResizeCpus(cpuset.New(), allCpus, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	verifyOn(t, "p1d0n1", addFrom, csit)
+
+	// The original allocator must not be affected.
+	if len(ta.options.preferCloseToDevices) != 0 {
+		t.Errorf("original allocator modified: preferCloseToDevices %v", ta.options.preferCloseToDevices)
+	}
+	if _, ok := ta.cacheCloseCpuSets["/sys/devices/pci0000:80/0000:80:01.0"]; ok {
+		t.Errorf("original allocator modified: close cpusets %v", ta.cacheCloseCpuSets)
+	}
+}
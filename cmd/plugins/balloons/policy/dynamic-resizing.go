@@ -0,0 +1,298 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package balloons
+
+import (
+	"time"
+
+	"github.com/containers/nri-plugins/pkg/cgroups"
+	"github.com/containers/nri-plugins/pkg/resmgr/events"
+)
+
+const (
+	// DynamicResizingTick is the policy event for sampling CPU usage
+	// and resizing balloons accordingly.
+	DynamicResizingTick = "dynamic-resizing-tick"
+)
+
+// dynamicResizer triggers periodic usage-driven balloon resizing.
+type dynamicResizer struct {
+	period time.Duration // sampling period, shortest of all balloon types
+	stop   chan struct{} // channel to stop the resizer
+}
+
+// cpuUsageSample is a sample of cumulative CPU usage of a container.
+type cpuUsageSample struct {
+	usage     int64 // CPU time used, in microseconds
+	stalled   int64 // time some tasks were stalled waiting for a CPU, in microseconds
+	throttled int64 // time throttled by the CPU quota, in microseconds
+}
+
+// balloonUsage tracks the observed CPU usage of a balloon.
+type balloonUsage struct {
+	sampled time.Time                 // time of the last sample
+	samples map[string]cpuUsageSample // last samples, by container ID
+	grow    int                       // consecutive samples calling for inflating
+	shrink  int                       // consecutive samples calling for deflating
+	resized time.Time                 // time of the last usage-driven resize
+}
+
+// startDynamicResizing starts triggering periodic usage-driven resizing
+// if any balloon type has it enabled.
+func (p *balloons) startDynamicResizing() {
+	p.stopDynamicResizing()
+
+	period := time.Duration(0)
+	for _, blnDef := range p.bpoptions.BalloonDefs {
+		dr := blnDef.DynamicResizing
+		if dr == nil {
+			continue
+		}
+		if period == 0 || dr.Period.Duration < period {
+			period = dr.Period.Duration
+		}
+	}
+	if period == 0 {
+		return
+	}
+
+	log.Info("starting dynamic balloon resizing with %s sampling period", period)
+
+	r := &dynamicResizer{
+		period: period,
+		stop:   make(chan struct{}),
+	}
+	p.resizer = r
+
+	go func() {
+		ticker := time.NewTicker(r.period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				e := &events.Policy{
+					Type:   DynamicResizingTick,
+					Source: PolicyName,
+				}
+				if err := p.options.SendEvent(e); err != nil {
+					log.Warnf("failed to send %s event: %v", e.Type, err)
+				}
+			}
+		}
+	}()
+}
+
+// stopDynamicResizing stops triggering usage-driven resizing.
+func (p *balloons) stopDynamicResizing() {
+	if p.resizer == nil {
+		return
+	}
+	close(p.resizer.stop)
+	p.resizer = nil
+}
+
+// resizeByUsage samples the CPU usage of balloons with dynamic resizing
+// enabled and resizes them as necessary. It returns true if any balloon
+// was resized.
+func (p *balloons) resizeByUsage(now time.Time) bool {
+	if p.resizer == nil {
+		return false
+	}
+
+	changed := false
+	for _, bln := range p.balloons {
+		dr := bln.Def.DynamicResizing
+		if dr == nil || bln.Def == p.reservedBalloonDef {
+			continue
+		}
+		if bln.usage == nil {
+			bln.usage = &balloonUsage{}
+		}
+		// Tolerate ticks arriving slightly early.
+		sampled := bln.usage.sampled
+		if !sampled.IsZero() && now.Sub(sampled)+p.resizer.period/2 < dr.Period.Duration {
+			continue
+		}
+
+		utilization, pressure, ok := p.sampleUsage(bln, now)
+		if !ok {
+			continue
+		}
+
+		delta := bln.usage.update(dr, utilization, pressure, now)
+		if delta == 0 {
+			continue
+		}
+
+		if p.resizeBalloonBy(bln, delta) {
+			bln.usage.resized = now
+			bln.usage.grow = 0
+			bln.usage.shrink = 0
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// sampleUsage samples the CPU usage of the containers in a balloon. It
+// returns the CPU utilization of the balloon and the highest pressure
+// of its containers since the previous sample, both in percent. The
+// returned boolean is false if there is no previous sample to compare to.
+func (p *balloons) sampleUsage(bln *Balloon, now time.Time) (int, int, bool) {
+	u := bln.usage
+	prevSampled, prevSamples := u.sampled, u.samples
+
+	u.sampled = now
+	u.samples = map[string]cpuUsageSample{}
+
+	for _, cID := range bln.ContainerIDs() {
+		c, ok := p.cch.LookupContainer(cID)
+		if !ok {
+			continue
+		}
+		dir := cgroups.FindV2Dir(c.GetCgroupDir())
+		if dir == "" {
+			log.Debug("dynamic resizing: no cgroup v2 directory for %s", c.PrettyName())
+			continue
+		}
+		sample, err := readCpuUsageSample(dir)
+		if err != nil {
+			log.Debug("dynamic resizing: failed to sample %s: %v", c.PrettyName(), err)
+			continue
+		}
+		u.samples[cID] = sample
+	}
+
+	if prevSampled.IsZero() || bln.Cpus.Size() == 0 {
+		return 0, 0, false
+	}
+
+	elapsed := now.Sub(prevSampled).Microseconds()
+	if elapsed <= 0 {
+		return 0, 0, false
+	}
+
+	usage, pressure := int64(0), int64(0)
+	for cID, sample := range u.samples {
+		prev, ok := prevSamples[cID]
+		if !ok {
+			continue
+		}
+		usage += sample.usage - prev.usage
+		pressure = max64(pressure, sample.stalled-prev.stalled)
+		pressure = max64(pressure, sample.throttled-prev.throttled)
+	}
+
+	utilization := int(100 * usage / (elapsed * int64(bln.Cpus.Size())))
+	pressurePct := int(100 * pressure / elapsed)
+
+	log.Debug("dynamic resizing: %s utilization %d%%, pressure %d%%",
+		bln.PrettyName(), utilization, pressurePct)
+
+	return utilization, pressurePct, true
+}
+
+// update records the utilization and pressure of a new sample and returns
+// the number of CPUs a balloon should be resized by, if any.
+func (u *balloonUsage) update(dr *DynamicResizing, utilization, pressure int, now time.Time) int {
+	switch {
+	case utilization >= dr.GrowUtilization || pressure >= dr.GrowPressure:
+		u.grow++
+		u.shrink = 0
+	case utilization <= *dr.ShrinkUtilization:
+		u.shrink++
+		u.grow = 0
+	default:
+		u.grow = 0
+		u.shrink = 0
+	}
+
+	if !u.resized.IsZero() && now.Sub(u.resized) < dr.Cooldown.Duration {
+		return 0
+	}
+
+	switch {
+	case u.grow >= dr.Samples:
+		return dr.Step
+	case u.shrink >= dr.Samples:
+		return -dr.Step
+	}
+
+	return 0
+}
+
+// resizeBalloonBy inflates or deflates a balloon by the given number of
+// CPUs, within the limits of its balloon type, free CPUs and the CPU
+// requests of its containers. It returns true if the balloon was resized.
+func (p *balloons) resizeBalloonBy(bln *Balloon, delta int) bool {
+	oldCpus := bln.Cpus.Size()
+	newCpus := oldCpus + delta
+
+	if delta > 0 {
+		if bln.Def.MaxCpus != NoLimit && newCpus > bln.Def.MaxCpus {
+			newCpus = bln.Def.MaxCpus
+		}
+		newCpus = min(newCpus, oldCpus+p.freeCpus.Size())
+		if newCpus <= oldCpus {
+			log.Debug("dynamic resizing: cannot inflate %s, no CPUs available", bln.PrettyName())
+			return false
+		}
+	} else {
+		reqCpus := (max(1, p.requestedMilliCpus(bln)) + 999) / 1000
+		newCpus = max(newCpus, max(reqCpus, bln.Def.MinCpus))
+		if newCpus >= oldCpus {
+			return false
+		}
+	}
+
+	log.Info("dynamic resizing: resizing %s from %d to %d CPUs", bln.PrettyName(), oldCpus, newCpus)
+
+	if err := p.resizeBalloon(bln, newCpus*1000, nil); err != nil {
+		log.Error("dynamic resizing: failed to resize %s: %v", bln.PrettyName(), err)
+		return false
+	}
+
+	return bln.Cpus.Size() != oldCpus
+}
+
+// readCpuUsageSample reads a CPU usage sample from a cgroup v2 directory.
+func readCpuUsageSample(dir string) (cpuUsageSample, error) {
+	stat, err := cgroups.GetCPUStat(dir)
+	if err != nil {
+		return cpuUsageSample{}, err
+	}
+
+	sample := cpuUsageSample{
+		usage:     stat.UsageUsec,
+		throttled: stat.ThrottledUsec,
+	}
+
+	// Pressure stall information might be disabled in the kernel.
+	if psi, err := cgroups.GetCPUPressure(dir); err == nil {
+		sample.stalled = psi.Some.Total
+	}
+
+	return sample, nil
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,184 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package balloons
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/containers/nri-plugins/pkg/resmgr/cache/cachetest"
+)
+
+func TestBalloonUsageUpdate(t *testing.T) {
+	type sample struct {
+		utilization int
+		pressure    int
+	}
+
+	dr := defaultDynamicResizing
+	dr.Samples = 2
+	dr.Cooldown.Duration = 30 * time.Second
+	period := 10 * time.Second
+
+	tcases := []struct {
+		name     string
+		samples  []sample
+		expected []int
+	}{
+		{
+			name:     "grow on sustained high utilization",
+			samples:  []sample{{95, 0}, {95, 0}},
+			expected: []int{0, 1},
+		},
+		{
+			name:     "grow on sustained pressure",
+			samples:  []sample{{50, 20}, {40, 15}},
+			expected: []int{0, 1},
+		},
+		{
+			name:     "shrink on sustained low utilization",
+			samples:  []sample{{10, 0}, {20, 0}},
+			expected: []int{0, -1},
+		},
+		{
+			name:     "no change between thresholds",
+			samples:  []sample{{50, 0}, {60, 0}, {70, 0}},
+			expected: []int{0, 0, 0},
+		},
+		{
+			name:     "hysteresis resets on interrupted streak",
+			samples:  []sample{{95, 0}, {50, 0}, {95, 0}, {10, 0}, {95, 0}, {95, 0}},
+			expected: []int{0, 0, 0, 0, 0, 1},
+		},
+		{
+			name:     "rate limited by cooldown",
+			samples:  []sample{{95, 0}, {95, 0}, {95, 0}, {95, 0}, {95, 0}},
+			expected: []int{0, 1, 0, 0, 1},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := &balloonUsage{}
+			now := time.Now()
+			for i, s := range tc.samples {
+				now = now.Add(period)
+				delta := u.update(&dr, s.utilization, s.pressure, now)
+				if delta != 0 {
+					u.resized = now
+					u.grow = 0
+					u.shrink = 0
+				}
+				if delta != tc.expected[i] {
+					t.Errorf("sample %d: expected delta %d, got %d", i, tc.expected[i], delta)
+				}
+			}
+		})
+	}
+}
+
+func TestResizeBalloonBy(t *testing.T) {
+	p, cch := newTestPolicy(t, &BalloonsOptions{
+		BalloonDefs: []*BalloonDef{
+			{
+				Name:            "dynamic",
+				Namespaces:      []string{"default"},
+				MinCpus:         1,
+				MaxCpus:         4,
+				DynamicResizing: &DynamicResizing{},
+			},
+		},
+	})
+
+	c, _ := cachetest.AddContainer(t, cch, "ctr0", nil)
+	require.NoError(t, p.AllocateResources(c))
+	bln := p.balloonByContainer(c)
+	require.NotNil(t, bln)
+	require.Equal(t, 1, bln.Cpus.Size())
+	free := p.freeCpus.Size()
+
+	require.True(t, p.resizeBalloonBy(bln, 2))
+	require.Equal(t, 3, bln.Cpus.Size())
+	require.Equal(t, free-2, p.freeCpus.Size())
+
+	require.True(t, p.resizeBalloonBy(bln, 5), "inflate up to maxCPUs")
+	require.Equal(t, 4, bln.Cpus.Size())
+	require.False(t, p.resizeBalloonBy(bln, 1), "inflate beyond maxCPUs")
+
+	require.True(t, p.resizeBalloonBy(bln, -10), "deflate down to minCPUs")
+	require.Equal(t, 1, bln.Cpus.Size())
+	require.False(t, p.resizeBalloonBy(bln, -1), "deflate beyond minCPUs")
+	require.Equal(t, free, p.freeCpus.Size())
+}
+
+func TestResizeByUsage(t *testing.T) {
+	p, cch := newTestPolicy(t, &BalloonsOptions{
+		BalloonDefs: []*BalloonDef{
+			{
+				Name:        "dynamic",
+				Namespaces:  []string{"default"},
+				MaxCpus:     4,
+				MaxBalloons: 1,
+				DynamicResizing: &DynamicResizing{
+					Period:   metav1.Duration{Duration: 10 * time.Second},
+					Samples:  1,
+					Cooldown: metav1.Duration{Duration: 30 * time.Second},
+				},
+			},
+		},
+	})
+	p.resizer = &dynamicResizer{period: 10 * time.Second}
+
+	cpuStat := func(usage time.Duration) map[string]string {
+		return map[string]string{
+			"cpu.stat": fmt.Sprintf("usage_usec %d\nthrottled_usec 0\n", usage.Microseconds()),
+		}
+	}
+
+	c0, dir := cachetest.AddContainer(t, cch, "ctr0", cpuStat(0))
+	c1, _ := cachetest.AddContainer(t, cch, "ctr1", cpuStat(0))
+	require.NoError(t, p.AllocateResources(c0))
+	require.NoError(t, p.AllocateResources(c1))
+	bln := p.balloonByContainer(c0)
+	require.Equal(t, bln, p.balloonByContainer(c1))
+	require.Equal(t, 1, bln.Cpus.Size())
+
+	now := time.Now()
+	require.False(t, p.resizeByUsage(now), "no previous sample")
+
+	// 95% utilization of the single CPU inflates the balloon.
+	cachetest.WriteFiles(t, dir, cpuStat(9500*time.Millisecond))
+	now = now.Add(10 * time.Second)
+	require.True(t, p.resizeByUsage(now))
+	require.Equal(t, 2, bln.Cpus.Size())
+
+	// Releasing a container does not undo usage-driven growth.
+	require.NoError(t, p.ReleaseResources(c1))
+	require.Equal(t, 2, bln.Cpus.Size())
+
+	// Samples taken too early are skipped.
+	require.False(t, p.resizeByUsage(now.Add(2*time.Second)))
+
+	// Low utilization deflates the balloon, once the cooldown has passed.
+	now = now.Add(10 * time.Second)
+	require.False(t, p.resizeByUsage(now))
+	require.Equal(t, 2, bln.Cpus.Size())
+	now = now.Add(30 * time.Second)
+	require.True(t, p.resizeByUsage(now))
+	require.Equal(t, 1, bln.Cpus.Size())
+}
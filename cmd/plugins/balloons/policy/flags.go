@@ -15,6 +15,8 @@
 package balloons
 
 import (
+	"time"
+
 	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/policy/balloons"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -22,6 +24,7 @@ import (
 type (
 	BalloonsOptions  = cfgapi.Config
 	BalloonDef       = cfgapi.BalloonDef
+	DynamicResizing  = cfgapi.DynamicResizing
 	CPUTopologyLevel = cfgapi.CPUTopologyLevel
 )
 
@@ -30,6 +33,16 @@ var (
 	defaultPinCPU             = true
 	defaultPinMemory          = true
 	defaultReservedNamespaces = []string{metav1.NamespaceSystem}
+	defaultShrinkUtilization  = cfgapi.DefaultShrinkUtilization
+	defaultDynamicResizing    = DynamicResizing{
+		Period:            metav1.Duration{Duration: 10 * time.Second},
+		GrowUtilization:   cfgapi.DefaultGrowUtilization,
+		GrowPressure:      10,
+		ShrinkUtilization: &defaultShrinkUtilization,
+		Samples:           3,
+		Cooldown:          metav1.Duration{Duration: 60 * time.Second},
+		Step:              1,
+	}
 )
 
 const (
@@ -60,4 +73,35 @@ func setOmittedDefaults(cfg *cfgapi.Config) {
 		cfg.ReservedPoolNamespaces = make([]string, len(defaultReservedNamespaces))
 		copy(cfg.ReservedPoolNamespaces, defaultReservedNamespaces)
 	}
+
+	for _, blnDef := range cfg.BalloonDefs {
+		if blnDef != nil && blnDef.DynamicResizing != nil {
+			setOmittedDynamicResizingDefaults(blnDef.DynamicResizing)
+		}
+	}
+}
+
+func setOmittedDynamicResizingDefaults(dr *DynamicResizing) {
+	if dr.Period.Duration == 0 {
+		dr.Period = defaultDynamicResizing.Period
+	}
+	if dr.GrowUtilization == 0 {
+		dr.GrowUtilization = defaultDynamicResizing.GrowUtilization
+	}
+	if dr.GrowPressure == 0 {
+		dr.GrowPressure = defaultDynamicResizing.GrowPressure
+	}
+	if dr.ShrinkUtilization == nil {
+		shrink := *defaultDynamicResizing.ShrinkUtilization
+		dr.ShrinkUtilization = &shrink
+	}
+	if dr.Samples == 0 {
+		dr.Samples = defaultDynamicResizing.Samples
+	}
+	if dr.Cooldown.Duration == 0 {
+		dr.Cooldown = defaultDynamicResizing.Cooldown
+	}
+	if dr.Step == 0 {
+		dr.Step = defaultDynamicResizing.Step
+	}
 }
@@ -0,0 +1,90 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package balloons
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/topology"
+	"github.com/containers/nri-plugins/pkg/utils/cpuset"
+	idset "github.com/intel/goresctrl/pkg/utils"
+)
+
+// containerHintCpus returns the allowed CPUs of each topology hint
+// of a container, indexed by the hint provider. Hints without any
+// allowed CPUs are left out.
+func (p *balloons) containerHintCpus(c cache.Container) map[string]cpuset.CPUSet {
+	hintCpus := map[string]cpuset.CPUSet{}
+	for provider, hint := range c.GetTopologyHints() {
+		cpus := p.hintCpus(hint).Intersection(p.allowed)
+		if cpus.IsEmpty() {
+			continue
+		}
+		hintCpus[provider] = cpus
+	}
+	return hintCpus
+}
+
+// hintCpus returns the cpuset for the CPU, NUMA or socket hints,
+// preferred in this particular order.
+func (p *balloons) hintCpus(h topology.Hint) cpuset.CPUSet {
+	var cpus cpuset.CPUSet
+
+	switch {
+	case h.CPUs != "":
+		hCpus, err := cpuset.Parse(h.CPUs)
+		if err != nil {
+			log.Warn("invalid hint CPUs '%s' from %s", h.CPUs, h.Provider)
+			return cpuset.New()
+		}
+		cpus = hCpus
+
+	case h.NUMAs != "":
+		for _, idstr := range strings.Split(h.NUMAs, ",") {
+			if id, err := strconv.ParseInt(idstr, 0, 0); err == nil {
+				if node := p.options.System.Node(idset.ID(id)); node != nil {
+					cpus = cpus.Union(node.CPUSet())
+				}
+			}
+		}
+
+	case h.Sockets != "":
+		for _, idstr := range strings.Split(h.Sockets, ",") {
+			if id, err := strconv.ParseInt(idstr, 0, 0); err == nil {
+				if pkg := p.options.System.Package(idset.ID(id)); pkg != nil {
+					cpus = cpus.Union(pkg.CPUSet())
+				}
+			}
+		}
+	}
+
+	return cpus
+}
+
+// hintScore returns how well a set of CPUs matches topology hints.
+// Each hint contributes the share of its CPUs found in the set, in
+// thousandths. A set containing all CPUs of N hints scores N*1000.
+func hintScore(hintCpus map[string]cpuset.CPUSet, cpus cpuset.CPUSet) int {
+	score := 0
+	for _, hCpus := range hintCpus {
+		if hCpus.Size() == 0 {
+			continue
+		}
+		score += 1000 * hCpus.Intersection(cpus).Size() / hCpus.Size()
+	}
+	return score
+}
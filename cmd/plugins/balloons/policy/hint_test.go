@@ -0,0 +1,76 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package balloons
+
+import (
+	"testing"
+
+	"github.com/containers/nri-plugins/pkg/utils/cpuset"
+)
+
+func TestHintScore(t *testing.T) {
+	tcases := []struct {
+		name     string
+		hintCpus map[string]cpuset.CPUSet
+		cpus     cpuset.CPUSet
+		expected int
+	}{
+		{
+			name:     "no hints",
+			cpus:     cpuset.New(0, 1),
+			expected: 0,
+		},
+		{
+			name: "no common CPUs",
+			hintCpus: map[string]cpuset.CPUSet{
+				"dev0": cpuset.New(4, 5, 6, 7),
+			},
+			cpus:     cpuset.New(0, 1),
+			expected: 0,
+		},
+		{
+			name: "partially matching hint",
+			hintCpus: map[string]cpuset.CPUSet{
+				"dev0": cpuset.New(0, 1, 2, 3),
+			},
+			cpus:     cpuset.New(0, 1),
+			expected: 500,
+		},
+		{
+			name: "two fully matching hints",
+			hintCpus: map[string]cpuset.CPUSet{
+				"dev0": cpuset.New(0, 1),
+				"dev1": cpuset.New(2),
+			},
+			cpus:     cpuset.New(0, 1, 2, 3),
+			expected: 2000,
+		},
+		{
+			name: "empty hint",
+			hintCpus: map[string]cpuset.CPUSet{
+				"dev0": cpuset.New(),
+			},
+			cpus:     cpuset.New(0, 1),
+			expected: 0,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if score := hintScore(tc.hintCpus, tc.cpus); score != tc.expected {
+				t.Errorf("expected score %d, got %d", tc.expected, score)
+			}
+		})
+	}
+}
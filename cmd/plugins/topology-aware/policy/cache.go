@@ -106,7 +106,7 @@ func (p *policy) reinstateGrants(grants map[string]Grant) error {
 			} else {
 				ug.SetMemoryZone(uZone)
 				if opt.PinMemory {
-					ug.GetContainer().SetCpusetMems(uZone.MemsetString())
+					ug.GetContainer().SetCpusetMems(grantMems(ug, uZone).MemsetString())
 				}
 				log.Info("updated grant %s to memory zone %s", uID, uZone)
 			}
@@ -124,6 +124,103 @@ func (p *policy) reinstateGrants(grants map[string]Grant) error {
 	return nil
 }
 
+// rebuildGrant reconstructs the grant of a running container from its
+// current cpuset and memset, without any state saved in the cache.
+func (p *policy) rebuildGrant(container cache.Container, cpus cpuset.CPUSet) (Grant, error) {
+	request := newRequest(container, p.memAllocator.Masks().AvailableTypes())
+
+	var (
+		cpuType   = request.CPUType()
+		full      = request.FullCPUs()
+		fraction  = request.CPUFraction()
+		exclusive = cpuset.New()
+		pool      Node
+	)
+
+	if cpuType == cpuReserved && p.root.FreeSupply().ReservedCPUs().IsEmpty() {
+		cpuType = cpuNormal
+	}
+
+	switch cpuType {
+	case cpuReserved:
+		if !cpus.IsSubsetOf(p.reserved) {
+			return nil, policyError("cpuset %q is not in reserved CPUs %q",
+				cpus, p.reserved)
+		}
+		pool = p.root
+		fraction += 1000 * full
+	case cpuNormal:
+		if full > 0 && fraction > 0 {
+			return nil, policyError("can't tell exclusive CPUs from shared ones in cpuset %q",
+				cpus)
+		}
+		if full > 0 {
+			if cpus.Size() != full {
+				return nil, policyError("cpuset %q does not match %d exclusive CPUs",
+					cpus, full)
+			}
+			exclusive = cpus
+		}
+		for _, n := range p.pools {
+			supply := n.GetSupply()
+			if !cpus.IsSubsetOf(supply.SharableCPUs().Union(supply.IsolatedCPUs())) {
+				continue
+			}
+			if pool == nil || n.RootDistance() > pool.RootDistance() {
+				pool = n
+			}
+		}
+		if pool == nil {
+			return nil, policyError("no pool with cpuset %q", cpus)
+		}
+	default:
+		return nil, policyError("can't rebuild grant for %s CPUs", cpuClassNames[cpuType])
+	}
+
+	grant := newGrant(pool, container, cpuType, exclusive, fraction, request.MemoryType(), 0)
+	grant.SetMemorySize(request.MemAmountToAllocate())
+
+	mems, err := cpuset.Parse(container.GetCpusetMems())
+	if err != nil || mems.IsEmpty() {
+		memType := request.MemoryType()
+		if memType == memoryPreserve {
+			memType = memoryAll
+		}
+		mems = cpuset.New(pool.GetMemset(memType).Members()...)
+	}
+	grant.SetMemoryZone(libmem.NewNodeMask(mems.List()...))
+
+	o, err := p.restoreMemOffer(grant)
+	if err != nil {
+		return nil, policyError("failed to get libmem offer for pool %q: %w", pool.Name(), err)
+	}
+
+	updates, err := pool.FreeSupply().Reserve(grant, o)
+	if err != nil {
+		return nil, err
+	}
+	p.grantHugePages(grant, request)
+
+	for uID, uZone := range updates {
+		if ug, ok := p.allocations.grants[uID]; ok {
+			ug.SetMemoryZone(uZone)
+			if opt.PinMemory {
+				ug.GetContainer().SetCpusetMems(grantMems(ug, uZone).MemsetString())
+			}
+		}
+	}
+
+	decision := p.newDecision(request)
+	decision.AddCandidate(pool.Name(), "", "")
+	decision.Choose(pool.Name(), "adopted current cpuset "+cpus.String())
+	container.SetDecision(decision)
+
+	p.allocations.grants[container.GetID()] = grant
+	p.saveAllocations()
+
+	return grant, nil
+}
+
 type cachedGrant struct {
 	PrettyName string
 	Exclusive  string
@@ -134,6 +231,7 @@ type cachedGrant struct {
 	MemoryPool libmem.NodeMask
 	MemType    memoryType
 	MemSize    int64
+	HugePages  nodeHugePages `json:",omitempty"`
 	ColdStart  time.Duration
 }
 
@@ -148,6 +246,7 @@ func newCachedGrant(cg Grant) *cachedGrant {
 	ccg.MemoryPool = cg.GetMemoryZone()
 	ccg.MemType = cg.MemoryType()
 	ccg.MemSize = cg.GetMemorySize()
+	ccg.HugePages = cg.GetHugePages()
 	ccg.ColdStart = cg.ColdStart()
 
 	return ccg
@@ -175,6 +274,7 @@ func (ccg *cachedGrant) ToGrant(policy *policy) (Grant, error) {
 
 	g.SetMemoryZone(ccg.MemoryPool)
 	g.SetMemorySize(ccg.MemSize)
+	g.SetHugePages(ccg.HugePages)
 
 	return g, nil
 }
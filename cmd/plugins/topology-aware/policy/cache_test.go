@@ -16,8 +16,17 @@ package topologyaware
 
 import (
 	"bytes"
+	"os"
+	"path"
 	"testing"
 
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/policy/topologyaware"
+	policyapi "github.com/containers/nri-plugins/pkg/resmgr/policy"
+	system "github.com/containers/nri-plugins/pkg/sysfs"
+	"github.com/containers/nri-plugins/pkg/utils"
 	"github.com/containers/nri-plugins/pkg/utils/cpuset"
 )
 
@@ -139,3 +148,82 @@ func TestAllocationMarshalling(t *testing.T) {
 		})
 	}
 }
+
+func TestRebuildGrant(t *testing.T) {
+	dir, err := os.MkdirTemp("", "nri-resource-policy-test-sysfs-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = utils.UncompressTbz2(path.Join("testdata", "sysfs.tar.bz2"), dir)
+	if err != nil {
+		panic(err)
+	}
+
+	sys, err := system.DiscoverSystemAt(path.Join(dir, "sysfs", "server", "sys"))
+	if err != nil {
+		panic(err)
+	}
+
+	policy := New().(*policy)
+	err = policy.Setup(&policyapi.BackendOptions{
+		Cache:  &mockCache{},
+		System: sys,
+		Config: &cfgapi.Config{
+			ReservedResources: cfgapi.Constraints{
+				cfgapi.CPU: "750m",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to setup test policy: %v", err)
+	}
+
+	leaf := findNodeWithName("NUMA node #1", policy.pools)
+	cpus := cpuset.New(leaf.GetSupply().SharableCPUs().List()[:2]...)
+
+	newContainer := func(id string, cpus cpuset.CPUSet) *mockContainer {
+		return &mockContainer{
+			returnValueForGetID: id,
+			returnValueForGetResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("2"),
+					v1.ResourceMemory: resource.MustParse("100M"),
+				},
+				Limits: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("2"),
+					v1.ResourceMemory: resource.MustParse("100M"),
+				},
+			},
+			cpusetCpus: cpus.String(),
+		}
+	}
+
+	c := newContainer("ctr0", cpus)
+	g, err := policy.rebuildGrant(c, cpus)
+	if err != nil {
+		t.Fatalf("failed to rebuild grant: %v", err)
+	}
+	if g.GetCPUNode().Name() != leaf.Name() {
+		t.Errorf("expected grant from pool %s, got %s", leaf.Name(), g.GetCPUNode().Name())
+	}
+	if !g.ExclusiveCPUs().Equals(cpus) {
+		t.Errorf("expected exclusive CPUs %s, got %s", cpus, g.ExclusiveCPUs())
+	}
+	if !leaf.FreeSupply().SharableCPUs().Intersection(cpus).IsEmpty() {
+		t.Errorf("exclusive CPUs %s not taken from pool %s", cpus, leaf.Name())
+	}
+	if d := c.GetDecision(); d == nil || d.Choice != leaf.Name() {
+		t.Errorf("expected decision choosing %s, got %s", leaf.Name(), d)
+	}
+
+	if _, err := policy.rebuildGrant(newContainer("ctr1", cpus), cpus); err == nil {
+		t.Errorf("rebuilding a grant with already taken exclusive CPUs should fail")
+	}
+
+	cpus = cpuset.New(leaf.GetSupply().SharableCPUs().List()[2:5]...)
+	if _, err := policy.rebuildGrant(newContainer("ctr2", cpus), cpus); err == nil {
+		t.Errorf("rebuilding a grant with the wrong number of exclusive CPUs should fail")
+	}
+}
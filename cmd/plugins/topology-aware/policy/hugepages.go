@@ -0,0 +1,322 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topologyaware
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/resmgr/events"
+	libmem "github.com/containers/nri-plugins/pkg/resmgr/lib/memory"
+	policyapi "github.com/containers/nri-plugins/pkg/resmgr/policy"
+	system "github.com/containers/nri-plugins/pkg/sysfs"
+	idset "github.com/intel/goresctrl/pkg/utils"
+)
+
+// hugePages is an amount of hugepages in bytes by resource name, for
+// instance hugepages-2Mi.
+type hugePages map[corev1.ResourceName]int64
+
+// nodeHugePages is an amount of hugepages by NUMA node.
+type nodeHugePages map[idset.ID]hugePages
+
+// discoverHugePages discovers the hugepage capacity of all NUMA nodes.
+func discoverHugePages(sys system.System) nodeHugePages {
+	capacity := nodeHugePages{}
+
+	for _, id := range sys.NodeIDs() {
+		info, err := sys.Node(id).HugePages()
+		if err != nil {
+			log.Error("failed to discover hugepages of NUMA node #%d: %v", id, err)
+			continue
+		}
+		for _, hp := range info {
+			if hp.Total == 0 {
+				continue
+			}
+			if capacity[id] == nil {
+				capacity[id] = hugePages{}
+			}
+			capacity[id][hugePageResourceName(hp.Size)] = int64(hp.Total * hp.Size)
+		}
+		if len(capacity[id]) > 0 {
+			log.Info("NUMA node #%d hugepages: %s", id, capacity[id])
+		}
+	}
+
+	return capacity
+}
+
+// hugePageResourceName returns the resource name for the given page size.
+func hugePageResourceName(size uint64) corev1.ResourceName {
+	qty := resource.NewQuantity(int64(size), resource.BinarySI)
+	return corev1.ResourceName(corev1.ResourceHugePagesPrefix + qty.String())
+}
+
+// hugePageRequest returns the hugepages requested by a container.
+func hugePageRequest(c cache.Container) hugePages {
+	resources, ok := c.GetResourceUpdates()
+	if !ok {
+		resources = c.GetResourceRequirements()
+	}
+
+	var req hugePages
+	for name, qty := range resources.Requests {
+		if !strings.HasPrefix(string(name), corev1.ResourceHugePagesPrefix) || qty.Value() <= 0 {
+			continue
+		}
+		if req == nil {
+			req = hugePages{}
+		}
+		req[name] = qty.Value()
+	}
+
+	return req
+}
+
+// names returns the sorted resource names of hugepages.
+func (hp hugePages) names() []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(hp))
+	for name := range hp {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// clone returns a copy of hugepages.
+func (hp hugePages) clone() hugePages {
+	c := make(hugePages, len(hp))
+	for name, amount := range hp {
+		c[name] = amount
+	}
+	return c
+}
+
+// fits checks if the given hugepages fit into these ones.
+func (hp hugePages) fits(req hugePages) bool {
+	for name, amount := range req {
+		if hp[name] < amount {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns hugepages as a string.
+func (hp hugePages) String() string {
+	entries := []string{}
+	for _, name := range hp.names() {
+		entries = append(entries, fmt.Sprintf("%s: %s", name, prettyMem(hp[name])))
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// ids returns the sorted NUMA node IDs of hugepages.
+func (nhp nodeHugePages) ids() []idset.ID {
+	ids := make([]idset.ID, 0, len(nhp))
+	for id := range nhp {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// NodeMask returns the NUMA nodes of hugepages as a mask.
+func (nhp nodeHugePages) NodeMask() libmem.NodeMask {
+	return libmem.NewNodeMask(nhp.ids()...)
+}
+
+// total returns the total amount of hugepages on all NUMA nodes.
+func (nhp nodeHugePages) total() hugePages {
+	total := hugePages{}
+	for _, hp := range nhp {
+		for name, amount := range hp {
+			total[name] += amount
+		}
+	}
+	return total
+}
+
+// clone returns a copy of hugepages.
+func (nhp nodeHugePages) clone() nodeHugePages {
+	if nhp == nil {
+		return nil
+	}
+	c := make(nodeHugePages, len(nhp))
+	for id, hp := range nhp {
+		c[id] = hp.clone()
+	}
+	return c
+}
+
+// String returns hugepages as a string.
+func (nhp nodeHugePages) String() string {
+	entries := []string{}
+	for _, id := range nhp.ids() {
+		entries = append(entries, fmt.Sprintf("#%d: %s", id, nhp[id]))
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// freeHugePages returns the hugepages not granted to any container on the
+// given NUMA nodes with hugepages.
+func (p *policy) freeHugePages(ids ...idset.ID) nodeHugePages {
+	free := nodeHugePages{}
+	for _, id := range ids {
+		if capacity, ok := p.hugePages[id]; ok {
+			free[id] = capacity.clone()
+		}
+	}
+
+	for _, g := range p.allocations.grants {
+		for id, granted := range g.GetHugePages() {
+			if hp, ok := free[id]; ok {
+				for name, amount := range granted {
+					hp[name] -= amount
+				}
+			}
+		}
+	}
+
+	return free
+}
+
+// poolHugePages returns the hugepage capacity and free hugepages of a pool.
+func (p *policy) poolHugePages(pool Node) (hugePages, hugePages) {
+	ids := pool.GetMemset(memoryAll).Members()
+	capacity := nodeHugePages{}
+	for _, id := range ids {
+		if hp, ok := p.hugePages[id]; ok {
+			capacity[id] = hp
+		}
+	}
+	return capacity.total(), p.freeHugePages(ids...).total()
+}
+
+// hugePagesFit checks if a pool has enough free hugepages for a request.
+func (p *policy) hugePagesFit(pool Node, req hugePages) bool {
+	if len(req) == 0 {
+		return true
+	}
+	_, free := p.poolHugePages(pool)
+	return free.fits(req)
+}
+
+// assignHugePages assigns the requested hugepages from the NUMA nodes of a
+// pool. If possible, hugepages are taken from a single NUMA node, preferably
+// from one of the given preferred nodes, which are typically the nodes of the
+// memory zone allocated for the container. Otherwise hugepages are spread over
+// the nodes of the pool, starting from the preferred ones.
+func (p *policy) assignHugePages(pool Node, req hugePages, prefer libmem.NodeMask) (nodeHugePages, error) {
+	if len(req) == 0 {
+		return nil, nil
+	}
+
+	free := p.freeHugePages(pool.GetMemset(memoryAll).Members()...)
+	ids := free.ids()
+	slices.SortStableFunc(ids, func(a, b idset.ID) int {
+		pa, pb := prefer.Contains(a), prefer.Contains(b)
+		switch {
+		case pa && !pb:
+			return -1
+		case !pa && pb:
+			return 1
+		}
+		return 0
+	})
+
+	for _, id := range ids {
+		if free[id].fits(req) {
+			return nodeHugePages{id: req.clone()}, nil
+		}
+	}
+
+	if !free.total().fits(req) {
+		return nil, policyError("insufficient free hugepages %s in pool %s for %s",
+			free.total(), pool.Name(), req)
+	}
+
+	assigned := nodeHugePages{}
+	for _, name := range req.names() {
+		missing := req[name]
+		for _, id := range ids {
+			if missing == 0 {
+				break
+			}
+			amount := min(missing, free[id][name])
+			if amount <= 0 {
+				continue
+			}
+			if assigned[id] == nil {
+				assigned[id] = hugePages{}
+			}
+			assigned[id][name] = amount
+			missing -= amount
+		}
+	}
+
+	return assigned, nil
+}
+
+// grantHugePages assigns the requested hugepages to a new grant.
+func (p *policy) grantHugePages(grant Grant, request Request) {
+	req := request.HugePages()
+	if len(req) == 0 {
+		return
+	}
+
+	pool := grant.GetCPUNode()
+	assigned, err := p.assignHugePages(pool, req, grant.GetMemoryZone())
+	if err != nil {
+		log.Warn("%s: %v", grant.GetContainer().PrettyName(), err)
+		p.sendPodEvent(grant.GetContainer(), events.Warning, events.InsufficientHugePages,
+			"hugepages %s not available in pool %s, memory is not pinned to their NUMA nodes",
+			req, pool.Name())
+		return
+	}
+
+	log.Info("assigned hugepages %s to %s", assigned, grant.GetContainer().PrettyName())
+	grant.SetHugePages(assigned)
+}
+
+// grantMems returns the memory nodes a grant should be pinned to, which are
+// the nodes of the allocated memory zone and the nodes with its hugepages.
+func grantMems(g Grant, zone libmem.NodeMask) libmem.NodeMask {
+	return zone.Or(g.GetHugePages().NodeMask())
+}
+
+// hugePageZoneResources returns the hugepage resources of a pool for
+// topology zones.
+func (p *policy) hugePageZoneResources(pool Node) []*policyapi.ZoneResource {
+	capacity, free := p.poolHugePages(pool)
+
+	resources := []*policyapi.ZoneResource{}
+	for _, name := range capacity.names() {
+		resources = append(resources, &policyapi.ZoneResource{
+			Name:        string(name),
+			Capacity:    *resource.NewQuantity(capacity[name], resource.BinarySI),
+			Allocatable: *resource.NewQuantity(capacity[name], resource.BinarySI),
+			Available:   *resource.NewQuantity(max(free[name], 0), resource.BinarySI),
+		})
+	}
+
+	return resources
+}
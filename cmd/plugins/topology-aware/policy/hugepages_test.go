@@ -0,0 +1,229 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topologyaware
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/policy/topologyaware"
+	libmem "github.com/containers/nri-plugins/pkg/resmgr/lib/memory"
+	policyapi "github.com/containers/nri-plugins/pkg/resmgr/policy"
+	system "github.com/containers/nri-plugins/pkg/sysfs"
+	"github.com/containers/nri-plugins/pkg/utils"
+	"github.com/containers/nri-plugins/pkg/utils/cpuset"
+)
+
+const (
+	hugePages2M = "hugepages-2Mi"
+	hugePages1G = "hugepages-1Gi"
+)
+
+func TestHugePagePlacement(t *testing.T) {
+	dir := t.TempDir()
+	if err := utils.UncompressTbz2(path.Join("testdata", "sysfs.tar.bz2"), dir); err != nil {
+		t.Fatalf("failed to uncompress test data: %v", err)
+	}
+
+	// Give NUMA node #1 512 2M pages and NUMA node #0 2 1G pages.
+	root := path.Join(dir, "sysfs", "server", "sys")
+	for file, pages := range map[string]string{
+		"node1/hugepages/hugepages-2048kB/nr_hugepages":    "512\n",
+		"node0/hugepages/hugepages-1048576kB/nr_hugepages": "2\n",
+	} {
+		err := os.WriteFile(path.Join(root, "devices", "system", "node", file), []byte(pages), 0644)
+		if err != nil {
+			t.Fatalf("failed to set up hugepages: %v", err)
+		}
+	}
+
+	sys, err := system.DiscoverSystemAt(root)
+	if err != nil {
+		t.Fatalf("failed to discover test system: %v", err)
+	}
+
+	p := New().(*policy)
+	err = p.Setup(&policyapi.BackendOptions{
+		Cache:  &mockCache{},
+		System: sys,
+		Config: &cfgapi.Config{
+			ReservedResources: cfgapi.Constraints{
+				cfgapi.CPU: "750m",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to set up policy: %v", err)
+	}
+
+	expected := nodeHugePages{
+		0: {hugePages1G: 2 << 30},
+		1: {hugePages2M: 1 << 30},
+	}
+	if !reflect.DeepEqual(p.hugePages, expected) {
+		t.Fatalf("expected hugepage capacity %s, got %s", expected, p.hugePages)
+	}
+
+	newHugePageRequest := func(hp hugePages) Request {
+		return &request{
+			full:      2,
+			memReq:    10000,
+			memLim:    10000,
+			memType:   memoryDRAM,
+			hugePages: hp,
+			container: &mockContainer{},
+		}
+	}
+
+	tcases := []struct {
+		name         string
+		request      hugePages
+		expectedPool string
+		expectedFit  bool
+		expectedHP   nodeHugePages
+	}{
+		{
+			name:         "2M pages from NUMA node #1",
+			request:      hugePages{hugePages2M: 512 << 20},
+			expectedPool: "NUMA node #1",
+			expectedFit:  true,
+			expectedHP:   nodeHugePages{1: {hugePages2M: 512 << 20}},
+		},
+		{
+			name:         "1G pages from NUMA node #0",
+			request:      hugePages{hugePages1G: 1 << 30},
+			expectedPool: "NUMA node #0",
+			expectedFit:  true,
+			expectedHP:   nodeHugePages{0: {hugePages1G: 1 << 30}},
+		},
+		{
+			name:         "2M and 1G pages from the root pool",
+			request:      hugePages{hugePages2M: 2 << 20, hugePages1G: 1 << 30},
+			expectedPool: "root",
+			expectedFit:  true,
+			expectedHP: nodeHugePages{
+				0: {hugePages1G: 1 << 30},
+				1: {hugePages2M: 2 << 20},
+			},
+		},
+		{
+			name:        "too many 2M pages",
+			request:     hugePages{hugePages2M: 2 << 30},
+			expectedFit: false,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := newHugePageRequest(tc.request)
+			scores, pools := p.sortPoolsByScore(req, nil)
+			pool := pools[0]
+
+			if fit := scores[pool.NodeID()].HugePagesFit(); fit != tc.expectedFit {
+				t.Fatalf("expected hugepages fit %v for best pool %s, got %v",
+					tc.expectedFit, pool.Name(), fit)
+			}
+			if !tc.expectedFit {
+				if _, err := p.assignHugePages(pool, tc.request, 0); err == nil {
+					t.Errorf("expected hugepage assignment from %s to fail", pool.Name())
+				}
+				return
+			}
+			if pool.Name() != tc.expectedPool {
+				t.Errorf("expected best pool %s, got %s", tc.expectedPool, pool.Name())
+			}
+
+			assigned, err := p.assignHugePages(pool, tc.request, 0)
+			if err != nil {
+				t.Fatalf("failed to assign hugepages: %v", err)
+			}
+			if !reflect.DeepEqual(assigned, tc.expectedHP) {
+				t.Errorf("expected hugepages %s, got %s", tc.expectedHP, assigned)
+			}
+		})
+	}
+
+	// Grant 768M of 2M pages from NUMA node #1, 1G of 1G pages from NUMA
+	// node #0, and check memory pinning and what is left.
+	g := newGrant(p.nodes["NUMA node #1"], &mockContainer{}, cpuNormal, cpuset.New(), 0, memoryDRAM, 0)
+	g.SetMemoryZone(libmem.NewNodeMask(1))
+	if mems := grantMems(g, g.GetMemoryZone()).MemsetString(); mems != "1" {
+		t.Errorf("expected grant mems 1, got %s", mems)
+	}
+	g.SetHugePages(nodeHugePages{0: {hugePages1G: 1 << 30}, 1: {hugePages2M: 768 << 20}})
+	if mems := grantMems(g, g.GetMemoryZone()).MemsetString(); mems != "0-1" {
+		t.Errorf("expected grant mems 0-1, got %s", mems)
+	}
+	p.allocations.grants["ctr0"] = g
+
+	req := newHugePageRequest(hugePages{hugePages2M: 512 << 20})
+	if p.hugePagesFit(p.nodes["NUMA node #1"], req.HugePages()) {
+		t.Errorf("expected 512M of 2M pages not to fit NUMA node #1 any more")
+	}
+
+	zones := map[string]*policyapi.TopologyZone{}
+	for _, z := range p.GetTopologyZones() {
+		zones[z.Name] = z
+	}
+	for name, expected := range map[string]map[string][2]string{
+		"NUMA node #1": {hugePages2M: {"1Gi", "256Mi"}},
+		"socket #1":    {hugePages2M: {"1Gi", "256Mi"}},
+		"NUMA node #0": {hugePages1G: {"2Gi", "1Gi"}},
+		"NUMA node #3": {},
+		"root":         {hugePages2M: {"1Gi", "256Mi"}, hugePages1G: {"2Gi", "1Gi"}},
+	} {
+		zone, ok := zones[name]
+		if !ok {
+			t.Fatalf("zone %s not found", name)
+		}
+		found := map[string][2]string{}
+		for _, r := range zone.Resources {
+			if r.Name == hugePages2M || r.Name == hugePages1G {
+				found[r.Name] = [2]string{r.Capacity.String(), r.Available.String()}
+			}
+		}
+		if !reflect.DeepEqual(found, expected) {
+			t.Errorf("zone %s: expected hugepages (capacity, available) %v, got %v",
+				name, expected, found)
+		}
+	}
+
+	// Check that granted hugepages survive saving to and restoring from the cache.
+	data, err := json.Marshal(newCachedGrant(g))
+	if err != nil {
+		t.Fatalf("failed to marshal grant: %v", err)
+	}
+	ccg := &cachedGrant{}
+	if err := json.Unmarshal(data, ccg); err != nil {
+		t.Fatalf("failed to unmarshal grant: %v", err)
+	}
+	if !reflect.DeepEqual(ccg.HugePages, g.GetHugePages()) {
+		t.Errorf("expected cached hugepages %s, got %s", g.GetHugePages(), ccg.HugePages)
+	}
+}
+
+func TestHugePageResourceName(t *testing.T) {
+	for size, expected := range map[uint64]string{
+		64 << 10: "hugepages-64Ki",
+		2 << 20:  hugePages2M,
+		1 << 30:  hugePages1G,
+	} {
+		if name := hugePageResourceName(size); string(name) != expected {
+			t.Errorf("expected resource name %s for page size %d, got %s", expected, size, name)
+		}
+	}
+}
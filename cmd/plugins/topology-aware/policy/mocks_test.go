@@ -34,17 +34,22 @@ import (
 )
 
 type mockSystemNode struct {
-	id       idset.ID // node id
-	memFree  uint64
-	memTotal uint64
-	memType  system.MemoryType
-	distance []int
+	id        idset.ID // node id
+	memFree   uint64
+	memTotal  uint64
+	memType   system.MemoryType
+	distance  []int
+	hugePages []*system.HugePageInfo
 }
 
 func (fake *mockSystemNode) MemoryInfo() (*system.MemInfo, error) {
 	return &system.MemInfo{MemFree: fake.memFree, MemTotal: fake.memTotal}, nil
 }
 
+func (fake *mockSystemNode) HugePages() ([]*system.HugePageInfo, error) {
+	return fake.hugePages, nil
+}
+
 func (fake *mockSystemNode) PackageID() idset.ID {
 	return 0
 }
@@ -334,6 +339,9 @@ type mockContainer struct {
 	returnValueForGetID                   string
 	returnValueForQOSClass                v1.PodQOSClass
 	pod                                   cache.Pod
+	decision                              *cache.Decision
+	cpusetCpus                            string
+	cpusetMems                            string
 }
 
 func (m *mockContainer) GetPod() (cache.Pod, bool) {
@@ -447,9 +455,11 @@ func (m *mockContainer) SetCPUPeriod(int64) {
 func (m *mockContainer) SetCPUQuota(int64) {
 	panic("unimplemented")
 }
-func (m *mockContainer) SetCpusetCpus(string) {
+func (m *mockContainer) SetCpusetCpus(cpus string) {
+	m.cpusetCpus = cpus
 }
-func (m *mockContainer) SetCpusetMems(string) {
+func (m *mockContainer) SetCpusetMems(mems string) {
+	m.cpusetMems = mems
 }
 func (m *mockContainer) SetMemoryLimit(int64) {
 	panic("unimplemented")
@@ -499,6 +509,12 @@ func (m *mockContainer) SetTag(string, string) (string, bool) {
 func (m *mockContainer) DeleteTag(string) (string, bool) {
 	panic("unimplemented")
 }
+func (m *mockContainer) SetDecision(d *cache.Decision) {
+	m.decision = d
+}
+func (m *mockContainer) GetDecision() *cache.Decision {
+	return m.decision
+}
 func (m *mockContainer) GetProcesses() ([]string, error) {
 	panic("unimplemented")
 }
@@ -515,10 +531,10 @@ func (m *mockContainer) GetCPUPeriod() int64 {
 	panic("unimplemented")
 }
 func (m *mockContainer) GetCpusetCpus() string {
-	panic("unimplemented")
+	return m.cpusetCpus
 }
 func (m *mockContainer) GetCpusetMems() string {
-	panic("unimplemented")
+	return m.cpusetMems
 }
 func (m *mockContainer) GetMemoryLimit() int64 {
 	panic("unimplemented")
@@ -701,12 +717,18 @@ func (m *mockCache) GetPolicyEntry(string, interface{}) bool {
 func (m *mockCache) Save() error {
 	return nil
 }
+func (m *mockCache) Flush() error {
+	return nil
+}
 func (m *mockCache) RefreshPods([]*nri.PodSandbox, <-chan *podresapi.PodResourcesList) ([]cache.Pod, []cache.Pod, []cache.Container) {
 	panic("unimplemented")
 }
 func (m *mockCache) RefreshContainers([]*nri.Container) ([]cache.Container, []cache.Container) {
 	panic("unimplemented")
 }
+func (m *mockCache) RefreshCpusets([]*nri.Container) {
+	panic("unimplemented")
+}
 func (m *mockCache) ContainerDirectory(string) string {
 	panic("unimplemented")
 }
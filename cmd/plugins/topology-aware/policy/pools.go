@@ -22,6 +22,7 @@ import (
 	"github.com/containers/nri-plugins/pkg/utils/cpuset"
 
 	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/resmgr/events"
 	libmem "github.com/containers/nri-plugins/pkg/resmgr/lib/memory"
 	system "github.com/containers/nri-plugins/pkg/sysfs"
 	idset "github.com/intel/goresctrl/pkg/utils"
@@ -356,6 +357,8 @@ func (p *policy) allocatePool(container cache.Container, poolHint string) (Grant
 	)
 
 	request := newRequest(container, p.memAllocator.Masks().AvailableTypes())
+	decision := p.newDecision(request)
+	p.checkMemoryType(request)
 
 	if p.root.FreeSupply().ReservedCPUs().IsEmpty() && request.CPUType() == cpuReserved {
 		// Fallback to allocating reserved CPUs from the shared pool
@@ -370,6 +373,8 @@ func (p *policy) allocatePool(container cache.Container, poolHint string) (Grant
 			return nil, policyError("failed to get offer for request %s: %v", request, err)
 		}
 		offer = o
+		decision.AddCandidate(pool.Name(), "", "")
+		decision.Choose(pool.Name(), cpuClassNames[request.CPUType()]+" CPUs are allocated from the root pool")
 	} else {
 		affinity, err := p.calculatePoolAffinities(request.GetContainer())
 
@@ -408,7 +413,11 @@ func (p *policy) allocatePool(container cache.Container, poolHint string) (Grant
 
 		if pool == nil {
 			pool = pools[0]
+			decision.Choose(pool.Name(), "best score")
+		} else {
+			decision.Choose(pool.Name(), fmt.Sprintf("hinted pool %q", poolHint))
 		}
+		p.recordPoolCandidates(decision, request, pools, pool, scores, affinity)
 
 		offer = scores[pool.NodeID()].Offer()
 		if offer == nil {
@@ -422,6 +431,7 @@ func (p *policy) allocatePool(container cache.Container, poolHint string) (Grant
 		return nil, policyError("failed to allocate %s from %s: %v",
 			request, supply.DumpAllocatable(), err)
 	}
+	p.grantHugePages(grant, request)
 
 	for id, z := range updates {
 		g, ok := p.allocations.grants[id]
@@ -431,7 +441,7 @@ func (p *policy) allocatePool(container cache.Container, poolHint string) (Grant
 			log.Info("updating memory allocation for %s to %s", g.GetContainer().PrettyName(), z)
 			g.SetMemoryZone(z)
 			if opt.PinMemory {
-				g.GetContainer().SetCpusetMems(z.MemsetString())
+				g.GetContainer().SetCpusetMems(grantMems(g, z).MemsetString())
 			}
 		}
 	}
@@ -440,12 +450,116 @@ func (p *policy) allocatePool(container cache.Container, poolHint string) (Grant
 		grant.GetMemoryZone())
 
 	p.allocations.grants[container.GetID()] = grant
+	container.SetDecision(decision)
 
 	p.saveAllocations()
 
 	return grant, nil
 }
 
+// checkMemoryType sends an event if the requested types of memory are not available.
+func (p *policy) checkMemoryType(request Request) {
+	container := request.GetContainer()
+	pod, ok := container.GetPod()
+	if !ok {
+		return
+	}
+
+	mtype := memoryTypePreference(pod, container)
+	if mtype == memoryUnspec || mtype == memoryPreserve {
+		return
+	}
+
+	available := p.memAllocator.Masks().AvailableTypes()
+	if missing := mtype.TypeMask().AndNot(available); missing != 0 {
+		p.sendPodEvent(container, events.Warning, events.MemoryTypeUnavailable,
+			"requested memory type %s is not available, using %s", missing,
+			mtype.TypeMask().And(available))
+	}
+}
+
+// sendPodEvent sends an event about the pod of a container.
+func (p *policy) sendPodEvent(c cache.Container, eventType, reason, format string, args ...interface{}) {
+	if p == nil {
+		return
+	}
+	p.options.SendPodEvent(c, eventType, reason, format, args...)
+}
+
+// hasIsolatedCPUs returns true if any of the CPUs we can use are isolated.
+func (p *policy) hasIsolatedCPUs() bool {
+	return p != nil && !p.isolated.IsEmpty()
+}
+
+// decisionAnnotationKeys are the effective annotations which affect allocation.
+var decisionAnnotationKeys = []string{
+	preferIsolatedCPUsKey,
+	preferSharedCPUsKey,
+	preferMemoryTypeKey,
+	preferColdStartKey,
+	preferReservedCPUsKey,
+	preferCpuPriorityKey,
+	hideHyperthreadsKey,
+}
+
+// newDecision creates a new allocation decision record for a request.
+func (p *policy) newDecision(request Request) *cache.Decision {
+	container := request.GetContainer()
+	decision := cache.NewDecision(PolicyName)
+	decision.Request = request.String()
+	decision.SetHints(container.GetTopologyHints())
+	for _, key := range decisionAnnotationKeys {
+		if value, ok := container.GetEffectiveAnnotation(key); ok {
+			decision.AddAnnotation(key, value)
+		}
+	}
+	return decision
+}
+
+// recordPoolCandidates records the pools considered for a request, best
+// first, with the reasons for rejecting all but the chosen one.
+func (p *policy) recordPoolCandidates(decision *cache.Decision, request Request, pools []Node,
+	chosen Node, scores map[int]Score, affinity map[int]int32) {
+	for idx, n := range pools {
+		score := fmt.Sprintf("%s, affinity %d", scores[n.NodeID()], affinity[n.NodeID()])
+		reason := ""
+		if n != chosen {
+			reason = p.poolLossReason(request, chosen, n, idx, scores, affinity)
+		}
+		decision.AddCandidate(n.Name(), score, reason)
+	}
+}
+
+// poolLossReason describes why the loser pool was not chosen over the winner.
+func (p *policy) poolLossReason(request Request, winner, loser Node, rank int,
+	scores map[int]Score, affinity map[int]int32) string {
+	w, l := scores[winner.NodeID()], scores[loser.NodeID()]
+
+	switch request.CPUType() {
+	case cpuNormal:
+		if (l.IsolatedCapacity() < 0 && w.IsolatedCapacity() >= 0) ||
+			(l.SharedCapacity() <= 0 && w.SharedCapacity() > 0) {
+			return "insufficient isolated or shared capacity"
+		}
+	case cpuReserved:
+		if l.ReservedCapacity() < 0 && w.ReservedCapacity() >= 0 {
+			return "insufficient reserved capacity"
+		}
+	}
+
+	if !l.HugePagesFit() && w.HugePagesFit() {
+		return "insufficient hugepages"
+	}
+	if affinityScore(affinity, loser) < affinityScore(affinity, winner) {
+		return "lower affinity"
+	}
+	if l.Offer() == nil && w.Offer() != nil {
+		return "no suitable memory offer"
+	}
+
+	return fmt.Sprintf("ranked #%d by pool scoring", rank+1)
+}
+
 // setPreferredCpusetCpus pins container's CPUs according to what has been
 // allocated for it, taking into account if the container should run
 // with hyperthreads hidden.
@@ -505,7 +619,7 @@ func (p *policy) applyGrant(grant Grant) {
 
 	mems := libmem.NodeMask(0)
 	if opt.PinMemory {
-		mems = grant.GetMemoryZone()
+		mems = grantMems(grant, grant.GetMemoryZone())
 	}
 
 	if opt.PinCPU {
@@ -677,6 +791,7 @@ func (p *policy) compareScores(request Request, pools []Node, scores map[int]Sco
 	// Our scoring/score sorting algorithm is:
 	//
 	//   - insufficient isolated, reserved or shared capacity loses
+	//   - insufficient free hugepages loses
 	//   - if we have affinity, the higher affinity score wins
 	//   - if only one node matches the memory type request, it wins
 	//   - if we have topology hints
@@ -718,6 +833,13 @@ func (p *policy) compareScores(request Request, pools []Node, scores map[int]Sco
 
 	log.Debug("  - isolated/reserved/shared insufficiency is a TIE")
 
+	// a node with insufficient hugepages loses
+	if h1, h2 := score1.HugePagesFit(), score2.HugePagesFit(); h1 != h2 {
+		log.Debug("  => %s loses, insufficient hugepages",
+			map[bool]string{true: node2.Name(), false: node1.Name()}[h1])
+		return h1
+	}
+
 	// higher affinity score wins
 	if a1 > a2 {
 		log.Debug("  => %s loses on affinity", node2.Name())
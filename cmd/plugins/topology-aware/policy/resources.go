@@ -25,6 +25,7 @@ import (
 	"github.com/containers/nri-plugins/pkg/cpuallocator"
 	"github.com/containers/nri-plugins/pkg/kubernetes"
 	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/resmgr/events"
 	libmem "github.com/containers/nri-plugins/pkg/resmgr/lib/memory"
 )
 
@@ -111,6 +112,8 @@ type Request interface {
 	MemoryType() memoryType
 	// MemAmountToAllocate retuns how much memory we need to reserve for a request.
 	MemAmountToAllocate() int64
+	// HugePages returns the requested hugepages.
+	HugePages() hugePages
 	// ColdStart returns the cold start timeout.
 	ColdStart() time.Duration
 }
@@ -156,6 +159,10 @@ type Grant interface {
 	SetMemoryZone(libmem.NodeMask)
 	// SetMemorySize sets the amount of memory to allocate.
 	SetMemorySize(int64)
+	// GetHugePages returns the hugepages granted, by NUMA node.
+	GetHugePages() nodeHugePages
+	// SetHugePages sets the hugepages granted, by NUMA node.
+	SetHugePages(nodeHugePages)
 	// SetColdstart sets coldstart period for the grant.
 	SetColdstart(time.Duration)
 
@@ -194,6 +201,7 @@ type Score interface {
 	Colocated() int
 	HintScores() map[string]float64
 	PrioCapacity(cpuPrio) int
+	HugePagesFit() bool
 
 	Offer() *libmem.Offer
 
@@ -223,6 +231,7 @@ type request struct {
 	memReq    int64
 	memLim    int64
 	memType   memoryType // requested types of memory
+	hugePages hugePages  // requested hugepages
 
 	// coldStart tells the timeout (in milliseconds) how long to wait until
 	// a DRAM memory controller should be added to a container asking for a
@@ -246,6 +255,7 @@ type grant struct {
 	coldStartTimer *time.Timer     // timer to trigger cold start timeout
 	memSize        int64           // amount of memory to allocate
 	memZone        libmem.NodeMask // allocated memory zone
+	hugePages      nodeHugePages   // granted hugepages by NUMA node
 }
 
 var _ Grant = &grant{}
@@ -261,6 +271,7 @@ type score struct {
 	prio      map[cpuPrio]int    // low/normal/high-prio CPU capacity
 	colocated int                // number of colocated containers
 	hints     map[string]float64 // hint scores
+	hugePages bool               // whether free hugepages fit the request
 }
 
 var _ Score = &score{}
@@ -389,6 +400,9 @@ func (cs *supply) AllocateCPU(r Request) (Grant, error) {
 
 	if cpuType == cpuReserved && full > 0 {
 		log.Warn("exclusive reserved CPUs not supported, allocating %d full CPUs as fractions", full)
+		cs.node.Policy().sendPodEvent(cr.GetContainer(), events.Warning, events.InsufficientExclusiveCPUs,
+			"%d exclusive CPUs requested, exclusive reserved CPUs not supported, using shared reserved CPUs instead",
+			full)
 		fraction += full * 1000
 		full = 0
 	}
@@ -399,6 +413,9 @@ func (cs *supply) AllocateCPU(r Request) (Grant, error) {
 		log.Warn("  %s: needs %d reserved, only %d available",
 			cr.GetContainer().PrettyName(), fraction, cs.AllocatableReservedCPU())
 		log.Warn("  falling back to using normal unreserved CPUs instead...")
+		cs.node.Policy().sendPodEvent(cr.GetContainer(), events.Warning, events.InsufficientReservedCPUs,
+			"%dm reserved CPU needed, only %dm available in pool %s, using normal CPUs instead",
+			fraction, cs.AllocatableReservedCPU(), cs.node.Name())
 		cpuType = cpuNormal
 	}
 
@@ -419,8 +436,16 @@ func (cs *supply) AllocateCPU(r Request) (Grant, error) {
 				"%s: can't take %d exclusive CPUs from %s: %v",
 				cs.node.Name(), full, cs.sharable, err)
 		}
+		if cr.isolate && cs.node.Policy().hasIsolatedCPUs() {
+			cs.node.Policy().sendPodEvent(cr.GetContainer(), events.Warning, events.InsufficientIsolatedCPUs,
+				"%d isolated CPUs preferred, only %d available in pool %s, using normal exclusive CPUs %s instead",
+				full, cs.isolated.Size(), cs.node.Name(), exclusive)
+		}
 
 	case full > 0:
+		cs.node.Policy().sendPodEvent(cr.GetContainer(), events.Warning, events.InsufficientExclusiveCPUs,
+			"%d exclusive CPUs requested, only %dm CPU available in pool %s",
+			full, cs.AllocatableSharedCPU(), cs.node.Name())
 		return nil, policyError("internal error: "+
 			"%s: can't slice %d exclusive CPUs from %s, %dm available",
 			cs.node.Name(), full, cs.sharable, cs.AllocatableSharedCPU())
@@ -686,6 +711,7 @@ func newRequest(container cache.Container, types libmem.TypeMask) Request {
 		memReq:    req,
 		memLim:    lim,
 		memType:   mtype,
+		hugePages: hugePageRequest(container),
 		coldStart: coldStart,
 		prio:      prio,
 	}
@@ -700,6 +726,9 @@ func (cr *request) GetContainer() cache.Container {
 func (cr *request) String() string {
 	mem := fmt.Sprintf("<Memory request: limit: %s, req: %s>",
 		prettyMem(cr.memLim), prettyMem(cr.memReq))
+	if len(cr.hugePages) > 0 {
+		mem += fmt.Sprintf(" <HugePages request: %s>", cr.hugePages)
+	}
 	isolated := map[bool]string{false: "", true: "isolated "}[cr.isolate]
 	switch {
 	case cr.full == 0 && cr.fraction == 0:
@@ -756,6 +785,11 @@ func (cr *request) MemAmountToAllocate() int64 {
 	return cr.memLim
 }
 
+// HugePages returns the requested hugepages.
+func (cr *request) HugePages() hugePages {
+	return cr.hugePages
+}
+
 // MemoryType returns the requested type of memory for the grant.
 func (cr *request) MemoryType() memoryType {
 	return cr.memType
@@ -829,6 +863,9 @@ func (cs *supply) GetScore(req Request) Score {
 		}
 	}
 
+	// check if free hugepages fit the request
+	score.hugePages = cs.node.Policy().hugePagesFit(cs.node, cr.hugePages)
+
 	// calculate real hint scores
 	hints := cr.container.GetTopologyHints()
 	hints.ResolvePartialHints(cs.GetNode().System().NodeHintToCPUs)
@@ -937,13 +974,21 @@ func (score *score) PrioCapacity(prio cpuPrio) int {
 	return score.prio[prio]
 }
 
+func (score *score) HugePagesFit() bool {
+	return score.hugePages
+}
+
 func (score *score) Offer() *libmem.Offer {
 	return score.offer
 }
 
 func (score *score) String() string {
-	return fmt.Sprintf("<CPU score: node %s, isolated:%d, reserved:%d, shared:%d, colocated:%d, hints: %v>",
-		score.supply.GetNode().Name(), score.isolated, score.reserved, score.shared, score.colocated, score.hints)
+	hugePages := ""
+	if len(score.req.HugePages()) > 0 {
+		hugePages = fmt.Sprintf(", hugepages fit: %v", score.hugePages)
+	}
+	return fmt.Sprintf("<CPU score: node %s, isolated:%d, reserved:%d, shared:%d, colocated:%d, hints: %v%s>",
+		score.supply.GetNode().Name(), score.isolated, score.reserved, score.shared, score.colocated, score.hints, hugePages)
 }
 
 // newGrant creates a CPU grant from the given node for the container.
@@ -980,6 +1025,16 @@ func (cg *grant) SetMemorySize(size int64) {
 	cg.memSize = size
 }
 
+// GetHugePages returns the hugepages granted, by NUMA node.
+func (cg *grant) GetHugePages() nodeHugePages {
+	return cg.hugePages
+}
+
+// SetHugePages sets the hugepages granted, by NUMA node.
+func (cg *grant) SetHugePages(hugePages nodeHugePages) {
+	cg.hugePages = hugePages
+}
+
 // SetColdstart sets coldstart period for the grant.
 func (cg *grant) SetColdstart(period time.Duration) {
 	cg.coldStart = period
@@ -996,6 +1051,7 @@ func (cg *grant) Clone() Grant {
 		memType:    cg.MemoryType(),
 		memZone:    cg.GetMemoryZone(),
 		memSize:    cg.GetMemorySize(),
+		hugePages:  cg.GetHugePages().clone(),
 		coldStart:  cg.ColdStart(),
 	}
 }
@@ -1102,6 +1158,9 @@ func (cg *grant) String() string {
 	}
 
 	mem := fmt.Sprintf(", memory: %s (%s)", cg.memZone, prettyMem(cg.memSize))
+	if len(cg.hugePages) > 0 {
+		mem += fmt.Sprintf(", hugepages: %s", cg.hugePages)
+	}
 
 	return fmt.Sprintf("<grant for %s from %s: %s%s%s%s%s%s>",
 		cg.container.PrettyName(), cg.node.Name(), cpuType, isolated, exclusive, reserved, shared, mem)
@@ -17,6 +17,7 @@ package topologyaware
 import (
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/containers/nri-plugins/pkg/utils/cpuset"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -25,6 +26,7 @@ import (
 	"github.com/containers/nri-plugins/pkg/cpuallocator"
 	"github.com/containers/nri-plugins/pkg/resmgr/cache"
 	"github.com/containers/nri-plugins/pkg/resmgr/events"
+	"github.com/containers/nri-plugins/pkg/resmgr/introspect"
 	libmem "github.com/containers/nri-plugins/pkg/resmgr/lib/memory"
 
 	policyapi "github.com/containers/nri-plugins/pkg/resmgr/policy"
@@ -65,6 +67,7 @@ type policy struct {
 	allocations  allocations               // container pool assignments
 	cpuAllocator cpuallocator.CPUAllocator // CPU allocator used by the policy
 	memAllocator *libmem.Allocator
+	hugePages    nodeHugePages // hugepage capacity by NUMA node
 	metrics      *TopologyAwareMetrics
 }
 
@@ -101,6 +104,7 @@ func (p *policy) Setup(opts *policyapi.BackendOptions) error {
 	if err != nil {
 		return policyError("failed to initialize %s policy: %w", err)
 	}
+	p.hugePages = discoverHugePages(opts.System)
 
 	opt = cfg
 	defaultPrio = cfg.DefaultCPUPriority.Value()
@@ -280,6 +284,41 @@ func (p *policy) UpdateResources(container cache.Container) error {
 	return nil
 }
 
+// AdoptResources keeps the allocation of a running container restored from
+// the cache, if it matches the current cpuset of the container. Without a
+// restored allocation, it rebuilds one from the current cpuset.
+func (p *policy) AdoptResources(container cache.Container) error {
+	cpus, err := cpuset.Parse(container.GetCpusetCpus())
+	if err != nil || cpus.IsEmpty() {
+		return policyError("invalid cpuset %q of %s", container.GetCpusetCpus(),
+			container.PrettyName())
+	}
+
+	grant, ok := p.allocations.grants[container.GetID()]
+	if !ok {
+		grant, err = p.rebuildGrant(container, cpus)
+		if err != nil {
+			return policyError("failed to rebuild allocation of %s: %v",
+				container.PrettyName(), err)
+		}
+		log.Info("adopting rebuilt allocation %s of %s", grant, container.PrettyName())
+		p.applyGrant(grant)
+		p.updateSharedAllocations(&grant)
+		return nil
+	}
+
+	supply := grant.GetCPUNode().GetSupply()
+	poolCPUs := supply.SharableCPUs().Union(supply.IsolatedCPUs()).Union(supply.ReservedCPUs())
+	if !grant.ExclusiveCPUs().IsSubsetOf(cpus) || !cpus.IsSubsetOf(poolCPUs) {
+		return policyError("cpuset %q of %s does not match its allocation %s",
+			container.GetCpusetCpus(), container.PrettyName(), grant)
+	}
+
+	log.Info("adopting allocation %s of %s", grant, container.PrettyName())
+
+	return nil
+}
+
 // HandleEvent handles policy-specific events.
 func (p *policy) HandleEvent(e *events.Policy) (bool, error) {
 	log.Debug("received policy event %s.%s with data %v...", e.Source, e.Type, e.Data)
@@ -306,10 +345,30 @@ func (p *policy) HandleEvent(e *events.Policy) (bool, error) {
 		}
 		log.Info("finishing coldstart period for %s", c.PrettyName())
 		return p.finishColdStart(c)
+	case events.Rebalance:
+		return true, p.rebalance()
 	}
 	return false, nil
 }
 
+// rebalance reallocates resources for all containers with allocations.
+func (p *policy) rebalance() error {
+	containers := make([]cache.Container, 0, len(p.allocations.grants))
+	for id := range p.allocations.grants {
+		if c, ok := p.cache.LookupContainer(id); ok {
+			containers = append(containers, c)
+		}
+	}
+
+	if err := p.reallocateResources(containers, nil); err != nil {
+		return policyError("rebalancing failed: %w", err)
+	}
+
+	p.root.Dump("<post-rebalance>")
+
+	return nil
+}
+
 // GetTopologyZones returns the policy/pool data for 'topology zone' CRDs.
 func (p *policy) GetTopologyZones() []*policyapi.TopologyZone {
 	zones := []*policyapi.TopologyZone{}
@@ -338,6 +397,7 @@ func (p *policy) GetTopologyZones() []*policyapi.TopologyZone {
 			Available:   *resource.NewQuantity(available, resource.DecimalSI),
 		}
 		zone.Resources = append(zone.Resources, memory)
+		zone.Resources = append(zone.Resources, p.hugePageZoneResources(pool)...)
 
 		attributes := []*policyapi.ZoneAttribute{
 			{
@@ -384,6 +444,39 @@ func (p *policy) GetTopologyZones() []*policyapi.TopologyZone {
 	return zones
 }
 
+// GetPools returns the pools of the pool tree for introspection.
+func (p *policy) GetPools() []*introspect.Pool {
+	containers := map[string][]string{}
+	for id, grant := range p.allocations.grants {
+		pool := grant.GetCPUNode().Name()
+		containers[pool] = append(containers[pool], id)
+	}
+
+	pools := make([]*introspect.Pool, 0, len(p.pools))
+	for _, node := range p.pools {
+		total := node.GetSupply()
+		free := node.FreeSupply()
+		pool := &introspect.Pool{
+			Name: node.Name(),
+			Type: string(node.Kind()),
+			CPUs: total.SharableCPUs().Union(total.ReservedCPUs()).
+				Union(total.IsolatedCPUs()).String(),
+			SharedCPUs:   free.SharableCPUs().String(),
+			ReservedCPUs: total.ReservedCPUs().String(),
+			IsolatedCPUs: total.IsolatedCPUs().String(),
+			Mems:         node.GetMemset(memoryAll).String(),
+			Containers:   containers[node.Name()],
+		}
+		if !node.IsRootNode() {
+			pool.Parent = node.Parent().Name()
+		}
+		sort.Strings(pool.Containers)
+		pools = append(pools, pool)
+	}
+
+	return pools
+}
+
 // ExportResourceData provides resource data to export for the container.
 func (p *policy) ExportResourceData(c cache.Container) map[string]string {
 	grant, ok := p.allocations.grants[c.GetID()]
@@ -0,0 +1,108 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	logger "github.com/containers/nri-plugins/pkg/log"
+	"github.com/containers/nri-plugins/pkg/sysfs"
+	"github.com/containers/nri-plugins/pkg/topology"
+)
+
+var (
+	log = logger.Default()
+)
+
+func main() {
+	var (
+		sysfsPath    string
+		configFile   string
+		scenarioFile string
+		stateDir     string
+		zones        bool
+	)
+
+	flag.StringVar(&sysfsPath, "sysfs", "",
+		"sysfs snapshot to simulate, a tarball or a directory containing sys")
+	flag.StringVar(&configFile, "config", "",
+		"policy configuration custom resource (YAML) to simulate")
+	flag.StringVar(&scenarioFile, "scenario", "",
+		"scenario (YAML) of pod and container events to replay")
+	flag.StringVar(&stateDir, "state-dir", "",
+		"directory to store simulated state in, a temporary one by default")
+	flag.BoolVar(&zones, "zones", true,
+		"print topology zones after each step")
+	flag.Parse()
+
+	if sysfsPath == "" || configFile == "" || scenarioFile == "" {
+		log.Errorf("-sysfs, -config and -scenario are mandatory")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	err := run(sysfsPath, configFile, scenarioFile, stateDir, zones)
+	logger.Flush()
+
+	if err != nil {
+		log.Errorf("%v", err)
+		os.Exit(1)
+	}
+}
+
+// run simulates a scenario. Temporary files are removed before returning.
+func run(sysfsPath, configFile, scenarioFile, stateDir string, zones bool) error {
+	tmpDir, err := os.MkdirTemp("", "policy-simulator-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sysRoot := sysfsPath
+	if info, err := os.Stat(sysfsPath); err != nil {
+		return fmt.Errorf("failed to access sysfs snapshot: %w", err)
+	} else if !info.IsDir() {
+		sysRoot, err = extractSysfs(sysfsPath, tmpDir)
+		if err != nil {
+			return err
+		}
+	} else if sysRoot, err = findSysRoot(sysfsPath); err != nil {
+		return err
+	}
+	sysfs.SetSysRoot(sysRoot)
+	topology.SetSysRoot(sysRoot)
+
+	if stateDir == "" {
+		stateDir = tmpDir + "/state"
+	}
+
+	scenario, err := ReadScenario(scenarioFile)
+	if err != nil {
+		return err
+	}
+
+	sim, err := NewSimulator(configFile, stateDir, zones)
+	if err != nil {
+		return err
+	}
+
+	if failed := sim.Run(scenario, os.Stdout); failed > 0 {
+		return fmt.Errorf("%d step(s) of the scenario failed", failed)
+	}
+
+	return nil
+}
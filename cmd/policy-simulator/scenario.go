@@ -0,0 +1,228 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	nri "github.com/containerd/nri/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/containers/nri-plugins/pkg/kubernetes"
+)
+
+// Scenario is a scripted sequence of pod and container events.
+type Scenario struct {
+	Steps []*Step `json:"steps"`
+}
+
+// Step is a single event of a scenario. Exactly one of the fields
+// must be set.
+type Step struct {
+	// Create creates a container, and its pod if necessary.
+	Create *Workload `json:"create,omitempty"`
+	// Update updates the resources of a container.
+	Update *Workload `json:"update,omitempty"`
+	// Remove removes a container, or a pod with all its containers
+	// if no container is given.
+	Remove *Workload `json:"remove,omitempty"`
+}
+
+// Workload identifies a pod or a container of a scenario step.
+type Workload struct {
+	// Pod is the name of the pod.
+	Pod string `json:"pod"`
+	// Namespace is the namespace of the pod, default by default.
+	Namespace string `json:"namespace,omitempty"`
+	// Container is the name of the container.
+	Container string `json:"container,omitempty"`
+	// QOSClass is the QoS class of the pod. If omitted, it is
+	// determined by the resources of the first container created.
+	QOSClass corev1.PodQOSClass `json:"qosClass,omitempty"`
+	// Labels are the labels of the pod.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are the annotations of the pod.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Resources are the resource requirements of the container.
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// ReadScenario reads a scenario from the given file.
+func ReadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+
+	s := &Scenario{}
+	if err := yaml.UnmarshalStrict(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", path, err)
+	}
+
+	for i, step := range s.Steps {
+		if err := step.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid scenario step #%d: %w", i+1, err)
+		}
+	}
+
+	return s, nil
+}
+
+// Validate checks that a step is well-formed.
+func (s *Step) Validate() error {
+	var w *Workload
+
+	cnt := 0
+	for _, sw := range []*Workload{s.Create, s.Update, s.Remove} {
+		if sw != nil {
+			w = sw
+			cnt++
+		}
+	}
+	if cnt != 1 {
+		return fmt.Errorf("expected exactly one of create, update or remove")
+	}
+
+	if w.Pod == "" {
+		return fmt.Errorf("missing pod name")
+	}
+	if w.Container == "" && s.Remove == nil {
+		return fmt.Errorf("missing container name for pod %s", w.Pod)
+	}
+
+	return nil
+}
+
+// String returns a short description of a step.
+func (s *Step) String() string {
+	switch {
+	case s.Create != nil:
+		return "create " + s.Create.String()
+	case s.Update != nil:
+		return "update " + s.Update.String()
+	case s.Remove != nil:
+		return "remove " + s.Remove.String()
+	}
+	return "<invalid step>"
+}
+
+// String returns the namespaced name of the workload.
+func (w *Workload) String() string {
+	name := w.GetNamespace() + "/" + w.Pod
+	if w.Container != "" {
+		name += "/" + w.Container
+	}
+	return name
+}
+
+// GetNamespace returns the namespace of the workload.
+func (w *Workload) GetNamespace() string {
+	if w.Namespace == "" {
+		return "default"
+	}
+	return w.Namespace
+}
+
+// GetQOSClass returns the QoS class of the workload.
+func (w *Workload) GetQOSClass() corev1.PodQOSClass {
+	if w.QOSClass != "" {
+		return w.QOSClass
+	}
+
+	r := &w.Resources
+	if len(r.Requests) == 0 && len(r.Limits) == 0 {
+		return corev1.PodQOSBestEffort
+	}
+
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		limit, ok := r.Limits[name]
+		if !ok {
+			return corev1.PodQOSBurstable
+		}
+		if request, ok := r.Requests[name]; ok && request.Cmp(limit) != 0 {
+			return corev1.PodQOSBurstable
+		}
+	}
+
+	return corev1.PodQOSGuaranteed
+}
+
+// NRIPod returns the NRI pod for the workload.
+func (w *Workload) NRIPod(id string) *nri.PodSandbox {
+	parent := "/kubepods"
+	switch qos := w.GetQOSClass(); qos {
+	case corev1.PodQOSBestEffort:
+		parent += "/besteffort"
+	case corev1.PodQOSBurstable:
+		parent += "/burstable"
+	}
+	parent += "/pod" + id
+
+	return &nri.PodSandbox{
+		Id:          id,
+		Name:        w.Pod,
+		Uid:         id,
+		Namespace:   w.GetNamespace(),
+		Labels:      w.Labels,
+		Annotations: w.Annotations,
+		Linux: &nri.LinuxPodSandbox{
+			CgroupParent: parent,
+		},
+	}
+}
+
+// NRIContainer returns the NRI container for the workload.
+func (w *Workload) NRIContainer(id, podID string) *nri.Container {
+	return &nri.Container{
+		Id:           id,
+		PodSandboxId: podID,
+		Name:         w.Container,
+		State:        nri.ContainerState_CONTAINER_CREATED,
+		Linux: &nri.LinuxContainer{
+			Resources: w.LinuxResources(),
+		},
+	}
+}
+
+// LinuxResources returns the container resources the way kubelet would
+// set them up for the resource requirements of the workload.
+func (w *Workload) LinuxResources() *nri.LinuxResources {
+	r := &w.Resources
+	cpu := &nri.LinuxCPU{}
+	mem := &nri.LinuxMemory{}
+
+	request, ok := r.Requests[corev1.ResourceCPU]
+	if !ok {
+		request = r.Limits[corev1.ResourceCPU]
+	}
+	cpu.Shares = nri.UInt64(kubernetes.MilliCPUToShares(request.MilliValue()))
+
+	if limit, ok := r.Limits[corev1.ResourceCPU]; ok {
+		quota, period := kubernetes.MilliCPUToQuota(limit.MilliValue())
+		cpu.Quota = nri.Int64(quota)
+		cpu.Period = nri.UInt64(uint64(period))
+	}
+
+	if limit, ok := r.Limits[corev1.ResourceMemory]; ok {
+		mem.Limit = nri.Int64(limit.Value())
+	}
+
+	return &nri.LinuxResources{
+		Cpu:    cpu,
+		Memory: mem,
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestReadScenario(t *testing.T) {
+	tcases := []struct {
+		name  string
+		yaml  string
+		steps []string
+		err   string
+	}{
+		{
+			name: "valid scenario",
+			yaml: `
+steps:
+- create:
+    pod: pod0
+    container: ctr0
+    resources:
+      requests:
+        cpu: 1
+- update:
+    pod: pod0
+    namespace: kube-system
+    container: ctr0
+- remove:
+    pod: pod0
+`,
+			steps: []string{
+				"create default/pod0/ctr0",
+				"update kube-system/pod0/ctr0",
+				"remove default/pod0",
+			},
+		},
+		{
+			name: "unknown field",
+			yaml: `
+steps:
+- create:
+    pod: pod0
+    container: ctr0
+    cpus: 1
+`,
+			err: "failed to parse scenario",
+		},
+		{
+			name: "invalid step",
+			yaml: `
+steps:
+- remove:
+    pod: pod0
+- update:
+    container: ctr0
+`,
+			err: "invalid scenario step #2: missing pod name",
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scenario.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.yaml), 0644))
+
+			s, err := ReadScenario(path)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			steps := []string{}
+			for _, step := range s.Steps {
+				steps = append(steps, step.String())
+			}
+			require.Equal(t, tc.steps, steps)
+		})
+	}
+
+	_, err := ReadScenario(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorContains(t, err, "failed to read scenario")
+}
+
+func TestStepValidate(t *testing.T) {
+	tcases := []struct {
+		name string
+		step *Step
+		err  string
+	}{
+		{
+			name: "create container",
+			step: &Step{Create: &Workload{Pod: "pod0", Container: "ctr0"}},
+		},
+		{
+			name: "update container",
+			step: &Step{Update: &Workload{Pod: "pod0", Container: "ctr0"}},
+		},
+		{
+			name: "remove container",
+			step: &Step{Remove: &Workload{Pod: "pod0", Container: "ctr0"}},
+		},
+		{
+			name: "remove pod",
+			step: &Step{Remove: &Workload{Pod: "pod0"}},
+		},
+		{
+			name: "no action",
+			step: &Step{},
+			err:  "expected exactly one of create, update or remove",
+		},
+		{
+			name: "multiple actions",
+			step: &Step{
+				Create: &Workload{Pod: "pod0", Container: "ctr0"},
+				Remove: &Workload{Pod: "pod0"},
+			},
+			err: "expected exactly one of create, update or remove",
+		},
+		{
+			name: "missing pod",
+			step: &Step{Create: &Workload{Container: "ctr0"}},
+			err:  "missing pod name",
+		},
+		{
+			name: "create without container",
+			step: &Step{Create: &Workload{Pod: "pod0"}},
+			err:  "missing container name for pod pod0",
+		},
+		{
+			name: "update without container",
+			step: &Step{Update: &Workload{Pod: "pod0"}},
+			err:  "missing container name for pod pod0",
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.step.Validate()
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetQOSClass(t *testing.T) {
+	list := func(cpu, mem string) corev1.ResourceList {
+		l := corev1.ResourceList{}
+		if cpu != "" {
+			l[corev1.ResourceCPU] = resource.MustParse(cpu)
+		}
+		if mem != "" {
+			l[corev1.ResourceMemory] = resource.MustParse(mem)
+		}
+		return l
+	}
+
+	tcases := []struct {
+		name     string
+		workload *Workload
+		qos      corev1.PodQOSClass
+	}{
+		{
+			name:     "no resources",
+			workload: &Workload{},
+			qos:      corev1.PodQOSBestEffort,
+		},
+		{
+			name: "explicit QoS class",
+			workload: &Workload{
+				QOSClass:  corev1.PodQOSBurstable,
+				Resources: corev1.ResourceRequirements{},
+			},
+			qos: corev1.PodQOSBurstable,
+		},
+		{
+			name: "requests only",
+			workload: &Workload{
+				Resources: corev1.ResourceRequirements{
+					Requests: list("1", "1G"),
+				},
+			},
+			qos: corev1.PodQOSBurstable,
+		},
+		{
+			name: "CPU limit only",
+			workload: &Workload{
+				Resources: corev1.ResourceRequirements{
+					Limits: list("1", ""),
+				},
+			},
+			qos: corev1.PodQOSBurstable,
+		},
+		{
+			name: "requests below limits",
+			workload: &Workload{
+				Resources: corev1.ResourceRequirements{
+					Requests: list("500m", "1G"),
+					Limits:   list("1", "1G"),
+				},
+			},
+			qos: corev1.PodQOSBurstable,
+		},
+		{
+			name: "requests equal to limits",
+			workload: &Workload{
+				Resources: corev1.ResourceRequirements{
+					Requests: list("1", "1G"),
+					Limits:   list("1000m", "1G"),
+				},
+			},
+			qos: corev1.PodQOSGuaranteed,
+		},
+		{
+			name: "limits only",
+			workload: &Workload{
+				Resources: corev1.ResourceRequirements{
+					Limits: list("2", "2G"),
+				},
+			},
+			qos: corev1.PodQOSGuaranteed,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.qos, tc.workload.GetQOSClass())
+		})
+	}
+}
@@ -0,0 +1,354 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	balloons "github.com/containers/nri-plugins/cmd/plugins/balloons/policy"
+	template "github.com/containers/nri-plugins/cmd/plugins/template/policy"
+	topologyaware "github.com/containers/nri-plugins/cmd/plugins/topology-aware/policy"
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1"
+	logger "github.com/containers/nri-plugins/pkg/log"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/resmgr/events"
+	"github.com/containers/nri-plugins/pkg/resmgr/policy"
+)
+
+// Simulator replays scenarios through a policy backend.
+type Simulator struct {
+	cache      cache.Cache
+	policy     policy.Policy
+	pods       map[string]string // pod IDs by namespaced pod name
+	containers map[string]string // container IDs by namespaced container name
+	nextID     int
+	errors     int
+	zones      bool
+}
+
+// NewSimulator creates a simulator for the given policy configuration,
+// keeping the state of its cache in the given directory.
+func NewSimulator(configFile, stateDir string, zones bool) (*Simulator, error) {
+	backend, cfg, err := readPolicyConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := logger.Configure(&cfg.CommonConfig().Log); err != nil {
+		log.Warnf("failed to configure logger: %v", err)
+	}
+
+	cch, err := cache.NewCache(cache.Options{CacheDir: stateDir})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache: %w", err)
+	}
+	if err := cch.SetActivePolicy(backend.Name()); err != nil {
+		return nil, fmt.Errorf("failed to set active policy: %w", err)
+	}
+
+	s := &Simulator{
+		cache:      cch,
+		pods:       map[string]string{},
+		containers: map[string]string{},
+		zones:      zones,
+	}
+
+	p, err := policy.NewPolicy(backend, cch, &policy.Options{SendEvent: s.sendEvent})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create policy: %w", err)
+	}
+	if err := p.Start(cfg.PolicyConfig()); err != nil {
+		return nil, fmt.Errorf("failed to start policy: %w", err)
+	}
+	s.policy = p
+
+	return s, nil
+}
+
+// Run replays the steps of a scenario, printing the resulting state
+// after each step. It returns the number of steps which failed.
+func (s *Simulator) Run(scenario *Scenario, out io.Writer) int {
+	fmt.Fprintf(out, "initial state:\n")
+	s.dump(out)
+
+	for i, step := range scenario.Steps {
+		fmt.Fprintf(out, "step #%d: %s\n", i+1, step)
+
+		var err error
+		switch {
+		case step.Create != nil:
+			err = s.create(step.Create)
+		case step.Update != nil:
+			err = s.update(step.Update)
+		case step.Remove != nil:
+			err = s.remove(step.Remove)
+		}
+		if err != nil {
+			fmt.Fprintf(out, "  error: %v\n", err)
+			s.errors++
+		}
+
+		s.dump(out)
+	}
+
+	return s.errors
+}
+
+func (s *Simulator) create(w *Workload) error {
+	podKey := w.GetNamespace() + "/" + w.Pod
+	podID, ok := s.pods[podKey]
+	if !ok {
+		podID = s.newID("pod")
+		s.cache.InsertPod(w.NRIPod(podID), nil)
+		s.pods[podKey] = podID
+	}
+
+	if _, ok := s.containers[w.String()]; ok {
+		return fmt.Errorf("container %s already exists", w)
+	}
+
+	c, err := s.cache.InsertContainer(w.NRIContainer(s.newID("ctr"), podID))
+	if err != nil {
+		return err
+	}
+	c.UpdateState(cache.ContainerStateCreating)
+
+	if err := s.policy.AllocateResources(c); err != nil {
+		c.UpdateState(cache.ContainerStateStale)
+		s.cache.DeleteContainer(c.GetID())
+		return fmt.Errorf("failed to allocate resources: %w", err)
+	}
+	s.containers[w.String()] = c.GetID()
+	c.UpdateState(cache.ContainerStateRunning)
+
+	e := &events.Policy{
+		Type:   events.ContainerStarted,
+		Source: "policy-simulator",
+		Data:   c,
+	}
+	if _, err := s.policy.HandleEvent(e); err != nil {
+		return fmt.Errorf("policy failed to handle event %s: %w", e.Type, err)
+	}
+
+	return nil
+}
+
+func (s *Simulator) update(w *Workload) error {
+	c, err := s.lookupContainer(w)
+	if err != nil {
+		return err
+	}
+
+	if !c.SetResourceUpdates(w.LinuxResources()) {
+		return nil
+	}
+
+	if err := s.policy.UpdateResources(c); err != nil {
+		return fmt.Errorf("failed to update resources: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Simulator) remove(w *Workload) error {
+	if w.Container != "" {
+		c, err := s.lookupContainer(w)
+		if err != nil {
+			return err
+		}
+		return s.removeContainer(w.String(), c)
+	}
+
+	podKey := w.GetNamespace() + "/" + w.Pod
+	podID, ok := s.pods[podKey]
+	if !ok {
+		return fmt.Errorf("unknown pod %s", podKey)
+	}
+	pod, ok := s.cache.LookupPod(podID)
+	if !ok {
+		return fmt.Errorf("pod %s not found in cache", podKey)
+	}
+
+	for _, c := range pod.GetContainers() {
+		if err := s.removeContainer(podKey+"/"+c.GetName(), c); err != nil {
+			return err
+		}
+	}
+
+	s.cache.DeletePod(podID)
+	delete(s.pods, podKey)
+
+	return nil
+}
+
+func (s *Simulator) removeContainer(key string, c cache.Container) error {
+	delete(s.containers, key)
+	defer s.cache.DeleteContainer(c.GetID())
+
+	if err := s.policy.ReleaseResources(c); err != nil {
+		return fmt.Errorf("failed to release resources: %w", err)
+	}
+	c.UpdateState(cache.ContainerStateExited)
+
+	return nil
+}
+
+func (s *Simulator) lookupContainer(w *Workload) (cache.Container, error) {
+	id, ok := s.containers[w.String()]
+	if !ok {
+		return nil, fmt.Errorf("unknown container %s", w)
+	}
+	c, ok := s.cache.LookupContainer(id)
+	if !ok {
+		return nil, fmt.Errorf("container %s not found in cache", w)
+	}
+	return c, nil
+}
+
+func (s *Simulator) newID(prefix string) string {
+	id := fmt.Sprintf("%s%d", prefix, s.nextID)
+	s.nextID++
+	return id
+}
+
+// sendEvent swallows events sent by the policy. There is no resource
+// manager to deliver them to, and timers are not simulated.
+func (s *Simulator) sendEvent(e interface{}) error {
+	if pe, ok := e.(*events.Policy); ok {
+		log.Debugf("dropping policy event %s from %s", pe.Type, pe.Source)
+	}
+	return nil
+}
+
+// dump prints the current container assignments and topology zones.
+func (s *Simulator) dump(out io.Writer) {
+	containers := s.cache.GetContainers()
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].PrettyName() < containers[j].PrettyName()
+	})
+
+	fmt.Fprintf(out, "  containers:\n")
+	if len(containers) == 0 {
+		fmt.Fprintf(out, "    <none>\n")
+	}
+	for _, c := range containers {
+		fmt.Fprintf(out, "    %s: cpus %q, mems %q\n", c.PrettyName(),
+			c.GetCpusetCpus(), c.GetCpusetMems())
+	}
+
+	if !s.zones {
+		return
+	}
+
+	fmt.Fprintf(out, "  zones:\n")
+	for _, z := range s.policy.GetTopologyZones() {
+		fmt.Fprintf(out, "    %s (type %s", z.Name, z.Type)
+		if z.Parent != "" {
+			fmt.Fprintf(out, ", parent %s", z.Parent)
+		}
+		fmt.Fprintf(out, ")\n")
+		for _, r := range z.Resources {
+			fmt.Fprintf(out, "      %s: capacity %s, allocatable %s, available %s\n",
+				r.Name, r.Capacity.String(), r.Allocatable.String(), r.Available.String())
+		}
+		for _, a := range z.Attributes {
+			fmt.Fprintf(out, "      %s: %s\n", a.Name, a.Value)
+		}
+	}
+}
+
+// readPolicyConfig reads a policy configuration custom resource, returning
+// the backend for the policy and its configuration.
+func readPolicyConfig(path string) (policy.Backend, cfgapi.ResmgrConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read policy configuration: %w", err)
+	}
+
+	meta := metav1.TypeMeta{}
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse policy configuration %s: %w", path, err)
+	}
+
+	var (
+		backend policy.Backend
+		cfg     cfgapi.ResmgrConfig
+	)
+
+	switch meta.Kind {
+	case "BalloonsPolicy":
+		backend, cfg = balloons.New(), &cfgapi.BalloonsPolicy{}
+	case "TopologyAwarePolicy":
+		backend, cfg = topologyaware.New(), &cfgapi.TopologyAwarePolicy{}
+	case "TemplatePolicy":
+		backend, cfg = template.New(), &cfgapi.TemplatePolicy{}
+	default:
+		return nil, nil, fmt.Errorf("policy configuration %s: unknown kind %q", path, meta.Kind)
+	}
+
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse policy configuration %s: %w", path, err)
+	}
+
+	return backend, cfg, nil
+}
+
+// extractSysfs extracts a sysfs snapshot tarball into the given directory
+// and returns the root of the snapshot, the directory containing sys.
+func extractSysfs(tarball, dir string) (string, error) {
+	flags := "-xf"
+	switch {
+	case strings.HasSuffix(tarball, ".xz"):
+		flags = "-xJf"
+	case strings.HasSuffix(tarball, ".gz"), strings.HasSuffix(tarball, ".tgz"):
+		flags = "-xzf"
+	}
+
+	cmd := exec.Command("tar", "-C", dir, flags, tarball)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to extract %s: %w: %s", tarball, err, string(out))
+	}
+
+	return findSysRoot(dir)
+}
+
+// findSysRoot returns dir, or its only subdirectory, if it contains sys.
+func findSysRoot(dir string) (string, error) {
+	if info, err := os.Stat(filepath.Join(dir, "sys")); err == nil && info.IsDir() {
+		return dir, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		sub := filepath.Join(dir, entries[0].Name())
+		if info, err := os.Stat(filepath.Join(sub, "sys")); err == nil && info.IsDir() {
+			return sub, nil
+		}
+	}
+
+	return "", fmt.Errorf("no sysfs snapshot found in %s", dir)
+}
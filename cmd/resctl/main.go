@@ -0,0 +1,347 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/containers/nri-plugins/pkg/resmgr/introspect"
+)
+
+const usage = `Usage: %s [options] <command>
+
+Query and debug a running NRI resource policy plugin through its HTTP
+introspection endpoint, and its actions socket if enabled.
+
+Commands:
+  pods        list pods with their containers
+  containers  list containers with their CPU and memory pinning, pool
+              and the rule which selected the pool
+  pools       show the pool, or balloon, tree with assigned containers
+  decisions   show why the policy placed containers where it did
+  zones       dump the topology zones of the active policy (JSON)
+  config-status
+              show the status of the configuration file, in standalone
+              mode (JSON)
+  rebalance   request the policy to reallocate resources for all containers
+              (needs -actions-socket)
+  save-cache  request the plugin to save its cache (needs -actions-socket)
+
+Options:
+`
+
+func main() {
+	var (
+		endpoint string
+		socket   string
+		output   string
+		timeout  time.Duration
+	)
+
+	flag.StringVar(&endpoint, "endpoint", "localhost:8891",
+		"HTTP endpoint of the plugin (instrumentation httpEndpoint)")
+	flag.StringVar(&socket, "actions-socket", "",
+		"actions socket of the plugin (--actions-socket of the plugin)")
+	flag.StringVar(&output, "o", "table",
+		"output format for listing commands, table or json")
+	flag.DurationVar(&timeout, "timeout", introspect.DefaultTimeout,
+		"timeout for requests")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), usage, os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if output != "table" && output != "json" {
+		fatalf("invalid output format %q, expecting table or json", output)
+	}
+
+	var (
+		cli = introspect.NewClient(endpoint, timeout)
+		cmd = flag.Arg(0)
+		err error
+	)
+
+	if path, ok := listPaths[cmd]; ok && output == "json" {
+		if err = dumpRaw(cli, path); err != nil {
+			fatalf("%v", err)
+		}
+		return
+	}
+
+	switch cmd {
+	case "pods":
+		err = listPods(cli, os.Stdout)
+	case "containers":
+		err = listContainers(cli, os.Stdout)
+	case "pools":
+		err = showPools(cli, os.Stdout)
+	case "decisions":
+		err = showDecisions(cli, os.Stdout)
+	case "zones":
+		err = dumpRaw(cli, introspect.ZonesPath)
+	case "config-status":
+		err = dumpRaw(cli, introspect.ConfigStatusPath)
+	case "rebalance":
+		err = actionClient(socket, timeout).Rebalance()
+	case "save-cache":
+		err = actionClient(socket, timeout).SaveCache()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", cmd)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fatalf("%v", err)
+	}
+}
+
+// actionClient returns a client for the actions socket of the plugin.
+func actionClient(socket string, timeout time.Duration) *introspect.Client {
+	if socket == "" {
+		fatalf("actions are only served on the actions socket, use -actions-socket")
+	}
+	return introspect.NewClient("unix://"+socket, timeout)
+}
+
+// listPaths are the introspection endpoints of listing commands.
+var listPaths = map[string]string{
+	"pods":          introspect.PodsPath,
+	"containers":    introspect.ContainersPath,
+	"pools":         introspect.PoolsPath,
+	"decisions":     introspect.DecisionsPath,
+	"zones":         introspect.ZonesPath,
+	"config-status": introspect.ConfigStatusPath,
+}
+
+func dumpRaw(cli *introspect.Client, path string) error {
+	data, err := cli.GetRaw(path)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(os.Stdout, "%s\n", data)
+	return err
+}
+
+func listPods(cli *introspect.Client, out io.Writer) error {
+	pods, err := cli.GetPods()
+	if err != nil {
+		return err
+	}
+	containers, err := cli.GetContainers()
+	if err != nil {
+		return err
+	}
+	names := containerNames(containers)
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "NAMESPACE\tPOD\tQOS\tID\tCONTAINERS\n")
+	for _, p := range pods {
+		ctrs := []string{}
+		for _, id := range p.Containers {
+			ctrs = append(ctrs, names.short(id))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Namespace, p.Name, p.QOSClass,
+			shortID(p.ID), strings.Join(ctrs, ","))
+	}
+
+	return w.Flush()
+}
+
+func listContainers(cli *introspect.Client, out io.Writer) error {
+	containers, err := cli.GetContainers()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "NAMESPACE\tPOD\tCONTAINER\tSTATE\tCPUS\tMEMS\tPOOL\tMATCH\n")
+	for _, c := range containers {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Namespace, c.PodName, c.Name,
+			c.State, orNone(c.CPUs), orNone(c.Mems), orNone(c.Pool), orNone(c.Match))
+	}
+
+	return w.Flush()
+}
+
+func showPools(cli *introspect.Client, out io.Writer) error {
+	pools, err := cli.GetPools()
+	if err != nil {
+		return err
+	}
+	containers, err := cli.GetContainers()
+	if err != nil {
+		return err
+	}
+	names := containerNames(containers)
+
+	children := map[string][]*introspect.Pool{}
+	known := map[string]bool{}
+	for _, p := range pools {
+		known[p.Name] = true
+	}
+	roots := []*introspect.Pool{}
+	for _, p := range pools {
+		if p.Parent == "" || !known[p.Parent] {
+			roots = append(roots, p)
+		} else {
+			children[p.Parent] = append(children[p.Parent], p)
+		}
+	}
+
+	var show func(p *introspect.Pool, indent string)
+	show = func(p *introspect.Pool, indent string) {
+		fmt.Fprintf(out, "%s%s", indent, p.Name)
+		if p.Type != "" && p.Type != p.Name {
+			fmt.Fprintf(out, " (%s)", p.Type)
+		}
+		fmt.Fprintf(out, ": cpus %s, mems %s", orNone(p.CPUs), orNone(p.Mems))
+		if p.SharedCPUs != "" {
+			fmt.Fprintf(out, ", shared cpus %s", p.SharedCPUs)
+		}
+		if p.ReservedCPUs != "" {
+			fmt.Fprintf(out, ", reserved cpus %s", p.ReservedCPUs)
+		}
+		if p.IsolatedCPUs != "" {
+			fmt.Fprintf(out, ", isolated cpus %s", p.IsolatedCPUs)
+		}
+		fmt.Fprintf(out, "\n")
+
+		ctrs := []string{}
+		for _, id := range p.Containers {
+			ctrs = append(ctrs, names.long(id))
+		}
+		sort.Strings(ctrs)
+		for _, name := range ctrs {
+			fmt.Fprintf(out, "%s  - %s\n", indent, name)
+		}
+
+		for _, child := range children[p.Name] {
+			show(child, indent+"    ")
+		}
+	}
+
+	for _, p := range roots {
+		show(p, "")
+	}
+
+	return nil
+}
+
+func showDecisions(cli *introspect.Client, out io.Writer) error {
+	decisions, err := cli.GetDecisions()
+	if err != nil {
+		return err
+	}
+
+	for _, cd := range decisions {
+		d := cd.Decision
+		fmt.Fprintf(out, "%s/%s/%s: %s chose %s", cd.Namespace, cd.PodName, cd.Name,
+			d.Policy, orNone(d.Choice))
+		if d.Reason != "" {
+			fmt.Fprintf(out, " (%s)", d.Reason)
+		}
+		fmt.Fprintf(out, " at %s\n", d.Time.Format(time.RFC3339))
+		if d.Request != "" {
+			fmt.Fprintf(out, "  request: %s\n", d.Request)
+		}
+		for _, h := range d.Hints {
+			fmt.Fprintf(out, "  hint: %s\n", h)
+		}
+		keys := make([]string, 0, len(d.Annotations))
+		for key := range d.Annotations {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(out, "  annotation: %s=%s\n", key, d.Annotations[key])
+		}
+		for _, e := range d.Expressions {
+			fmt.Fprintf(out, "  expression: %s\n", e)
+		}
+		for _, c := range d.Candidates {
+			fmt.Fprintf(out, "  candidate %s", c.Name)
+			if c.Score != "" {
+				fmt.Fprintf(out, " [%s]", c.Score)
+			}
+			if c.Name == d.Choice {
+				fmt.Fprintf(out, ": chosen")
+			} else if c.Reason != "" {
+				fmt.Fprintf(out, ": %s", c.Reason)
+			}
+			fmt.Fprintf(out, "\n")
+		}
+	}
+
+	return nil
+}
+
+// containerNameMap maps container IDs to containers.
+type containerNameMap map[string]*introspect.Container
+
+func containerNames(containers []*introspect.Container) containerNameMap {
+	names := containerNameMap{}
+	for _, c := range containers {
+		names[c.ID] = c
+	}
+	return names
+}
+
+// short returns the name of a container, or its short ID if it is unknown.
+func (m containerNameMap) short(id string) string {
+	if c, ok := m[id]; ok {
+		return c.Name
+	}
+	return shortID(id)
+}
+
+// long returns the namespace/pod/container name of a container.
+func (m containerNameMap) long(id string) string {
+	if c, ok := m[id]; ok {
+		return c.Namespace + "/" + c.PodName + "/" + c.Name
+	}
+	return shortID(id)
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "error: "+format+"\n", args...)
+	os.Exit(1)
+}
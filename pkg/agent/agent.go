@@ -68,6 +68,15 @@ func WithConfigFile(file string) Option {
 	}
 }
 
+// WithConfigStatusFile sets the file to write the status of the configuration
+// file to. It is only used together with a configuration file.
+func WithConfigStatusFile(file string) Option {
+	return func(a *Agent) error {
+		a.statusFile = file
+		return nil
+	}
+}
+
 // WithConfigGroupLabel sets the key used to label nodes into config groups.
 func WithConfigGroupLabel(label string) Option {
 	return func(a *Agent) error {
@@ -76,6 +85,15 @@ func WithConfigGroupLabel(label string) Option {
 	}
 }
 
+// WithLayeredConfig sets up the agent to merge the default, group-specific
+// and node-specific configuration instead of using the most specific one.
+func WithLayeredConfig(layered bool) Option {
+	return func(a *Agent) error {
+		a.layered = layered
+		return nil
+	}
+}
+
 // ConfigInterface is used by the agent to access config custom resources.
 type ConfigInterface interface {
 	// Set the preferred client and configuration for cluster/apiserver access.
@@ -120,12 +138,19 @@ var (
 // configuration is used depending on whether the node belongs to a group. A
 // node can be assigned to a group by setting the group label on the node. By
 // default this group label is 'config.nri/group'.
+//
+// With layered configuration, the default, the group-specific and the node-
+// specific configuration are merged instead, each of these strategic-merge-
+// patching the ones before it. This allows node- and group-specific custom
+// resources to only contain the settings that differ from the default.
 type Agent struct {
 	nodeName   string // kubernetes node name, defaults to $NODE_NAME
 	namespace  string // config resource namespace
 	groupLabel string // config resource node grouping label key
 	kubeConfig string // kubeconfig path
 	configFile string // configuration file to use instead of custom resource
+	statusFile string // file to write configuration file status to
+	layered    bool   // merge default, group and node configuration
 
 	cfgIf     ConfigInterface      // custom resource access interface
 	httpCli   *http.Client         // shared HTTP client
@@ -134,14 +159,24 @@ type Agent struct {
 	nrtLock   sync.Mutex           // serialize NRT custom resource updates
 	podResCli *podresapi.Client    // pod resources API client
 
-	notifyFn      NotifyFn        // config resource change notification callback
-	nodeWatch     watch.Interface // kubernetes node watch
-	group         string          // current config group
-	nodeCfgWatch  watch.Interface // per-node config watch
-	nodeCfg       metav1.Object   // node specific config resource
-	groupCfgWatch watch.Interface // group-specific/default config watch
-	groupCfg      metav1.Object   // group-specific/default config resource
-	currentCfg    metav1.Object
+	eventLimiter *logger.Limiter // rate limiter for posting events
+
+	notifyFn      NotifyFn            // config resource change notification callback
+	nodeWatch     watch.Interface     // kubernetes node watch
+	group         string              // current config group
+	nodeCfgWatch  watch.Interface     // per-node config watch
+	nodeCfg       metav1.Object       // node specific config resource
+	groupCfgWatch watch.Interface     // group-specific/default config watch
+	groupCfg      metav1.Object       // group-specific/default config resource
+	dfltCfgWatch  watch.Interface     // default config watch, for layered config
+	dfltCfg       metav1.Object       // default config resource, for layered config
+	currentCfg    metav1.Object       // current effective config
+	currentLayers []metav1.Object     // config layers merged into current config
+	rollouts      map[string]*rollout // staged rollouts of shared configs
+	stateDir      string              // directory for persistent state
+
+	statusLock  sync.RWMutex      // protects localStatus
+	localStatus *ConfigFileStatus // status of configuration file, in standalone mode
 
 	stopLock sync.Mutex
 	stopC    chan struct{}
@@ -158,10 +193,15 @@ func New(cfgIf ConfigInterface, options ...Option) (*Agent, error) {
 		nodeName:   os.Getenv("NODE_NAME"),
 		kubeConfig: defaultKubeConfig,
 		configFile: defaultConfigFile,
+		statusFile: defaultStatusFile,
 		namespace:  defaultNamespace,
 		groupLabel: defaultGroupLabel,
+		layered:    defaultLayeredConfig,
 		cfgIf:      cfgIf,
 		stopC:      make(chan struct{}),
+		doneC:      make(chan struct{}),
+
+		eventLimiter: newEventLimiter(),
 	}
 
 	for _, o := range options {
@@ -174,10 +214,16 @@ func New(cfgIf ConfigInterface, options ...Option) (*Agent, error) {
 		return nil, fmt.Errorf("failed to create agent: neither node name nor config file set")
 	}
 
+	if a.statusFile != "" && a.configFile == "" {
+		return nil, fmt.Errorf("failed to create agent: config status file set without config file")
+	}
+
 	return a, nil
 }
 
 func (a *Agent) Start(notifyFn NotifyFn) error {
+	defer close(a.doneC)
+
 	a.notifyFn = notifyFn
 
 	err := a.setupClients()
@@ -194,6 +240,11 @@ func (a *Agent) Start(notifyFn NotifyFn) error {
 		return err
 	}
 
+	if err = a.setupDefaultConfigWatch(); err != nil {
+		a.cleanupWatches()
+		return err
+	}
+
 	eventChanOf := func(w watch.Interface) <-chan watch.Event {
 		if w == nil {
 			return nil
@@ -237,6 +288,8 @@ func (a *Agent) Start(notifyFn NotifyFn) error {
 				a.updateNodeConfig(e.Object)
 			case watch.Deleted:
 				a.updateNodeConfig(nil)
+			case watch.Error:
+				a.configFileError(e.Object)
 			}
 
 		case e, ok := <-eventChanOf(a.groupCfgWatch):
@@ -249,6 +302,17 @@ func (a *Agent) Start(notifyFn NotifyFn) error {
 			case watch.Deleted:
 				a.updateGroupConfig(nil)
 			}
+
+		case e, ok := <-eventChanOf(a.dfltCfgWatch):
+			if !ok {
+				break
+			}
+			switch e.Type {
+			case watch.Added, watch.Modified:
+				a.updateDefaultConfig(e.Object)
+			case watch.Deleted:
+				a.updateDefaultConfig(nil)
+			}
 		}
 	}
 }
@@ -280,7 +344,6 @@ func getAgentConfig(newConfig metav1.Object) *cfgapi.AgentConfig {
 
 func (a *Agent) configure(newConfig metav1.Object) {
 	if a.hasLocalConfig() {
-		log.Warn("running with local configuration, skipping client setup...")
 		return
 	}
 
@@ -332,7 +395,8 @@ func (a *Agent) hasLocalConfig() bool {
 
 func (a *Agent) setupClients() error {
 	if a.hasLocalConfig() {
-		log.Warn("running with local configuration, skipping cluster access client setup...")
+		log.Infof("running standalone with configuration file %s", a.configFile)
+		log.Info("cluster access, NRT and PodResourceAPI clients are disabled")
 		return nil
 	}
 
@@ -471,10 +535,24 @@ func (a *Agent) setupGroupConfigWatch(group string) error {
 	if group != "" {
 		log.Infof("node assigned to config group '%s'", group)
 	} else {
-		log.Infof("node removed from config group '%s'", group)
+		log.Infof("node removed from config group '%s'", a.group)
 	}
 	a.group = group
 
+	if a.layered {
+		// With layered configuration the default configuration is watched
+		// separately. Drop the layer of any previous group. If we switched
+		// to a new group, its watch will bring in the new layer.
+		prev := a.groupCfg
+		a.groupCfg = nil
+		if group == "" {
+			if prev != nil {
+				a.refreshConfig()
+			}
+			return nil
+		}
+	}
+
 	w, err := watch.Object(context.Background(), a.namespace, a.groupConfigName(),
 		func(ctx context.Context, ns, name string) (watch.Interface, error) {
 			return a.cfgIf.CreateWatch(ctx, ns, name)
@@ -490,6 +568,31 @@ func (a *Agent) setupGroupConfigWatch(group string) error {
 	return nil
 }
 
+func (a *Agent) setupDefaultConfigWatch() error {
+	if a.hasLocalConfig() || !a.layered {
+		return nil
+	}
+
+	if a.dfltCfgWatch != nil {
+		a.dfltCfgWatch.Stop()
+		a.dfltCfgWatch = nil
+	}
+
+	w, err := watch.Object(context.Background(), a.namespace, defaultConfigName,
+		func(ctx context.Context, ns, name string) (watch.Interface, error) {
+			return a.cfgIf.CreateWatch(ctx, ns, name)
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create default config watch for %s/%s: %w",
+			a.namespace, defaultConfigName, err)
+	}
+
+	a.dfltCfgWatch = w
+
+	return nil
+}
+
 func (a *Agent) cleanupWatches() {
 	if a.nodeWatch != nil {
 		a.nodeWatch.Stop()
@@ -503,6 +606,10 @@ func (a *Agent) cleanupWatches() {
 		a.groupCfgWatch.Stop()
 		a.groupCfgWatch = nil
 	}
+	if a.dfltCfgWatch != nil {
+		a.dfltCfgWatch.Stop()
+		a.dfltCfgWatch = nil
+	}
 }
 
 func (a *Agent) nodeConfigName() string {
@@ -513,7 +620,7 @@ func (a *Agent) groupConfigName() string {
 	if a.group != "" {
 		return "group." + a.group
 	}
-	return "default"
+	return defaultConfigName
 }
 
 func (a *Agent) updateNodeConfig(obj runtime.Object) {
@@ -528,6 +635,10 @@ func (a *Agent) updateNodeConfig(obj runtime.Object) {
 		cfg = o
 	}
 
+	if cfg == nil && a.hasLocalConfig() {
+		a.setConfigFileStatus(fmt.Errorf("no configuration in file %s", a.configFile))
+	}
+
 	if sameConfigVersion(cfg, a.nodeCfg) {
 		log.Debug("ignoring duplicate node-specific config update")
 		return
@@ -548,11 +659,7 @@ func (a *Agent) updateNodeConfig(obj runtime.Object) {
 	// If it was removed switch to group-specific one if we have it.
 	//
 
-	if cfg == nil {
-		cfg = a.groupCfg
-	}
-
-	a.updateConfig(cfg)
+	a.refreshConfig()
 }
 
 func (a *Agent) updateGroupConfig(obj runtime.Object) {
@@ -567,6 +674,10 @@ func (a *Agent) updateGroupConfig(obj runtime.Object) {
 		cfg = o
 	}
 
+	if a.isSharedConfigInUse() {
+		cfg = a.stageConfig(cfg, a.groupCfg)
+	}
+
 	if sameConfigVersion(cfg, a.groupCfg) {
 		log.Debug("ignoring duplicate group-specific config update")
 		return
@@ -587,33 +698,116 @@ func (a *Agent) updateGroupConfig(obj runtime.Object) {
 
 	a.groupCfg = cfg
 
-	if a.nodeCfg != nil {
+	if !a.isSharedConfigInUse() {
 		return
 	}
 
-	a.updateConfig(cfg)
+	if err := a.refreshConfig(); err != nil {
+		a.groupCfg = a.stagingFailed(cfg, err)
+	}
 }
 
-func (a *Agent) updateConfig(cfg metav1.Object) {
+func (a *Agent) updateDefaultConfig(obj runtime.Object) {
+	var cfg metav1.Object
+
+	if obj != nil {
+		o, ok := obj.(metav1.Object)
+		if !ok {
+			log.Error("can't handle object %T, not meta/v1.Object, ignoring it", obj)
+			return
+		}
+		cfg = o
+	}
+
+	cfg = a.stageConfig(cfg, a.dfltCfg)
+
+	if sameConfigVersion(cfg, a.dfltCfg) {
+		log.Debug("ignoring duplicate default config update")
+		return
+	}
+
+	if cfg == nil {
+		log.Info("default config deleted")
+	} else {
+		log.Info("default config updated")
+	}
+
+	a.dfltCfg = cfg
+
+	if err := a.refreshConfig(); err != nil {
+		a.dfltCfg = a.stagingFailed(cfg, err)
+	}
+}
+
+// isSharedConfigInUse returns true if the group-specific or default config
+// is used for the node.
+func (a *Agent) isSharedConfigInUse() bool {
+	return a.layered || a.nodeCfg == nil
+}
+
+// configLayers returns the configuration layers for the node, from the lowest
+// to the highest precedence. Without layered configuration this is the most
+// specific configuration we have.
+func (a *Agent) configLayers() []metav1.Object {
+	if !a.layered {
+		switch {
+		case a.nodeCfg != nil:
+			return []metav1.Object{a.nodeCfg}
+		case a.groupCfg != nil:
+			return []metav1.Object{a.groupCfg}
+		}
+		return nil
+	}
+
+	var layers []metav1.Object
+	for _, cfg := range []metav1.Object{a.dfltCfg, a.groupCfg, a.nodeCfg} {
+		if cfg != nil {
+			layers = append(layers, cfg)
+		}
+	}
+
+	return layers
+}
+
+// refreshConfig switches to the current effective configuration.
+func (a *Agent) refreshConfig() error {
+	layers := a.configLayers()
+
+	cfg, err := mergeConfigLayers(layers)
+	if err != nil {
+		log.Errorf("failed to merge configuration layers %v: %v", configLayerNames(layers), err)
+		a.rejectConfig(layers, err)
+		return err
+	}
+
+	return a.updateConfig(cfg, layers)
+}
+
+func (a *Agent) updateConfig(cfg metav1.Object, layers []metav1.Object) error {
 	if cfg == nil {
 		log.Warnf("node (%s) has no effective configuration", a.nodeName)
-		return
+		return nil
+	}
+
+	if len(layers) > 1 {
+		log.Infof("using configuration merged from layers %v", configLayerNames(layers))
 	}
 
 	if v, ok := cfg.(cfgapi.Validator); ok {
 		if err := v.Validate(); err != nil {
 			log.Errorf("failed to validate configuration: %v", err)
-
-			a.patchConfigStatus(a.currentCfg, cfg, err)
+			a.rejectConfig(layers, err)
 			a.currentCfg = cfg
-			return
+			return err
 		}
 	}
 
 	fatal, err := a.notifyFn(cfg)
-	a.patchConfigStatus(a.currentCfg, cfg, err)
+	a.patchConfigStatus(a.currentLayers, layers, cfg, err)
+	a.currentLayers = layers
 
 	if err != nil {
+		a.postConfigRejectedEvent(cfg, err)
 		if fatal {
 			log.Fatalf("failed to apply configuration: %v", err)
 		} else {
@@ -623,28 +817,46 @@ func (a *Agent) updateConfig(cfg metav1.Object) {
 
 	a.currentCfg = cfg
 	a.configure(cfg)
+
+	return err
 }
 
-func (a *Agent) patchConfigStatus(prev, curr metav1.Object, errors error) {
-	if a.cfgIf == nil {
-		return
+// rejectConfig reports the rejection of the configuration merged from layers.
+func (a *Agent) rejectConfig(layers []metav1.Object, err error) {
+	a.patchConfigStatus(a.currentLayers, layers, nil, err)
+	a.currentLayers = layers
+	if len(layers) > 0 {
+		a.postConfigRejectedEvent(layers[len(layers)-1], err)
 	}
+}
 
-	prevName := ""
-	if prev != nil {
-		prevName = prev.GetName()
+// patchConfigStatus updates the status of the configuration layers merged
+// into the effective configuration and clears the status of any previous
+// layers no longer used.
+func (a *Agent) patchConfigStatus(prev, curr []metav1.Object, effective metav1.Object, errors error) {
+	if a.hasLocalConfig() {
+		a.setConfigFileStatus(errors)
+		return
 	}
 
-	currName := ""
-	if curr != nil {
-		currName = curr.GetName()
+	if a.cfgIf == nil {
+		return
 	}
 
 	ctx := context.TODO()
 	ns := a.namespace
 	node := a.nodeName
 
-	if prev != nil && prevName != currName {
+	inUse := map[string]struct{}{}
+	for _, cfg := range curr {
+		inUse[cfg.GetName()] = struct{}{}
+	}
+
+	for _, cfg := range prev {
+		prevName := cfg.GetName()
+		if _, ok := inUse[prevName]; ok {
+			continue
+		}
 		data, pt, err := cfgapi.NodeStatusPatch(node, nil)
 		if err == nil {
 			err = a.cfgIf.PatchStatus(ctx, ns, prevName, pt, data, metav1.PatchOptions{})
@@ -654,8 +866,20 @@ func (a *Agent) patchConfigStatus(prev, curr metav1.Object, errors error) {
 		}
 	}
 
-	if curr != nil {
-		status := cfgapi.NewNodeStatus(errors, curr.GetGeneration())
+	for i, cfg := range curr {
+		currName := cfg.GetName()
+		status := cfgapi.NewNodeStatus(errors, cfg.GetGeneration())
+		if len(curr) > 1 {
+			status.Layers = configLayerStatus(curr)
+			if i == len(curr)-1 && effective != nil {
+				if spec, err := effectiveSpec(effective); err != nil {
+					log.Errorf("failed to report effective config: %v", err)
+				} else {
+					status.Effective = &spec
+				}
+			}
+		}
+		a.rolloutStatus(currName, status)
 		data, pt, err := cfgapi.NodeStatusPatch(node, status)
 		if err == nil {
 			err = a.cfgIf.PatchStatus(ctx, ns, currName, pt, data, metav1.PatchOptions{})
@@ -689,10 +913,12 @@ func sameConfigVersion(cfg1, cfg2 metav1.Object) bool {
 }
 
 var (
-	defaultNamespace  string
-	defaultGroupLabel string
-	defaultKubeConfig string
-	defaultConfigFile string
+	defaultNamespace     string
+	defaultGroupLabel    string
+	defaultKubeConfig    string
+	defaultConfigFile    string
+	defaultStatusFile    string
+	defaultLayeredConfig bool
 
 	deprecatedGroupLabels = []string{
 		"group.config.nri",
@@ -709,6 +935,10 @@ func init() {
 		"name of the label used to assign the node to a configuration group")
 	flag.StringVar(&defaultConfigFile, "config-file", "",
 		"config file to use/monitor instead of a CustomResource")
+	flag.StringVar(&defaultStatusFile, "config-status-file", "",
+		"file to write the status of the config file to, used with --config-file")
 	flag.StringVar(&defaultKubeConfig, "kubeconfig", "",
 		"kubeconfig file to use, empty for in-cluster configuration")
+	flag.BoolVar(&defaultLayeredConfig, "layered-config", false,
+		"merge default, group-specific and node-specific configuration")
 }
@@ -0,0 +1,232 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+)
+
+const (
+	defaultConfigName = "default"
+	specField         = "spec"
+)
+
+// mergeConfigLayers merges configuration layers, given from the lowest to
+// the highest precedence, into a single effective configuration. The spec
+// of each layer is strategic-merge-patched on top of the layers below it.
+// Apart from its spec, the merged configuration is identical to the layer
+// with the highest precedence.
+func mergeConfigLayers(layers []metav1.Object) (metav1.Object, error) {
+	if len(layers) == 0 {
+		return nil, nil
+	}
+
+	top := layers[len(layers)-1]
+	if len(layers) == 1 {
+		return top, nil
+	}
+
+	var merged map[string]interface{}
+	for i, layer := range layers {
+		patch, err := layerSpec(layer, i > 0)
+		if err != nil {
+			return nil, err
+		}
+
+		if merged == nil {
+			merged = patch
+			continue
+		}
+
+		merged, err = strategicpatch.StrategicMergeMapPatch(merged, patch, top)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge config layer %s: %w", layer.GetName(), err)
+		}
+	}
+
+	obj, err := toMap(top)
+	if err != nil {
+		return nil, err
+	}
+	delete(obj, "status")
+	obj[specField] = merged[specField]
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal merged config: %w", err)
+	}
+
+	cfg := reflect.New(reflect.TypeOf(top).Elem()).Interface().(metav1.Object)
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal merged config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// layerSpec returns the spec of a configuration layer as a map. If explicit
+// is true only fields explicitly set in the layer are returned, leaving out
+// fields filled in by defaults. Fields are considered explicitly set if they
+// are owned by any field manager. Without any managed fields, for instance
+// for configuration read from a file, all fields are returned.
+func layerSpec(layer metav1.Object, explicit bool) (map[string]interface{}, error) {
+	obj, err := toMap(layer)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, ok := obj[specField].(map[string]interface{})
+	if !ok {
+		spec = map[string]interface{}{}
+	}
+
+	if explicit {
+		set, err := explicitFields(layer)
+		if err != nil {
+			return nil, err
+		}
+		if set != nil {
+			if s, ok := set.Children.Get(fieldpath.PathElement{FieldName: ptr(specField)}); ok {
+				spec = pruneFields(spec, s)
+			} else {
+				spec = map[string]interface{}{}
+			}
+		}
+	}
+
+	return map[string]interface{}{specField: spec}, nil
+}
+
+// explicitFields returns the fields of an object owned by any field manager,
+// or nil if the object has no managed fields.
+func explicitFields(obj metav1.Object) (*fieldpath.Set, error) {
+	var set *fieldpath.Set
+
+	for _, mf := range obj.GetManagedFields() {
+		if mf.Subresource != "" || mf.FieldsV1 == nil {
+			continue
+		}
+		s := &fieldpath.Set{}
+		if err := s.FromJSON(bytes.NewReader(mf.FieldsV1.Raw)); err != nil {
+			return nil, fmt.Errorf("failed to parse managed fields of %s: %w", obj.GetName(), err)
+		}
+		if set == nil {
+			set = s
+		} else {
+			set = set.Union(s)
+		}
+	}
+
+	return set, nil
+}
+
+// pruneFields removes all fields not in the given set from a map. Fields in
+// the set but missing from the map have been omitted for having a zero value.
+// These are set to null, which resets them to zero when used as a patch.
+func pruneFields(m map[string]interface{}, set *fieldpath.Set) map[string]interface{} {
+	pruned := map[string]interface{}{}
+
+	zeroOmitted := func(pe fieldpath.PathElement) {
+		if pe.FieldName == nil {
+			return
+		}
+		if _, ok := m[*pe.FieldName]; !ok {
+			pruned[*pe.FieldName] = nil
+		}
+	}
+	set.Members.Iterate(zeroOmitted)
+	set.Children.Iterate(zeroOmitted)
+
+	for key, value := range m {
+		pe := fieldpath.PathElement{FieldName: ptr(key)}
+		if set.Members.Has(pe) {
+			pruned[key] = value
+			continue
+		}
+		s, ok := set.Children.Get(pe)
+		if !ok {
+			continue
+		}
+		if v, ok := value.(map[string]interface{}); ok {
+			pruned[key] = pruneFields(v, s)
+		} else {
+			pruned[key] = value
+		}
+	}
+
+	return pruned
+}
+
+// effectiveSpec returns the spec of a configuration as a JSON string.
+func effectiveSpec(cfg metav1.Object) (string, error) {
+	obj, err := toMap(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(obj[specField])
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config spec: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// configLayerNames returns the names of configuration layers.
+func configLayerNames(layers []metav1.Object) []string {
+	names := make([]string, 0, len(layers))
+	for _, l := range layers {
+		names = append(names, l.GetName())
+	}
+	return names
+}
+
+// configLayerStatus returns the names and generations of configuration layers.
+func configLayerStatus(layers []metav1.Object) []cfgapi.ConfigLayer {
+	status := make([]cfgapi.ConfigLayer, 0, len(layers))
+	for _, l := range layers {
+		status = append(status, cfgapi.ConfigLayer{
+			Name:       l.GetName(),
+			Generation: l.GetGeneration(),
+		})
+	}
+	return status
+}
+
+func toMap(obj interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	return m, nil
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,148 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1"
+	"github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/policy/balloons"
+	"github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/policy/topologyaware"
+)
+
+func managedFields(fields string) []metav1.ManagedFieldsEntry {
+	return []metav1.ManagedFieldsEntry{
+		{
+			Manager:    "kubectl",
+			Operation:  metav1.ManagedFieldsOperationApply,
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(fields)},
+		},
+	}
+}
+
+func TestMergeBalloonsConfigLayers(t *testing.T) {
+	dflt := &cfgapi.BalloonsPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Generation: 1},
+	}
+	pinCPU := true
+	dflt.Spec.PinCPU = &pinCPU
+	dflt.Spec.ReservedResources = balloons.Constraints{balloons.CPU: "cpuset:0"}
+	dflt.Spec.BalloonDefs = []*balloons.BalloonDef{
+		{Name: "a", MinCpus: 2, MaxCpus: 8},
+		{Name: "b", MinCpus: 1},
+	}
+
+	node := &cfgapi.BalloonsPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "node.test", Generation: 3},
+	}
+	node.Spec.ReservedResources = balloons.Constraints{balloons.CPU: "cpuset:1"}
+	node.Spec.BalloonDefs = []*balloons.BalloonDef{
+		{Name: "a", MinCpus: 4},
+		{Name: "c", MinCpus: 1},
+	}
+
+	cfg, err := mergeConfigLayers([]metav1.Object{dflt, node})
+	require.NoError(t, err)
+
+	merged, ok := cfg.(*cfgapi.BalloonsPolicy)
+	require.True(t, ok)
+	require.Equal(t, "node.test", merged.GetName())
+	require.Equal(t, int64(3), merged.GetGeneration())
+	require.NotNil(t, merged.Spec.PinCPU)
+	require.True(t, *merged.Spec.PinCPU)
+	require.Equal(t, balloons.Amount("cpuset:1"), merged.Spec.ReservedResources[balloons.CPU])
+
+	types := map[string]*balloons.BalloonDef{}
+	for _, bdef := range merged.Spec.BalloonDefs {
+		types[bdef.Name] = bdef
+	}
+	require.Len(t, types, 3)
+	require.Equal(t, 4, types["a"].MinCpus)
+	require.Equal(t, 8, types["a"].MaxCpus)
+	require.Equal(t, 1, types["b"].MinCpus)
+	require.Equal(t, 1, types["c"].MinCpus)
+
+	// Layers are not modified by merging.
+	require.Equal(t, 2, dflt.Spec.BalloonDefs[0].MinCpus)
+	require.Len(t, node.Spec.BalloonDefs, 2)
+}
+
+func TestMergeExplicitlySetFields(t *testing.T) {
+	dflt := &cfgapi.TopologyAwarePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+	}
+	dflt.Spec.PinCPU = true
+	dflt.Spec.ReservedResources = topologyaware.Constraints{"cpu": "750m"}
+
+	group := &cfgapi.TopologyAwarePolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:          "group.test",
+			ManagedFields: managedFields(`{"f:spec":{"f:pinCPU":{}}}`),
+		},
+	}
+	group.Spec.PinCPU = false
+	group.Spec.PinMemory = true
+	group.Spec.ReservedResources = topologyaware.Constraints{"cpu": "2"}
+
+	// PinCPU is only set by defaults in the node-specific config.
+	node := &cfgapi.TopologyAwarePolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:          "node.test",
+			ManagedFields: managedFields(`{"f:spec":{"f:reservedResources":{"f:cpu":{}}}}`),
+		},
+	}
+	node.Spec.PinCPU = true
+	node.Spec.ReservedResources = topologyaware.Constraints{"cpu": "1"}
+
+	cfg, err := mergeConfigLayers([]metav1.Object{dflt, group, node})
+	require.NoError(t, err)
+
+	merged := cfg.(*cfgapi.TopologyAwarePolicy)
+	require.False(t, merged.Spec.PinCPU)
+	require.False(t, merged.Spec.PinMemory)
+	require.Equal(t, topologyaware.Constraints{"cpu": "1"}, merged.Spec.ReservedResources)
+
+	spec, err := effectiveSpec(merged)
+	require.NoError(t, err)
+	require.Contains(t, spec, `"reservedResources":{"cpu":"1"}`)
+}
+
+func TestConfigLayerStatus(t *testing.T) {
+	dflt := &cfgapi.TemplatePolicy{ObjectMeta: metav1.ObjectMeta{Name: "default", Generation: 3}}
+	node := &cfgapi.TemplatePolicy{ObjectMeta: metav1.ObjectMeta{Name: "node.test", Generation: 1}}
+
+	require.Equal(t,
+		[]cfgapi.ConfigLayer{
+			{Name: "default", Generation: 3},
+			{Name: "node.test", Generation: 1},
+		},
+		configLayerStatus([]metav1.Object{dflt, node}),
+	)
+}
+
+func TestMergeSingleConfigLayer(t *testing.T) {
+	cfg, err := mergeConfigLayers(nil)
+	require.NoError(t, err)
+	require.Nil(t, cfg)
+
+	node := &cfgapi.TemplatePolicy{ObjectMeta: metav1.ObjectMeta{Name: "node.test"}}
+	cfg, err = mergeConfigLayers([]metav1.Object{node})
+	require.NoError(t, err)
+	require.True(t, cfg == metav1.Object(node))
+}
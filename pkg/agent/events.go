@@ -0,0 +1,138 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1"
+	logger "github.com/containers/nri-plugins/pkg/log"
+	"github.com/containers/nri-plugins/pkg/resmgr/events"
+)
+
+const (
+	// eventComponent is the source component of the events we post.
+	eventComponent = "nri-resource-policy"
+	// eventInterval is the minimum interval between identical events.
+	eventInterval = 5 * time.Minute
+)
+
+// PostPodEvent posts a Kubernetes Event about a pod. Identical events for
+// the same pod are rate-limited. Events are not posted when running with a
+// local configuration file, without cluster access.
+func (a *Agent) PostPodEvent(e *events.Pod) error {
+	ref := &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  e.Namespace,
+		Name:       e.Name,
+		UID:        types.UID(e.UID),
+	}
+	return a.postEvent(ref, e.Type, e.Reason, e.Message)
+}
+
+// postConfigRejectedEvent posts an Event about a rejected configuration.
+func (a *Agent) postConfigRejectedEvent(cfg metav1.Object, err error) {
+	ref := &corev1.ObjectReference{
+		APIVersion:      cfgapi.SchemeGroupVersion.String(),
+		Namespace:       cfg.GetNamespace(),
+		Name:            cfg.GetName(),
+		UID:             cfg.GetUID(),
+		ResourceVersion: cfg.GetResourceVersion(),
+	}
+	if obj, ok := cfg.(runtime.Object); ok {
+		ref.Kind = obj.GetObjectKind().GroupVersionKind().Kind
+	}
+	if ref.Kind == "" {
+		ref.Kind = reflect.Indirect(reflect.ValueOf(cfg)).Type().Name()
+	}
+
+	msg := fmt.Sprintf("configuration rejected by node %s: %v", a.nodeName, err)
+	if err := a.postEvent(ref, events.Warning, events.ConfigRejected, msg); err != nil {
+		log.Errorf("failed to post %s event: %v", events.ConfigRejected, err)
+	}
+}
+
+// postEvent posts an Event about the given object asynchronously.
+func (a *Agent) postEvent(ref *corev1.ObjectReference, eventType, reason, message string) error {
+	if a.hasLocalConfig() {
+		return nil
+	}
+
+	if a.k8sCli == nil {
+		return fmt.Errorf("no kubernetes client, can't post event")
+	}
+
+	key := ref.Kind + ":" + ref.Namespace + "/" + ref.Name + ":" + reason + ":" + message
+	if !a.eventLimiter.Allow(key) {
+		log.Debug("rate-limited %s event for %s %s/%s", reason, ref.Kind, ref.Namespace, ref.Name)
+		return nil
+	}
+
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", ref.Name, now.UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject: *ref,
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Source: corev1.EventSource{
+			Component: eventComponent,
+			Host:      a.nodeName,
+		},
+		ReportingController: eventComponent,
+		ReportingInstance:   eventComponent + "-" + a.nodeName,
+	}
+
+	log.Info("posting %s event %s for %s %s/%s: %s", eventType, reason,
+		ref.Kind, ref.Namespace, ref.Name, message)
+
+	// Like NRT updates, post events asynchronously to avoid blocking
+	// NRI request processing on the API server.
+	cli := a.k8sCli
+	go func() {
+		_, err := cli.CoreV1().Events(namespace).Create(context.Background(), event,
+			metav1.CreateOptions{})
+		if err != nil {
+			log.Errorf("failed to post %s event for %s %s/%s: %v", reason,
+				ref.Kind, ref.Namespace, ref.Name, err)
+		}
+	}()
+
+	return nil
+}
+
+func newEventLimiter() *logger.Limiter {
+	return logger.NewLimiter(logger.Interval(eventInterval))
+}
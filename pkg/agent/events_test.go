@@ -0,0 +1,175 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1"
+	"github.com/containers/nri-plugins/pkg/resmgr/events"
+)
+
+// newEventTestAgent returns an agent posting events to a fake API server,
+// and a channel receiving the posted events.
+func newEventTestAgent(t *testing.T) (*Agent, <-chan *corev1.Event) {
+	posted := make(chan *corev1.Event, 16)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/events") {
+			http.Error(w, "unexpected request", http.StatusNotFound)
+			return
+		}
+		e := &corev1.Event{}
+		if err := json.NewDecoder(req.Body).Decode(e); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		posted <- e
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(e)
+	}))
+	t.Cleanup(srv.Close)
+
+	cli, err := k8sclient.NewForConfig(&rest.Config{Host: srv.URL})
+	require.NoError(t, err)
+
+	return &Agent{
+		nodeName:     "node0",
+		k8sCli:       cli,
+		eventLimiter: newEventLimiter(),
+	}, posted
+}
+
+func receiveEvent(t *testing.T, posted <-chan *corev1.Event) *corev1.Event {
+	t.Helper()
+	select {
+	case e := <-posted:
+		return e
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timeout waiting for posted event")
+	}
+	return nil
+}
+
+func requireNoEvent(t *testing.T, posted <-chan *corev1.Event) {
+	t.Helper()
+	select {
+	case e := <-posted:
+		require.FailNow(t, "unexpected event", "%s: %s", e.Reason, e.Message)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPostPodEvent(t *testing.T) {
+	a, posted := newEventTestAgent(t)
+
+	e := &events.Pod{
+		Namespace: "test",
+		Name:      "pod0",
+		UID:       "uid0",
+		Type:      events.Warning,
+		Reason:    events.AllocationFailed,
+		Message:   "not enough CPUs",
+	}
+	require.NoError(t, a.PostPodEvent(e))
+
+	got := receiveEvent(t, posted)
+	require.Equal(t, "test", got.Namespace)
+	require.True(t, strings.HasPrefix(got.Name, "pod0."), got.Name)
+	require.Equal(t, corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  "test",
+		Name:       "pod0",
+		UID:        "uid0",
+	}, got.InvolvedObject)
+	require.Equal(t, events.Warning, got.Type)
+	require.Equal(t, events.AllocationFailed, got.Reason)
+	require.Equal(t, "not enough CPUs", got.Message)
+	require.Equal(t, "node0", got.Source.Host)
+	require.Equal(t, int32(1), got.Count)
+
+	// Identical events are rate-limited.
+	require.NoError(t, a.PostPodEvent(e))
+	requireNoEvent(t, posted)
+
+	// Events differing in pod, reason or message are not.
+	for _, diff := range []func(e *events.Pod){
+		func(e *events.Pod) { e.Name = "pod1" },
+		func(e *events.Pod) { e.Namespace = "other" },
+		func(e *events.Pod) { e.Reason = events.BalloonLimitReached },
+		func(e *events.Pod) { e.Message = "not enough memory" },
+	} {
+		other := *e
+		diff(&other)
+		require.NoError(t, a.PostPodEvent(&other))
+		got := receiveEvent(t, posted)
+		require.Equal(t, other.Name, got.InvolvedObject.Name)
+		require.Equal(t, other.Namespace, got.InvolvedObject.Namespace)
+		require.Equal(t, other.Reason, got.Reason)
+		require.Equal(t, other.Message, got.Message)
+	}
+	requireNoEvent(t, posted)
+}
+
+func TestPostPodEventWithoutCluster(t *testing.T) {
+	a, posted := newEventTestAgent(t)
+	e := &events.Pod{Namespace: "test", Name: "pod0", Reason: events.AllocationFailed}
+
+	// No events are posted in standalone mode.
+	a.configFile = "/etc/nri-resource-policy/config.yaml"
+	require.NoError(t, a.PostPodEvent(e))
+	requireNoEvent(t, posted)
+
+	// Without a client, posting fails.
+	a.configFile = ""
+	a.k8sCli = nil
+	require.Error(t, a.PostPodEvent(e))
+}
+
+func TestPostConfigRejectedEvent(t *testing.T) {
+	a, posted := newEventTestAgent(t)
+
+	cfg := &cfgapi.TemplatePolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "default",
+			Namespace:       "kube-system",
+			UID:             "uid",
+			ResourceVersion: "1",
+		},
+	}
+	a.postConfigRejectedEvent(cfg, fmt.Errorf("invalid reserved CPUs"))
+
+	got := receiveEvent(t, posted)
+	require.Equal(t, "kube-system", got.Namespace)
+	require.Equal(t, "TemplatePolicy", got.InvolvedObject.Kind)
+	require.Equal(t, cfgapi.SchemeGroupVersion.String(), got.InvolvedObject.APIVersion)
+	require.Equal(t, "default", got.InvolvedObject.Name)
+	require.Equal(t, events.ConfigRejected, got.Reason)
+	require.Equal(t, "configuration rejected by node node0: invalid reserved CPUs", got.Message)
+}
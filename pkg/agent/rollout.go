@@ -0,0 +1,403 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1"
+)
+
+// rollout tracks the staged rollout of a configuration shared by nodes.
+//
+// With staged rollout, a node takes a new generation of a shared config
+// into use only once it manages to claim a rollout slot. Rollout slots are
+// claimed by setting the node status for the configuration to 'Updating'
+// for the generation. At most the configured maximum number of nodes can
+// be updating to the same generation at the same time. If any node fails
+// to take the generation into use, the rollout pauses: no more nodes take
+// the generation into use and nodes which already did roll back to the
+// generation they used before.
+//
+// A rollout slot claimed by a node expires once its 'Updating' status is
+// older than rolloutSlotExpiry, so a node which crashes while updating does
+// not hold its slot forever. The last known-good generation is stored under
+// the state directory, if one is set, so that a node restarted during a
+// rollout can still roll back.
+type rollout struct {
+	previous   metav1.Object      // last known-good generation before the staged one
+	staged     int64              // generation being staged on this node
+	rejected   int64              // generation not to take into use
+	rolledBack int64              // generation rolled back from
+	failed     *cfgapi.NodeStatus // status to report for a failed generation
+}
+
+const (
+	// rolloutSlotExpiry is the time after which a claimed slot is released.
+	rolloutSlotExpiry = 10 * time.Minute
+	// rolloutDir is the directory for rollout state under the state directory.
+	rolloutDir = "rollout"
+)
+
+// rolloutState is the persisted state of a rollout.
+type rolloutState struct {
+	Staged   int64           `json:"staged"`
+	Previous json.RawMessage `json:"previous"`
+}
+
+// SetStateDir sets the directory to store persistent agent state in.
+func (a *Agent) SetStateDir(dir string) {
+	a.stateDir = dir
+}
+
+func (a *Agent) getRollout(name string) *rollout {
+	if a.rollouts == nil {
+		a.rollouts = make(map[string]*rollout)
+	}
+	r, ok := a.rollouts[name]
+	if !ok {
+		r = &rollout{}
+		a.rollouts[name] = r
+	}
+	return r
+}
+
+// dropRollout forgets any rollout of a config.
+func (a *Agent) dropRollout(name string) {
+	delete(a.rollouts, name)
+	a.removeRolloutState(name)
+}
+
+func (a *Agent) rolloutStateFile(name string) string {
+	if a.stateDir == "" {
+		return ""
+	}
+	return filepath.Join(a.stateDir, rolloutDir, name+".json")
+}
+
+// saveRollout stores the last known-good generation of a rollout, or removes
+// any stored one if the rollout has none.
+func (a *Agent) saveRollout(name string, r *rollout) {
+	file := a.rolloutStateFile(name)
+	if file == "" {
+		return
+	}
+
+	if r.previous == nil {
+		a.removeRolloutState(name)
+		return
+	}
+
+	prev, err := json.Marshal(r.previous)
+	if err != nil {
+		log.Errorf("failed to marshal rollout state of config %s: %v", name, err)
+		return
+	}
+	data, err := json.Marshal(&rolloutState{Staged: r.staged, Previous: prev})
+	if err != nil {
+		log.Errorf("failed to marshal rollout state of config %s: %v", name, err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		log.Errorf("failed to save rollout state of config %s: %v", name, err)
+		return
+	}
+	if err := writeRolloutState(file, data); err != nil {
+		log.Errorf("failed to save rollout state of config %s: %v", name, err)
+	}
+}
+
+// writeRolloutState writes rollout state to a file, replacing it atomically.
+// The data is synced to disk before the file is replaced.
+func writeRolloutState(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create rollout state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write rollout state file %s: %w", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename rollout state file to %s: %w", path, err)
+	}
+
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		err = dir.Sync()
+		dir.Close()
+		if err != nil {
+			return fmt.Errorf("failed to sync directory of %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+func (a *Agent) removeRolloutState(name string) {
+	file := a.rolloutStateFile(name)
+	if file == "" {
+		return
+	}
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		log.Errorf("failed to remove rollout state of config %s: %v", name, err)
+	}
+}
+
+// restoreRollout restores the stored rollout of a config, if it was staging
+// the given generation of the config.
+func (a *Agent) restoreRollout(cfg metav1.Object) {
+	name := cfg.GetName()
+	file := a.rolloutStateFile(name)
+	if file == "" {
+		return
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("failed to read rollout state of config %s: %v", name, err)
+		}
+		return
+	}
+
+	state := &rolloutState{}
+	prev := reflect.New(reflect.TypeOf(cfg).Elem()).Interface().(metav1.Object)
+	if err := json.Unmarshal(data, state); err != nil {
+		log.Errorf("failed to unmarshal rollout state of config %s: %v", name, err)
+	} else if err := json.Unmarshal(state.Previous, prev); err != nil {
+		log.Errorf("failed to unmarshal rollout state of config %s: %v", name, err)
+	} else if state.Staged == cfg.GetGeneration() && prev.GetUID() == cfg.GetUID() {
+		log.Infof("restored rollout of config %s generation %d, last known-good generation %d",
+			name, state.Staged, prev.GetGeneration())
+		r := a.getRollout(name)
+		r.previous = prev
+		r.staged = state.Staged
+		return
+	}
+
+	a.removeRolloutState(name)
+}
+
+// rolloutConfig returns the staged rollout configuration of a config.
+func rolloutConfig(cfg metav1.Object) *cfgapi.RolloutConfig {
+	if ac := cfgapi.GetAgentConfig(cfg); ac != nil {
+		return ac.Rollout
+	}
+	return nil
+}
+
+// stageConfig returns the generation of a shared configuration to use with
+// staged rollout. This is either the given one, the one in use, or for a
+// rollback the last known-good one.
+func (a *Agent) stageConfig(cfg, inUse metav1.Object) metav1.Object {
+	if cfg == nil || inUse == nil || cfg.GetUID() != inUse.GetUID() {
+		if inUse != nil {
+			a.dropRollout(inUse.GetName())
+		}
+		if cfg != nil && inUse == nil && rolloutConfig(cfg) != nil {
+			a.restoreRollout(cfg)
+		}
+		return cfg
+	}
+
+	ro := rolloutConfig(cfg)
+	if ro == nil {
+		a.dropRollout(cfg.GetName())
+		return cfg
+	}
+
+	var (
+		r      = a.getRollout(cfg.GetName())
+		name   = cfg.GetName()
+		gen    = cfg.GetGeneration()
+		status = cfgapi.GetConfigStatus(cfg)
+	)
+
+	if gen <= inUse.GetGeneration() {
+		if gen == inUse.GetGeneration() && r.previous != nil {
+			if node, ok := a.failedNode(status, gen); ok {
+				log.Warnf("config %s generation %d failed on node %s, rolling back to generation %d",
+					name, gen, node, r.previous.GetGeneration())
+				prev := r.previous
+				r.previous = nil
+				r.staged = 0
+				r.rejected = gen
+				r.rolledBack = gen
+				a.saveRollout(name, r)
+				return prev
+			}
+		}
+		return cfg
+	}
+
+	if gen == r.rejected {
+		return inUse
+	}
+
+	if node, ok := a.failedNode(status, gen); ok {
+		log.Warnf("rollout of config %s generation %d paused, failed on node %s",
+			name, gen, node)
+		r.rejected = gen
+		return inUse
+	}
+
+	total := 1
+	if status != nil && len(status.Nodes) > 0 {
+		total = len(status.Nodes)
+	}
+	maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(&ro.MaxUnavailable, total, false)
+	if err != nil {
+		log.Errorf("invalid maxUnavailable %q for config %s: %v", ro.MaxUnavailable.String(), name, err)
+	}
+	if maxUnavailable < 1 {
+		maxUnavailable = 1
+	}
+
+	updating := 0
+	if status != nil {
+		for node, s := range status.Nodes {
+			if node == a.nodeName || s.Status != cfgapi.StatusUpdating || s.Generation != gen {
+				continue
+			}
+			if time.Since(s.Timestamp.Time) > rolloutSlotExpiry {
+				log.Warnf("config %s generation %d rollout slot of node %s expired",
+					name, gen, node)
+				continue
+			}
+			updating++
+		}
+	}
+	if updating >= maxUnavailable {
+		log.Infof("config %s generation %d waiting for rollout, %d/%d nodes updating",
+			name, gen, updating, maxUnavailable)
+		return inUse
+	}
+
+	if err := a.claimRollout(cfg); err != nil {
+		log.Infof("config %s generation %d waiting for rollout: %v", name, gen, err)
+		return inUse
+	}
+
+	log.Infof("rolling out config %s generation %d", name, gen)
+
+	r.previous = inUse
+	r.staged = gen
+	r.rolledBack = 0
+	r.failed = nil
+	a.saveRollout(name, r)
+
+	return cfg
+}
+
+// stagingFailed handles failure to take a staged generation of a config into
+// use. It returns the last known-good generation of the config to keep using.
+func (a *Agent) stagingFailed(cfg metav1.Object, err error) metav1.Object {
+	if cfg == nil {
+		return cfg
+	}
+
+	r, ok := a.rollouts[cfg.GetName()]
+	if !ok || r.previous == nil || r.staged != cfg.GetGeneration() {
+		return cfg
+	}
+
+	log.Warnf("config %s generation %d failed, pausing rollout and keeping generation %d",
+		cfg.GetName(), r.staged, r.previous.GetGeneration())
+
+	prev := r.previous
+	r.previous = nil
+	r.rejected = r.staged
+	r.failed = cfgapi.NewNodeStatus(err, r.staged)
+	r.staged = 0
+	a.saveRollout(cfg.GetName(), r)
+
+	return prev
+}
+
+// failedNode returns a node which has failed to take a generation into use.
+func (a *Agent) failedNode(status *cfgapi.ConfigStatus, gen int64) (string, bool) {
+	if status == nil {
+		return "", false
+	}
+	for node, s := range status.Nodes {
+		if node != a.nodeName && s.Status == cfgapi.StatusFailure && s.Generation == gen {
+			return node, true
+		}
+	}
+	return "", false
+}
+
+// claimRollout claims a rollout slot for the current generation of a config.
+func (a *Agent) claimRollout(cfg metav1.Object) error {
+	if a.cfgIf == nil {
+		return nil
+	}
+
+	status := cfgapi.NewNodeStatus(nil, cfg.GetGeneration())
+	status.Status = cfgapi.StatusUpdating
+
+	data, pt, err := cfgapi.NodeStatusPatchForVersion(a.nodeName, status, cfg.GetResourceVersion())
+	if err != nil {
+		return err
+	}
+
+	err = a.cfgIf.PatchStatus(context.TODO(), a.namespace, cfg.GetName(), pt, data, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to claim rollout slot: %w", err)
+	}
+
+	return nil
+}
+
+// rolloutStatus adjusts the node status reported for a config to reflect
+// any failure or rollback during staged rollout.
+func (a *Agent) rolloutStatus(name string, status *cfgapi.NodeStatus) {
+	r, ok := a.rollouts[name]
+	if !ok {
+		return
+	}
+
+	switch {
+	case r.failed != nil:
+		status.Status = r.failed.Status
+		status.Generation = r.failed.Generation
+		status.Error = r.failed.Error
+	case r.rolledBack != 0:
+		msg := fmt.Sprintf("rolled back from generation %d", r.rolledBack)
+		status.Status = cfgapi.StatusRolledBack
+		status.Error = &msg
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/rest"
+
+	"github.com/containers/nri-plugins/pkg/agent/watch"
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1"
+)
+
+type statusPatch struct {
+	name    string
+	version string
+	status  *cfgapi.NodeStatus
+}
+
+type fakeConfigIf struct {
+	patches []*statusPatch
+}
+
+func (f *fakeConfigIf) SetKubeClient(*http.Client, *rest.Config) error {
+	return nil
+}
+
+func (f *fakeConfigIf) CreateWatch(context.Context, string, string) (watch.Interface, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (f *fakeConfigIf) PatchStatus(_ context.Context, _, name string, _ types.PatchType, data []byte, _ metav1.PatchOptions) error {
+	patch := struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+		Status struct {
+			Nodes map[string]*cfgapi.NodeStatus `json:"nodes"`
+		} `json:"status"`
+	}{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return err
+	}
+	f.patches = append(f.patches, &statusPatch{
+		name:    name,
+		version: patch.Metadata.ResourceVersion,
+		status:  patch.Status.Nodes["node0"],
+	})
+	return nil
+}
+
+func (f *fakeConfigIf) Unmarshal([]byte, string) (runtime.Object, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (f *fakeConfigIf) lastPatch() *statusPatch {
+	if len(f.patches) == 0 {
+		return nil
+	}
+	return f.patches[len(f.patches)-1]
+}
+
+type rolloutTest struct {
+	t       *testing.T
+	agent   *Agent
+	cfgIf   *fakeConfigIf
+	applied []int64
+	fail    map[int64]bool
+}
+
+func newRolloutTest(t *testing.T) *rolloutTest {
+	rt := &rolloutTest{
+		t:     t,
+		cfgIf: &fakeConfigIf{},
+		fail:  map[int64]bool{},
+	}
+	rt.agent = &Agent{
+		nodeName:  "node0",
+		namespace: "kube-system",
+		cfgIf:     rt.cfgIf,
+		notifyFn: func(cfg interface{}) (bool, error) {
+			gen := cfg.(metav1.Object).GetGeneration()
+			if rt.fail[gen] {
+				return false, fmt.Errorf("generation %d failed", gen)
+			}
+			rt.applied = append(rt.applied, gen)
+			return false, nil
+		},
+		eventLimiter: newEventLimiter(),
+	}
+	return rt
+}
+
+// update delivers a group config update with the given node statuses.
+func (rt *rolloutTest) update(gen int64, maxUnavailable intstr.IntOrString, nodes map[string]cfgapi.NodeStatus) {
+	cfg := &cfgapi.TemplatePolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "default",
+			UID:             "uid",
+			Generation:      gen,
+			ResourceVersion: fmt.Sprintf("%d-%d", gen, len(rt.cfgIf.patches)),
+		},
+	}
+	cfg.Spec.Agent.Rollout = &cfgapi.RolloutConfig{MaxUnavailable: maxUnavailable}
+	cfg.Status.Nodes = nodes
+	rt.agent.updateGroupConfig(cfg)
+}
+
+func (rt *rolloutTest) requireApplied(gens ...int64) {
+	require.Equal(rt.t, gens, rt.applied)
+}
+
+func (rt *rolloutTest) requireStatus(status string, gen int64) {
+	p := rt.cfgIf.lastPatch()
+	require.NotNil(rt.t, p)
+	require.Equal(rt.t, status, p.status.Status)
+	require.Equal(rt.t, gen, p.status.Generation)
+}
+
+func nodeStatus(status string, gen int64) cfgapi.NodeStatus {
+	return cfgapi.NodeStatus{Status: status, Generation: gen, Timestamp: metav1.Now()}
+}
+
+func TestStagedRollout(t *testing.T) {
+	var (
+		one  = intstr.FromInt32(1)
+		half = intstr.FromString("50%")
+	)
+
+	rt := newRolloutTest(t)
+
+	// The initial configuration is taken into use right away.
+	rt.update(1, one, nil)
+	rt.requireApplied(1)
+	rt.requireStatus(cfgapi.StatusSuccess, 1)
+
+	// A new generation waits while the maximum number of nodes is updating.
+	rt.update(2, one, map[string]cfgapi.NodeStatus{
+		"node0": nodeStatus(cfgapi.StatusSuccess, 1),
+		"node1": nodeStatus(cfgapi.StatusUpdating, 2),
+	})
+	rt.requireApplied(1)
+
+	rt.update(2, half, map[string]cfgapi.NodeStatus{
+		"node0": nodeStatus(cfgapi.StatusSuccess, 1),
+		"node1": nodeStatus(cfgapi.StatusUpdating, 2),
+		"node2": nodeStatus(cfgapi.StatusUpdating, 2),
+		"node3": nodeStatus(cfgapi.StatusSuccess, 1),
+	})
+	rt.requireApplied(1)
+
+	// Once a slot is free, it is claimed and the new generation taken into use.
+	rt.update(2, half, map[string]cfgapi.NodeStatus{
+		"node0": nodeStatus(cfgapi.StatusSuccess, 1),
+		"node1": nodeStatus(cfgapi.StatusSuccess, 2),
+		"node2": nodeStatus(cfgapi.StatusUpdating, 2),
+		"node3": nodeStatus(cfgapi.StatusSuccess, 1),
+	})
+	rt.requireApplied(1, 2)
+	rt.requireStatus(cfgapi.StatusSuccess, 2)
+	claim := rt.cfgIf.patches[len(rt.cfgIf.patches)-2]
+	require.Equal(t, cfgapi.StatusUpdating, claim.status.Status)
+	require.Equal(t, int64(2), claim.status.Generation)
+	require.NotEmpty(t, claim.version)
+
+	// A failure on another node rolls back to the last known-good generation.
+	failed := map[string]cfgapi.NodeStatus{
+		"node0": nodeStatus(cfgapi.StatusSuccess, 2),
+		"node1": nodeStatus(cfgapi.StatusSuccess, 2),
+		"node2": nodeStatus(cfgapi.StatusFailure, 2),
+		"node3": nodeStatus(cfgapi.StatusSuccess, 1),
+	}
+	rt.update(2, half, failed)
+	rt.requireApplied(1, 2, 1)
+	rt.requireStatus(cfgapi.StatusRolledBack, 1)
+
+	// The failed generation is not taken into use again.
+	rt.update(2, half, failed)
+	rt.requireApplied(1, 2, 1)
+
+	// A newer generation is rolled out again.
+	rt.update(3, half, map[string]cfgapi.NodeStatus{
+		"node0": nodeStatus(cfgapi.StatusRolledBack, 1),
+		"node2": nodeStatus(cfgapi.StatusFailure, 2),
+	})
+	rt.requireApplied(1, 2, 1, 3)
+	rt.requireStatus(cfgapi.StatusSuccess, 3)
+}
+
+func TestStagedRolloutPause(t *testing.T) {
+	one := intstr.FromInt32(1)
+
+	rt := newRolloutTest(t)
+	rt.update(1, one, nil)
+
+	// Rollout is paused once any node fails.
+	rt.update(2, one, map[string]cfgapi.NodeStatus{
+		"node0": nodeStatus(cfgapi.StatusSuccess, 1),
+		"node1": nodeStatus(cfgapi.StatusFailure, 2),
+	})
+	rt.requireApplied(1)
+	rt.update(2, one, map[string]cfgapi.NodeStatus{
+		"node0": nodeStatus(cfgapi.StatusSuccess, 1),
+		"node1": nodeStatus(cfgapi.StatusSuccess, 1),
+	})
+	rt.requireApplied(1)
+
+	// A failure on this node keeps the last known-good generation in use.
+	rt.fail[3] = true
+	rt.update(3, one, map[string]cfgapi.NodeStatus{
+		"node0": nodeStatus(cfgapi.StatusSuccess, 1),
+	})
+	rt.requireApplied(1)
+	rt.requireStatus(cfgapi.StatusFailure, 3)
+	require.Equal(t, int64(1), rt.agent.groupCfg.GetGeneration())
+
+	rt.update(3, one, map[string]cfgapi.NodeStatus{
+		"node0": nodeStatus(cfgapi.StatusFailure, 3),
+	})
+	rt.requireApplied(1)
+
+	// A newer generation is rolled out again.
+	rt.update(4, one, nil)
+	rt.requireApplied(1, 4)
+
+	// Without staged rollout, new generations are taken into use at once.
+	cfg := rt.agent.groupCfg.(*cfgapi.TemplatePolicy).DeepCopy()
+	cfg.Generation = 5
+	cfg.Spec.Agent.Rollout = nil
+	cfg.Status.Nodes = map[string]cfgapi.NodeStatus{
+		"node1": nodeStatus(cfgapi.StatusUpdating, 5),
+	}
+	rt.agent.updateGroupConfig(cfg)
+	rt.requireApplied(1, 4, 5)
+}
+
+func TestStagedRolloutSlotExpiry(t *testing.T) {
+	one := intstr.FromInt32(1)
+
+	rt := newRolloutTest(t)
+	rt.update(1, one, nil)
+
+	// A slot claimed too long ago by another node is not held any more.
+	stale := nodeStatus(cfgapi.StatusUpdating, 2)
+	stale.Timestamp = metav1.NewTime(time.Now().Add(-2 * rolloutSlotExpiry))
+	rt.update(2, one, map[string]cfgapi.NodeStatus{
+		"node0": nodeStatus(cfgapi.StatusSuccess, 1),
+		"node1": stale,
+	})
+	rt.requireApplied(1, 2)
+	rt.requireStatus(cfgapi.StatusSuccess, 2)
+}
+
+func TestStagedRolloutRestart(t *testing.T) {
+	var (
+		one      = intstr.FromInt32(1)
+		stateDir = t.TempDir()
+	)
+
+	rt := newRolloutTest(t)
+	rt.agent.SetStateDir(stateDir)
+	rt.update(1, one, nil)
+	rt.update(2, one, map[string]cfgapi.NodeStatus{
+		"node0": nodeStatus(cfgapi.StatusSuccess, 1),
+	})
+	rt.requireApplied(1, 2)
+	require.FileExists(t, filepath.Join(stateDir, rolloutDir, "default.json"))
+	entries, err := os.ReadDir(filepath.Join(stateDir, rolloutDir))
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "leftover temporary rollout state files")
+
+	// A restarted node still rolls back to the last known-good generation.
+	rt = newRolloutTest(t)
+	rt.agent.SetStateDir(stateDir)
+	rt.update(2, one, map[string]cfgapi.NodeStatus{
+		"node0": nodeStatus(cfgapi.StatusUpdating, 2),
+	})
+	rt.requireApplied(2)
+
+	rt.update(2, one, map[string]cfgapi.NodeStatus{
+		"node0": nodeStatus(cfgapi.StatusSuccess, 2),
+		"node1": nodeStatus(cfgapi.StatusFailure, 2),
+	})
+	rt.requireApplied(2, 1)
+	rt.requireStatus(cfgapi.StatusRolledBack, 1)
+	require.NoFileExists(t, filepath.Join(stateDir, rolloutDir, "default.json"))
+}
@@ -0,0 +1,132 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1"
+)
+
+// ConfigFileStatus is the status of taking a configuration file into use.
+// In standalone mode, it replaces the node status of configuration custom
+// resources.
+type ConfigFileStatus struct {
+	// File is the configuration file.
+	File string `json:"file"`
+	// Status of taking the configuration into use, Success or Failure.
+	Status string `json:"status"`
+	// Error provides further details of a configuration error.
+	Error string `json:"error,omitempty"`
+	// Timestamp of setting this status.
+	Timestamp metav1.Time `json:"timestamp"`
+}
+
+// ConfigFileStatus returns the status of the configuration file. It is only
+// available when running standalone with a configuration file.
+func (a *Agent) ConfigFileStatus() (*ConfigFileStatus, error) {
+	if !a.hasLocalConfig() {
+		return nil, fmt.Errorf("no configuration file, config status is reported in custom resources")
+	}
+
+	a.statusLock.RLock()
+	defer a.statusLock.RUnlock()
+
+	if a.localStatus == nil {
+		return nil, fmt.Errorf("no status for configuration file %s yet", a.configFile)
+	}
+
+	status := *a.localStatus
+	return &status, nil
+}
+
+// setConfigFileStatus updates the status of the configuration file and
+// writes it to the status file, if we have one.
+func (a *Agent) setConfigFileStatus(err error) {
+	status := &ConfigFileStatus{
+		File:      a.configFile,
+		Status:    cfgapi.StatusSuccess,
+		Timestamp: metav1.Now(),
+	}
+	if err != nil {
+		status.Status = cfgapi.StatusFailure
+		status.Error = err.Error()
+	}
+
+	a.statusLock.Lock()
+	a.localStatus = status
+	a.statusLock.Unlock()
+
+	if a.statusFile == "" {
+		return
+	}
+
+	if err := writeStatusFile(a.statusFile, status); err != nil {
+		log.Errorf("failed to write config status file: %v", err)
+	}
+}
+
+// configFileError updates the status of the configuration file on a watch
+// error, typically a failure to parse an updated file. The configuration in
+// use is left intact.
+func (a *Agent) configFileError(obj runtime.Object) {
+	if !a.hasLocalConfig() {
+		return
+	}
+
+	msg := "unknown configuration file watch error"
+	if s, ok := obj.(*metav1.Status); ok && s.Message != "" {
+		msg = s.Message
+	}
+
+	log.Errorf("failed to update configuration: %s", msg)
+	a.setConfigFileStatus(fmt.Errorf("%s", msg))
+}
+
+// writeStatusFile writes the status to a file, replacing it atomically.
+func writeStatusFile(path string, status *ConfigFileStatus) error {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config status: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create config status file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(append(data, '\n')); err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write config status file %s: %w", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename config status file to %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1"
+)
+
+const (
+	validConfigFile = `
+metadata:
+  name: test
+spec:
+  reservedResources:
+    cpu: 750m
+`
+	invalidConfigFile = `
+metadata:
+  name: test
+spec:
+  pinCPU: true
+`
+	unparsableConfigFile = `
+metadata:
+  name: test
+spec:
+  noSuchField: true
+`
+)
+
+func TestStandaloneConfigStatus(t *testing.T) {
+	var (
+		dir        = t.TempDir()
+		configFile = filepath.Join(dir, "config.yaml")
+		statusFile = filepath.Join(dir, "status.json")
+		lock       sync.Mutex
+		notified   int
+	)
+
+	writeConfigFile(t, configFile, validConfigFile)
+
+	a, err := New(TopologyAwareConfigInterface(),
+		WithConfigFile(configFile),
+		WithConfigStatusFile(statusFile),
+	)
+	require.NoError(t, err)
+
+	_, err = a.ConfigFileStatus()
+	require.Error(t, err, "status before initial configuration")
+
+	go func() {
+		require.NoError(t, a.Start(func(cfg interface{}) (bool, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			notified++
+			return false, nil
+		}))
+	}()
+	defer a.Stop()
+
+	requireStatus := func(status, errMsg string) {
+		t.Helper()
+		require.Eventually(t, func() bool {
+			data, err := os.ReadFile(statusFile)
+			if err != nil {
+				return false
+			}
+			s := &ConfigFileStatus{}
+			if err := json.Unmarshal(data, s); err != nil {
+				return false
+			}
+			return s.File == configFile && s.Status == status &&
+				strings.Contains(s.Error, errMsg)
+		}, 5*time.Second, 10*time.Millisecond)
+
+		s, err := a.ConfigFileStatus()
+		require.NoError(t, err)
+		require.Equal(t, status, s.Status)
+	}
+
+	requireStatus(cfgapi.StatusSuccess, "")
+
+	writeConfigFile(t, configFile, unparsableConfigFile)
+	requireStatus(cfgapi.StatusFailure, "failed to unmarshal")
+
+	writeConfigFile(t, configFile, invalidConfigFile)
+	requireStatus(cfgapi.StatusFailure, "missing CPU reservation")
+
+	writeConfigFile(t, configFile, validConfigFile)
+	requireStatus(cfgapi.StatusSuccess, "")
+
+	lock.Lock()
+	require.Equal(t, 2, notified, "only valid configuration taken into use")
+	lock.Unlock()
+}
+
+func TestConfigStatusFileWithoutConfigFile(t *testing.T) {
+	t.Setenv("NODE_NAME", "node0")
+	_, err := New(TopologyAwareConfigInterface(),
+		WithConfigFile(""),
+		WithConfigStatusFile("status.json"),
+	)
+	require.Error(t, err)
+}
+
+// writeConfigFile replaces the content of a configuration file atomically.
+func writeConfigFile(t *testing.T, file, content string) {
+	tmp := file + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte(content), 0o644))
+	require.NoError(t, os.Rename(tmp, file))
+}
@@ -139,6 +139,7 @@ func (w *FileWatch) run() error {
 					obj, err := w.readAndUnmarshal()
 					if err != nil {
 						log.Warnf("%v", err)
+						w.sendError(err)
 						continue
 					}
 
@@ -148,6 +149,7 @@ func (w *FileWatch) run() error {
 					obj, err := w.readAndUnmarshal()
 					if err != nil {
 						log.Warnf("%v", err)
+						w.sendError(err)
 						continue
 					}
 
@@ -171,6 +173,19 @@ func (w *FileWatch) sendEvent(t EventType, obj runtime.Object) {
 	}
 }
 
+// sendError sends an error event for a file which could not be read or
+// parsed. Unlike a failure to receive file system events, these errors do
+// not terminate the watch.
+func (w *FileWatch) sendError(err error) {
+	w.sendEvent(
+		Error,
+		&metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: err.Error(),
+		},
+	)
+}
+
 func (w *FileWatch) readAndUnmarshal() (runtime.Object, error) {
 	file := path.Join(w.dir, w.file)
 
@@ -14,6 +14,10 @@
 
 package v1alpha1
 
+import (
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
 // AgentConfig provides access to configuration data for the agent.
 type AgentConfig struct {
 	// NodeResourceTopology enables support for exporting resource usage using
@@ -23,6 +27,20 @@ type AgentConfig struct {
 	// PodResourceAPI enables support for querying kubelet Pod Resource API.
 	// +optional
 	PodResourceAPI bool `json:"podResourceAPI,omitempty"`
+	// Rollout enables staged rollout of new generations of a configuration
+	// shared by several nodes.
+	// +optional
+	Rollout *RolloutConfig `json:"rollout,omitempty"`
+}
+
+// RolloutConfig controls staged rollout of new configuration generations.
+type RolloutConfig struct {
+	// MaxUnavailable is the maximum number or percentage of nodes taking
+	// a new generation of the configuration into use at the same time.
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:XIntOrString
+	// +optional
+	MaxUnavailable intstr.IntOrString `json:"maxUnavailable,omitempty"`
 }
 
 // GetAgentConfig returns the agent-specific configuration if we have one.
@@ -39,6 +39,13 @@ func (c *BalloonsPolicy) CommonConfig() *CommonConfig {
 	}
 }
 
+func (c *BalloonsPolicy) ConfigStatus() *ConfigStatus {
+	if c == nil {
+		return nil
+	}
+	return &c.Status
+}
+
 func (c *BalloonsPolicy) PolicyConfig() interface{} {
 	if c == nil {
 		return nil
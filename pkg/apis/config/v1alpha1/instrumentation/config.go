@@ -35,7 +35,14 @@ type Config struct {
 	// +optional
 	// +kubebuilder:example="otlp-http://localhost:4318"
 	TracingCollector string `json:"tracingCollector,omitempty"`
-	// ReportPeriod is the interval between collecting polled metrics.
+	// MetricsCollector defines the external endpoint for pushing metrics to
+	// using OTLP. Endpoints are specified like for TracingCollector. Metrics
+	// enabled in Metrics are pushed once every ReportPeriod.
+	// +optional
+	// +kubebuilder:example="otlp-grpc://localhost:4317"
+	MetricsCollector string `json:"metricsCollector,omitempty"`
+	// ReportPeriod is the interval between collecting polled metrics, and
+	// between pushing metrics to the MetricsCollector, if one is set.
 	// +optional
 	// +kubebuilder:validation:Format="duration"
 	// +kubebuilder:default="30s"
@@ -49,6 +56,6 @@ type Config struct {
 	// +optional
 	PrometheusExport bool `json:"prometheusExport,omitempty"`
 	// Metrics defines which metrics to collect.
-	// +kubebuilder:default={"enabled": {"policy", "buildinfo"}}
+	// +kubebuilder:default={"enabled": {"policy", "buildinfo", "golang"}}
 	Metrics *metrics.Config `json:"metrics,omitempty"`
 }
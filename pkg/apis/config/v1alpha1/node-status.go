@@ -27,8 +27,20 @@ const (
 	StatusSuccess = metav1.StatusSuccess
 	// StatusFailure indicates failure to take a configuration into use.
 	StatusFailure = metav1.StatusFailure
+	// StatusUpdating indicates a staged rollout in progress to a generation.
+	StatusUpdating = "Updating"
+	// StatusRolledBack indicates rolling back to an earlier generation.
+	StatusRolledBack = "RolledBack"
 )
 
+// GetConfigStatus returns the status of a configuration if it has one.
+func GetConfigStatus(cfg interface{}) *ConfigStatus {
+	if cs, ok := cfg.(interface{ ConfigStatus() *ConfigStatus }); ok {
+		return cs.ConfigStatus()
+	}
+	return nil
+}
+
 // NewNodeStatus create a node status for the given generation and error.
 func NewNodeStatus(err error, generation int64) *NodeStatus {
 	s := &NodeStatus{
@@ -51,6 +63,13 @@ func NewNodeStatus(err error, generation int64) *NodeStatus {
 
 // NodeStatusPatch creates a (MergePatch) for the given node status.
 func NodeStatusPatch(node string, status *NodeStatus) ([]byte, types.PatchType, error) {
+	return NodeStatusPatchForVersion(node, status, "")
+}
+
+// NodeStatusPatchForVersion creates a (MergePatch) for the given node status
+// which only succeeds if the resource version of the configuration matches.
+// An empty resource version matches any version.
+func NodeStatusPatchForVersion(node string, status *NodeStatus, version string) ([]byte, types.PatchType, error) {
 	cfg := &patchConfig{
 		Status: patchStatus{
 			Nodes: map[string]*NodeStatus{
@@ -58,6 +77,11 @@ func NodeStatusPatch(node string, status *NodeStatus) ([]byte, types.PatchType,
 			},
 		},
 	}
+	if version != "" {
+		cfg.Metadata = &patchMetadata{
+			ResourceVersion: version,
+		}
+	}
 
 	data, err := json.Marshal(cfg)
 	if err != nil {
@@ -68,7 +92,12 @@ func NodeStatusPatch(node string, status *NodeStatus) ([]byte, types.PatchType,
 }
 
 type patchConfig struct {
-	Status patchStatus `json:"status,omitempty"`
+	Metadata *patchMetadata `json:"metadata,omitempty"`
+	Status   patchStatus    `json:"status,omitempty"`
+}
+
+type patchMetadata struct {
+	ResourceVersion string `json:"resourceVersion,omitempty"`
 }
 
 type patchStatus struct {
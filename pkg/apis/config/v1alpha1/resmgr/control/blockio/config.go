@@ -0,0 +1,60 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blockio
+
+// +k8s:deepcopy-gen=true
+type Config struct {
+	// Classes define the block I/O classes containers can be assigned to.
+	Classes map[string]Class `json:"classes"`
+}
+
+// +k8s:deepcopy-gen=true
+type Class struct {
+	// Weight is the relative block I/O weight of this class.
+	// +kubebuilder:validation:Minimum=10
+	// +kubebuilder:validation:Maximum=1000
+	// +optional
+	Weight uint `json:"weight,omitempty"`
+	// Devices set weights and throttling limits for specific devices.
+	// +optional
+	Devices []Device `json:"devices,omitempty"`
+}
+
+// +k8s:deepcopy-gen=true
+type Device struct {
+	// Paths are the block device paths, or globs matching them, for
+	// instance /dev/sda or /dev/nvme*n1, the parameters apply to.
+	// +kubebuilder:validation:MinItems=1
+	Paths []string `json:"paths"`
+	// Weight is the relative block I/O weight on these devices.
+	// +kubebuilder:validation:Minimum=10
+	// +kubebuilder:validation:Maximum=1000
+	// +optional
+	Weight uint `json:"weight,omitempty"`
+	// ReadBps limits reading from these devices, in bytes per second.
+	// +kubebuilder:example="100Mi"
+	// +optional
+	ReadBps string `json:"readBps,omitempty"`
+	// WriteBps limits writing to these devices, in bytes per second.
+	// +kubebuilder:example="50Mi"
+	// +optional
+	WriteBps string `json:"writeBps,omitempty"`
+	// ReadIOPS limits reading from these devices, in operations per second.
+	// +optional
+	ReadIOPS uint `json:"readIOPS,omitempty"`
+	// WriteIOPS limits writing to these devices, in operations per second.
+	// +optional
+	WriteIOPS uint `json:"writeIOPS,omitempty"`
+}
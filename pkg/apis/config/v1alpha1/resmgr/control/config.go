@@ -15,11 +15,20 @@
 package control
 
 import (
+	"github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control/blockio"
 	"github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control/cpu"
+	"github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control/drift"
+	"github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control/rdt"
 )
 
 // +k8s:deepcopy-gen=true
 type Config struct {
 	// +optional
 	CPU *cpu.Config `json:"cpu,omitempty"`
+	// +optional
+	RDT *rdt.Config `json:"rdt,omitempty"`
+	// +optional
+	BlockIO *blockio.Config `json:"blockio,omitempty"`
+	// +optional
+	Drift *drift.Config `json:"drift,omitempty"`
 }
@@ -0,0 +1,48 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drift
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultInterval is the default interval between drift checks.
+	DefaultInterval = time.Minute
+)
+
+// Config enables detecting containers whose cpuset has been changed
+// behind our back, and optionally restoring the cpuset we assigned.
+// +k8s:deepcopy-gen=true
+type Config struct {
+	// Interval is the time between checking the cpusets of containers.
+	// +optional
+	// +kubebuilder:validation:Format="duration"
+	// +kubebuilder:default="1m"
+	Interval metav1.Duration `json:"interval,omitempty"`
+	// Repair enables restoring the assigned cpusets of drifted containers.
+	// +optional
+	Repair bool `json:"repair,omitempty"`
+}
+
+// GetInterval returns the configured interval or the default one.
+func (c *Config) GetInterval() time.Duration {
+	if c == nil || c.Interval.Duration <= 0 {
+		return DefaultInterval
+	}
+	return c.Interval.Duration
+}
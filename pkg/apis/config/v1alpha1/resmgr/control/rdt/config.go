@@ -0,0 +1,53 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdt
+
+const (
+	// DefaultRoot is the default mount point of the resctrl filesystem.
+	DefaultRoot = "/sys/fs/resctrl"
+)
+
+// +k8s:deepcopy-gen=true
+type Config struct {
+	// Root is the mount point of the resctrl filesystem.
+	// +kubebuilder:default="/sys/fs/resctrl"
+	// +optional
+	Root string `json:"root,omitempty"`
+	// Classes define the RDT classes containers can be assigned to.
+	// A resctrl group with the name of the class is created for each.
+	Classes map[string]Class `json:"classes"`
+}
+
+type Class struct {
+	// L3Ways is the range of L3 cache ways, for instance 0-3, this class
+	// is allowed to allocate on all cache domains.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(-[0-9]+)?$`
+	// +optional
+	L3Ways string `json:"l3Ways,omitempty"`
+	// MBAPercent is the memory bandwidth limit of this class in percents
+	// of the total bandwidth on all memory bandwidth domains.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	MBAPercent uint `json:"mbaPercent,omitempty"`
+}
+
+// GetRoot returns the configured resctrl mount point or the default one.
+func (c *Config) GetRoot() string {
+	if c == nil || c.Root == "" {
+		return DefaultRoot
+	}
+	return c.Root
+}
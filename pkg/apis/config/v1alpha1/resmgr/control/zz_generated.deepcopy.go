@@ -19,7 +19,10 @@
 package control
 
 import (
+	"github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control/blockio"
 	"github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control/cpu"
+	"github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control/drift"
+	"github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control/rdt"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -30,6 +33,21 @@ func (in *Config) DeepCopyInto(out *Config) {
 		*out = new(cpu.Config)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RDT != nil {
+		in, out := &in.RDT, &out.RDT
+		*out = new(rdt.Config)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BlockIO != nil {
+		in, out := &in.BlockIO, &out.BlockIO
+		*out = new(blockio.Config)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = new(drift.Config)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Config.
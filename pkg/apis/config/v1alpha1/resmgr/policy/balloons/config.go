@@ -16,12 +16,14 @@ package balloons
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 
 	policy "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/policy"
 	resmgr "github.com/containers/nri-plugins/pkg/apis/resmgr/v1alpha1"
 	"github.com/containers/nri-plugins/pkg/cpuallocator"
 	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type (
@@ -39,6 +41,13 @@ const (
 	AmountCPUSet   = policy.AmountCPUSet
 )
 
+const (
+	// ReservedBalloonDefName is the name of the reserved balloon type.
+	ReservedBalloonDefName = "reserved"
+	// DefaultBalloonDefName is the name of the default balloon type.
+	DefaultBalloonDefName = "default"
+)
+
 // +k8s:deepcopy-gen=true
 type Config struct {
 	// PinCPU controls pinning containers to CPUs.
@@ -72,8 +81,11 @@ type Config struct {
 	// overridden with the balloon type specific setting with the same
 	// name.
 	PreferSpreadOnPhysicalCores bool `json:"preferSpreadOnPhysicalCores,omitempty"`
-	// BallonDefs contains balloon type definitions.
-	BalloonDefs []*BalloonDef `json:"balloonTypes,omitempty"`
+	// BallonDefs contains balloon type definitions. When configuration
+	// layers are merged, balloon types are merged by name.
+	// +patchMergeKey=name
+	// +patchStrategy=merge
+	BalloonDefs []*BalloonDef `json:"balloonTypes,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
 	// Available/allowed (CPU) resources to use.
 	AvailableResources Constraints `json:"availableResources,omitempty"`
 	// Reserved (CPU) resources for kube-system namespace.
@@ -190,6 +202,12 @@ type BalloonDef struct {
 	// CpuClass controls how CPUs of a balloon are (re)configured
 	// whenever a balloon is created, inflated or deflated.
 	CpuClass string `json:"cpuClass,omitempty"`
+	// RdtClass is the RDT class containers in balloons of this type
+	// are assigned to, unless overridden by a pod annotation.
+	RdtClass string `json:"rdtClass,omitempty"`
+	// BlockioClass is the block I/O class containers in balloons of
+	// this type are assigned to, unless overridden by a pod annotation.
+	BlockioClass string `json:"blockioClass,omitempty"`
 	// MinBalloons is the number of balloon instances that always
 	// exist even if they would become empty. At init this number
 	// of instances will be created before assigning any
@@ -239,6 +257,86 @@ type BalloonDef struct {
 	// +optional
 	// +kubebuilder:validation:Enum=efficient;performance
 	PreferCoreType string `json:"preferCoreType,omitempty"`
+	// DynamicResizing enables resizing balloons of this type based
+	// on the observed CPU usage and pressure of their containers,
+	// in addition to the CPU requests of the containers.
+	// +optional
+	DynamicResizing *DynamicResizing `json:"dynamicResizing,omitempty"`
+}
+
+const (
+	// DefaultGrowUtilization is the default growUtilization of dynamic resizing.
+	DefaultGrowUtilization = 90
+	// DefaultShrinkUtilization is the default shrinkUtilization of dynamic resizing.
+	DefaultShrinkUtilization = 30
+)
+
+// DynamicResizing controls resizing balloons based on observed CPU usage.
+// +k8s:deepcopy-gen=true
+type DynamicResizing struct {
+	// Period is the interval between CPU usage samples.
+	// +optional
+	// +kubebuilder:validation:Format="duration"
+	// +kubebuilder:default="10s"
+	Period metav1.Duration `json:"period,omitempty"`
+	// GrowUtilization is the CPU utilization, in percent of
+	// the CPUs of a balloon, at or above which the balloon is
+	// inflated.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=90
+	GrowUtilization int `json:"growUtilization,omitempty"`
+	// GrowPressure is the CPU pressure, in percent of time any
+	// container in a balloon was stalled waiting for a CPU or
+	// throttled by its CPU quota, at or above which the balloon is
+	// inflated.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=10
+	GrowPressure int `json:"growPressure,omitempty"`
+	// ShrinkUtilization is the CPU utilization, in percent of
+	// the CPUs of a balloon, at or below which the balloon is
+	// deflated. It must be less than GrowUtilization. Setting it
+	// to 0 only deflates balloons which are completely idle.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=30
+	ShrinkUtilization *int `json:"shrinkUtilization,omitempty"`
+	// Samples is the number of consecutive samples that must call
+	// for inflating or deflating a balloon before it is resized.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	Samples int `json:"samples,omitempty"`
+	// Cooldown is the minimum time between two usage-driven
+	// resizes of a balloon.
+	// +optional
+	// +kubebuilder:validation:Format="duration"
+	// +kubebuilder:default="60s"
+	Cooldown metav1.Duration `json:"cooldown,omitempty"`
+	// Step is the number of CPUs added to or removed from a
+	// balloon in one resize.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	Step int `json:"step,omitempty"`
+}
+
+// GetGrowUtilization returns the configured growUtilization or the default one.
+func (dr *DynamicResizing) GetGrowUtilization() int {
+	if dr == nil || dr.GrowUtilization == 0 {
+		return DefaultGrowUtilization
+	}
+	return dr.GrowUtilization
+}
+
+// GetShrinkUtilization returns the configured shrinkUtilization or the default one.
+func (dr *DynamicResizing) GetShrinkUtilization() int {
+	if dr == nil || dr.ShrinkUtilization == nil {
+		return DefaultShrinkUtilization
+	}
+	return *dr.ShrinkUtilization
 }
 
 // String stringifies a BalloonDef
@@ -285,6 +383,9 @@ func (cmc *ContainerMatchConfig) MatchContainer(c cache.Container) (string, erro
 
 func (c *Config) Validate() error {
 	errs := []error{}
+	if err := policy.CheckResources(c.AvailableResources, c.ReservedResources); err != nil {
+		errs = append(errs, err)
+	}
 	if c.Preserve != nil {
 		for _, expr := range c.Preserve.MatchExpressions {
 			if err := expr.Validate(); err != nil {
@@ -292,11 +393,59 @@ func (c *Config) Validate() error {
 			}
 		}
 	}
+	seenNames := map[string]struct{}{}
 	for _, blnDef := range c.BalloonDefs {
-		for _, expr := range blnDef.MatchExpressions {
-			if err := expr.Validate(); err != nil {
-				errs = append(errs, err)
-			}
+		if err := blnDef.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		if blnDef.Name == "" {
+			continue
+		}
+		if _, ok := seenNames[blnDef.Name]; ok {
+			errs = append(errs, fmt.Errorf("two balloon types with the same name: %q", blnDef.Name))
+		}
+		seenNames[blnDef.Name] = struct{}{}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks a balloon definition for errors.
+func (bdef *BalloonDef) Validate() error {
+	errs := []error{}
+	if bdef.Name == "" {
+		errs = append(errs, errors.New("missing or empty name in a balloon type"))
+	}
+	for _, expr := range bdef.MatchExpressions {
+		if err := expr.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if bdef.MaxCpus != 0 && bdef.MinCpus > bdef.MaxCpus {
+		errs = append(errs, fmt.Errorf("minCPUs (%d) > maxCPUs (%d) in balloon type %q",
+			bdef.MinCpus, bdef.MaxCpus, bdef.Name))
+	}
+	if bdef.MaxBalloons != 0 && bdef.MinBalloons > bdef.MaxBalloons {
+		errs = append(errs, fmt.Errorf("minBalloons (%d) > maxBalloons (%d) in balloon type %q",
+			bdef.MinBalloons, bdef.MaxBalloons, bdef.Name))
+	}
+	if bdef.Name == ReservedBalloonDefName {
+		if bdef.MinBalloons < 0 || bdef.MinBalloons > 1 {
+			errs = append(errs, fmt.Errorf("exactly one %q balloon expected but minBalloons=%d",
+				bdef.Name, bdef.MinBalloons))
+		}
+		if bdef.MaxBalloons < 0 || bdef.MaxBalloons > 1 {
+			errs = append(errs, fmt.Errorf("exactly one %q balloon expected but maxBalloons=%d",
+				bdef.Name, bdef.MaxBalloons))
+		}
+	}
+	if dr := bdef.DynamicResizing; dr != nil {
+		if bdef.Name == ReservedBalloonDefName {
+			errs = append(errs, fmt.Errorf("dynamicResizing is not supported in balloon type %q",
+				bdef.Name))
+		}
+		if shrink, grow := dr.GetShrinkUtilization(), dr.GetGrowUtilization(); shrink >= grow {
+			errs = append(errs, fmt.Errorf("dynamicResizing shrinkUtilization (%d) >= growUtilization (%d) in balloon type %q",
+				shrink, grow, bdef.Name))
 		}
 	}
 	return errors.Join(errs...)
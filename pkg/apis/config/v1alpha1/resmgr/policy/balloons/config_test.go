@@ -0,0 +1,70 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package balloons_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/policy/balloons"
+)
+
+func TestValidateDynamicResizing(t *testing.T) {
+	zero, ten, fifty := 0, 10, 50
+
+	for _, tc := range []struct {
+		name    string
+		dr      *balloons.DynamicResizing
+		invalid bool
+	}{
+		{
+			name: "all defaults",
+			dr:   &balloons.DynamicResizing{},
+		},
+		{
+			name: "zero shrinkUtilization",
+			dr:   &balloons.DynamicResizing{ShrinkUtilization: &zero},
+		},
+		{
+			name: "growUtilization above default shrinkUtilization",
+			dr:   &balloons.DynamicResizing{GrowUtilization: 40},
+		},
+		{
+			name:    "growUtilization below default shrinkUtilization",
+			dr:      &balloons.DynamicResizing{GrowUtilization: 20},
+			invalid: true,
+		},
+		{
+			name: "shrinkUtilization below growUtilization",
+			dr:   &balloons.DynamicResizing{GrowUtilization: 20, ShrinkUtilization: &ten},
+		},
+		{
+			name:    "shrinkUtilization equal to growUtilization",
+			dr:      &balloons.DynamicResizing{GrowUtilization: 50, ShrinkUtilization: &fifty},
+			invalid: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bdef := &balloons.BalloonDef{Name: "dynamic", DynamicResizing: tc.dr}
+			err := bdef.Validate()
+			if tc.invalid {
+				require.ErrorContains(t, err, "shrinkUtilization")
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
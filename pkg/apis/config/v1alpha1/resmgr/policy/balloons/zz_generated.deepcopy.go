@@ -73,6 +73,11 @@ func (in *BalloonDef) DeepCopyInto(out *BalloonDef) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.DynamicResizing != nil {
+		in, out := &in.DynamicResizing, &out.DynamicResizing
+		*out = new(DynamicResizing)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BalloonDef.
@@ -145,6 +150,28 @@ func (in *Config) DeepCopy() *Config {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DynamicResizing) DeepCopyInto(out *DynamicResizing) {
+	*out = *in
+	out.Period = in.Period
+	if in.ShrinkUtilization != nil {
+		in, out := &in.ShrinkUtilization, &out.ShrinkUtilization
+		*out = new(int)
+		**out = **in
+	}
+	out.Cooldown = in.Cooldown
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DynamicResizing.
+func (in *DynamicResizing) DeepCopy() *DynamicResizing {
+	if in == nil {
+		return nil
+	}
+	out := new(DynamicResizing)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ContainerMatchConfig) DeepCopyInto(out *ContainerMatchConfig) {
 	*out = *in
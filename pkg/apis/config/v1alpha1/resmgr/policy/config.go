@@ -15,6 +15,7 @@
 package policy
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -72,3 +73,72 @@ func (amount Amount) ParseQuantity() (resource.Quantity, error) {
 	}
 	return q, nil
 }
+
+// CheckResources checks that available and reserved resource constraints
+// can be parsed and that the reserved CPUs are a subset of the available
+// ones.
+func CheckResources(available, reserved Constraints) error {
+	var (
+		allowed   cpuset.CPUSet
+		limited   bool
+		errs      []error
+		addErrorf = func(format string, args ...interface{}) {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	)
+
+	amount, kind := available.Get(CPU)
+	switch kind {
+	case AmountCPUSet:
+		cset, err := amount.ParseCPUSet()
+		if err != nil {
+			addErrorf("invalid available CPUs: %w", err)
+			break
+		}
+		allowed, limited = cset, true
+	case AmountQuantity:
+		addErrorf("invalid available CPUs %q, expected a cpuset", amount)
+	}
+
+	amount, kind = reserved.Get(CPU)
+	switch kind {
+	case AmountCPUSet:
+		cset, err := amount.ParseCPUSet()
+		if err != nil {
+			addErrorf("invalid reserved CPUs: %w", err)
+			break
+		}
+		if limited && !cset.IsSubsetOf(allowed) {
+			addErrorf("reserved CPUs %s are not a subset of available CPUs %s",
+				cset, allowed)
+		}
+	case AmountQuantity:
+		qty, err := amount.ParseQuantity()
+		if err != nil {
+			addErrorf("invalid reserved CPUs: %w", err)
+			break
+		}
+		if qty.Sign() <= 0 {
+			addErrorf("invalid reserved CPUs %q, expected a positive quantity", amount)
+		}
+		if limited && qty.MilliValue() > int64(1000*allowed.Size()) {
+			addErrorf("reserved CPUs %q exceed available CPUs %s", amount, allowed)
+		}
+	}
+
+	for _, c := range []struct {
+		kind        string
+		constraints Constraints
+	}{
+		{"available", available},
+		{"reserved", reserved},
+	} {
+		if amount, ok := c.constraints[Memory]; ok {
+			if _, err := amount.ParseQuantity(); err != nil {
+				addErrorf("invalid %s memory: %w", c.kind, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
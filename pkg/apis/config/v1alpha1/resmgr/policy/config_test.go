@@ -0,0 +1,99 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/policy"
+)
+
+func TestCheckResources(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		available policy.Constraints
+		reserved  policy.Constraints
+		invalid   bool
+	}{
+		{
+			name:     "reserved cpuset without available resources",
+			reserved: policy.Constraints{policy.CPU: "cpuset:0-1"},
+		},
+		{
+			name:      "reserved cpuset in available cpuset",
+			available: policy.Constraints{policy.CPU: "cpuset:0-7"},
+			reserved:  policy.Constraints{policy.CPU: "cpuset:0,4"},
+		},
+		{
+			name:      "reserved cpuset outside available cpuset",
+			available: policy.Constraints{policy.CPU: "cpuset:2-7"},
+			reserved:  policy.Constraints{policy.CPU: "cpuset:0-2"},
+			invalid:   true,
+		},
+		{
+			name:      "reserved quantity within available cpuset",
+			available: policy.Constraints{policy.CPU: "cpuset:0-1"},
+			reserved:  policy.Constraints{policy.CPU: "1500m"},
+		},
+		{
+			name:      "reserved quantity exceeding available cpuset",
+			available: policy.Constraints{policy.CPU: "cpuset:0-1"},
+			reserved:  policy.Constraints{policy.CPU: "3"},
+			invalid:   true,
+		},
+		{
+			name:     "zero reserved quantity",
+			reserved: policy.Constraints{policy.CPU: "0"},
+			invalid:  true,
+		},
+		{
+			name:      "available quantity",
+			available: policy.Constraints{policy.CPU: "4"},
+			reserved:  policy.Constraints{policy.CPU: "1"},
+			invalid:   true,
+		},
+		{
+			name:     "malformed reserved cpuset",
+			reserved: policy.Constraints{policy.CPU: "cpuset:0-x"},
+			invalid:  true,
+		},
+		{
+			name:     "malformed reserved quantity",
+			reserved: policy.Constraints{policy.CPU: "1 cpu"},
+			invalid:  true,
+		},
+		{
+			name:      "memory quantities",
+			available: policy.Constraints{policy.Memory: "16Gi"},
+			reserved:  policy.Constraints{policy.CPU: "1", policy.Memory: "1Gi"},
+		},
+		{
+			name:     "malformed memory quantity",
+			reserved: policy.Constraints{policy.CPU: "1", policy.Memory: "1 GB"},
+			invalid:  true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.CheckResources(tc.available, tc.reserved)
+			if tc.invalid {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -40,3 +40,7 @@ type Config struct {
 	// +kubebuilder:validation:Required
 	ReservedResources Constraints `json:"reservedResources"`
 }
+
+func (c *Config) Validate() error {
+	return policy.CheckResources(c.AvailableResources, c.ReservedResources)
+}
@@ -15,6 +15,7 @@
 package topologyaware
 
 import (
+	"errors"
 	"strings"
 
 	policy "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/policy"
@@ -110,3 +111,14 @@ type Config struct {
 	// +kubebuilder:validation:Format:string
 	DefaultCPUPriority CPUPriority `json:"defaultCPUPriority,omitempty"`
 }
+
+func (c *Config) Validate() error {
+	errs := []error{}
+	if _, kind := c.ReservedResources.Get(CPU); kind == AmountAbsent {
+		errs = append(errs, errors.New("missing CPU reservation in reservedResources"))
+	}
+	if err := policy.CheckResources(c.AvailableResources, c.ReservedResources); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
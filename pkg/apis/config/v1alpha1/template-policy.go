@@ -39,9 +39,23 @@ func (c *TemplatePolicy) CommonConfig() *CommonConfig {
 	}
 }
 
+func (c *TemplatePolicy) ConfigStatus() *ConfigStatus {
+	if c == nil {
+		return nil
+	}
+	return &c.Status
+}
+
 func (c *TemplatePolicy) PolicyConfig() interface{} {
 	if c == nil {
 		return nil
 	}
 	return &c.Spec.Config
 }
+
+func (c *TemplatePolicy) Validate() error {
+	if c == nil {
+		return nil
+	}
+	return c.Spec.Config.Validate()
+}
@@ -39,9 +39,23 @@ func (c *TopologyAwarePolicy) CommonConfig() *CommonConfig {
 	}
 }
 
+func (c *TopologyAwarePolicy) ConfigStatus() *ConfigStatus {
+	if c == nil {
+		return nil
+	}
+	return &c.Status
+}
+
 func (c *TopologyAwarePolicy) PolicyConfig() interface{} {
 	if c == nil {
 		return nil
 	}
 	return &c.Spec.Config
 }
+
+func (c *TopologyAwarePolicy) Validate() error {
+	if c == nil {
+		return nil
+	}
+	return c.Spec.Config.Validate()
+}
@@ -138,7 +138,7 @@ type ConfigStatus struct {
 // NodeStatus is the configuration status for a single node.
 type NodeStatus struct {
 	// Status of activating the configuration on this node.
-	// +kubebuilder:validation:Enum=Success;Failure
+	// +kubebuilder:validation:Enum=Success;Failure;Updating;RolledBack
 	Status string `json:"status"`
 	// Generation is the generation the configuration this status was set for.
 	Generation int64 `json:"generation"`
@@ -146,6 +146,23 @@ type NodeStatus struct {
 	Error *string `json:"errors,omitempty"`
 	// Timestamp of setting this status.
 	Timestamp metav1.Time `json:"timestamp,omitempty"`
+	// Layers lists the configuration resources, from the lowest to the
+	// highest precedence, merged into the effective configuration.
+	// +optional
+	Layers []ConfigLayer `json:"layers,omitempty"`
+	// Effective is the effective configuration merged from layers. It is
+	// only set for the resource with the highest precedence.
+	// +optional
+	Effective *string `json:"effective,omitempty"`
+}
+
+// ConfigLayer identifies a configuration resource merged into the effective
+// configuration of a node.
+type ConfigLayer struct {
+	// Name of the configuration resource.
+	Name string `json:"name"`
+	// Generation of the configuration resource merged.
+	Generation int64 `json:"generation"`
 }
 
 func init() {
@@ -25,6 +25,11 @@ import (
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AgentConfig) DeepCopyInto(out *AgentConfig) {
 	*out = *in
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutConfig)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentConfig.
@@ -103,7 +108,7 @@ func (in *BalloonsPolicySpec) DeepCopyInto(out *BalloonsPolicySpec) {
 	in.Control.DeepCopyInto(&out.Control)
 	in.Log.DeepCopyInto(&out.Log)
 	in.Instrumentation.DeepCopyInto(&out.Instrumentation)
-	out.Agent = in.Agent
+	in.Agent.DeepCopyInto(&out.Agent)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BalloonsPolicySpec.
@@ -134,6 +139,21 @@ func (in *CommonConfig) DeepCopy() *CommonConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigLayer) DeepCopyInto(out *ConfigLayer) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigLayer.
+func (in *ConfigLayer) DeepCopy() *ConfigLayer {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigLayer)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigStatus) DeepCopyInto(out *ConfigStatus) {
 	*out = *in
@@ -165,6 +185,16 @@ func (in *NodeStatus) DeepCopyInto(out *NodeStatus) {
 		**out = **in
 	}
 	in.Timestamp.DeepCopyInto(&out.Timestamp)
+	if in.Layers != nil {
+		in, out := &in.Layers, &out.Layers
+		*out = make([]ConfigLayer, len(*in))
+		copy(*out, *in)
+	}
+	if in.Effective != nil {
+		in, out := &in.Effective, &out.Effective
+		*out = new(string)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeStatus.
@@ -177,6 +207,22 @@ func (in *NodeStatus) DeepCopy() *NodeStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutConfig) DeepCopyInto(out *RolloutConfig) {
+	*out = *in
+	out.MaxUnavailable = in.MaxUnavailable
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutConfig.
+func (in *RolloutConfig) DeepCopy() *RolloutConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TemplatePolicy) DeepCopyInto(out *TemplatePolicy) {
 	*out = *in
@@ -243,7 +289,7 @@ func (in *TemplatePolicySpec) DeepCopyInto(out *TemplatePolicySpec) {
 	in.Control.DeepCopyInto(&out.Control)
 	in.Log.DeepCopyInto(&out.Log)
 	in.Instrumentation.DeepCopyInto(&out.Instrumentation)
-	out.Agent = in.Agent
+	in.Agent.DeepCopyInto(&out.Agent)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplatePolicySpec.
@@ -322,7 +368,7 @@ func (in *TopologyAwarePolicySpec) DeepCopyInto(out *TopologyAwarePolicySpec) {
 	in.Control.DeepCopyInto(&out.Control)
 	in.Log.DeepCopyInto(&out.Log)
 	in.Instrumentation.DeepCopyInto(&out.Instrumentation)
-	out.Agent = in.Agent
+	in.Agent.DeepCopyInto(&out.Agent)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologyAwarePolicySpec.
@@ -344,9 +344,11 @@ func (dpm defaultPlatform) readFromFile(filename string) (string, error) {
 	return string(content), err
 }
 
-// writeToFile writes content to an existing file.
+// writeToFile writes content to an existing file. The file is truncated,
+// which makes no difference for cgroupfs but lets a regular file reflect
+// the last write.
 func (dpm defaultPlatform) writeToFile(filename string, content string) error {
-	f, err := os.OpenFile(filename, os.O_WRONLY, 0666)
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_TRUNC, 0666)
 	if err != nil {
 		return err
 	}
@@ -142,6 +142,11 @@ func (g Group) GetProcesses() ([]string, error) {
 	return g.readPids(Procs)
 }
 
+// GetThreads reads the pids of threads currently in a cgroup v2 group.
+func (g Group) GetThreads() ([]string, error) {
+	return g.readPids(Threads)
+}
+
 // AddTasks writes the given thread pids to the group.
 func (g Group) AddTasks(pids ...string) error {
 	return g.writePids(Tasks, pids...)
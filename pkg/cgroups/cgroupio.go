@@ -0,0 +1,179 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgroups
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// cgroup v2 io parameter filenames.
+var ioWeightFiles = []string{"io.bfq.weight", "io.weight"}
+var ioBfqWeightFiles = []string{"io.bfq.weight"}
+var ioCoreWeightFiles = []string{"io.weight"}
+var ioMaxFiles = []string{"io.max"}
+
+const (
+	// ioDefaultWeight is the default cgroup v2 io weight.
+	ioDefaultWeight = 100
+	// ioBfqDefaultWeight is the default cgroup v2 BFQ io weight.
+	ioBfqDefaultWeight = 100
+)
+
+// SetIOParameters writes OCI BlockIO parameters to files in a cgroup v2
+// directory. Weights are given in the OCI (cgroup v1) range [10, 1000]. They
+// are written as such to io.bfq.weight, which accepts [1, 1000], or if BFQ is
+// not available, converted to the io.weight range [1, 10000]. A zero weight
+// resets the default weight and a zero rate removes the limit, like with
+// cgroup v1.
+func SetIOParameters(cgroupsDir string, blockIO OciBlockIOParameters) error {
+	log.Debug("configuring cgroups io controller in directory %#v with parameters %+v", cgroupsDir, blockIO)
+	errs := []error{}
+	if blockIO.Weight >= 0 {
+		errs = append(errs, writeIOWeight(cgroupsDir, "default", blockIO.Weight))
+	}
+	for _, weightDevice := range blockIO.WeightDevice {
+		dev := fmt.Sprintf("%d:%d", weightDevice.Major, weightDevice.Minor)
+		errs = append(errs, writeIOWeight(cgroupsDir, dev, weightDevice.Weight))
+	}
+
+	limits := map[devMajMin][]string{}
+	devices := []devMajMin{}
+	for _, throttle := range []struct {
+		key   string
+		rates OciDeviceRates
+	}{
+		{"rbps", blockIO.ThrottleReadBpsDevice},
+		{"wbps", blockIO.ThrottleWriteBpsDevice},
+		{"riops", blockIO.ThrottleReadIOPSDevice},
+		{"wiops", blockIO.ThrottleWriteIOPSDevice},
+	} {
+		for _, rateDevice := range throttle.rates {
+			dev := devMajMin{rateDevice.Major, rateDevice.Minor}
+			if _, ok := limits[dev]; !ok {
+				devices = append(devices, dev)
+			}
+			rate := "max"
+			if rateDevice.Rate > 0 {
+				rate = strconv.FormatInt(rateDevice.Rate, 10)
+			}
+			limits[dev] = append(limits[dev], throttle.key+"="+rate)
+		}
+	}
+	for _, dev := range devices {
+		content := fmt.Sprintf("%d:%d %s", dev.Major, dev.Minor, strings.Join(limits[dev], " "))
+		errs = append(errs, writeToFileInDir(cgroupsDir, ioMaxFiles, content))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ResetIOParameters adds new, changes existing and removes missing blockIO
+// parameters in a cgroup v2 directory.
+func ResetIOParameters(cgroupsDir string, blockIO OciBlockIOParameters) error {
+	errs := []error{}
+	newBlockIO := NewOciBlockIOParameters()
+	newBlockIO.Weight = blockIO.Weight
+
+	weightDevs, err := readIODevices(cgroupsDir, ioWeightFiles)
+	errs = append(errs, err)
+	seenDev := map[devMajMin]bool{}
+	for _, ociWDP := range blockIO.WeightDevice {
+		seenDev[devMajMin{ociWDP.Major, ociWDP.Minor}] = true
+		newBlockIO.WeightDevice = append(newBlockIO.WeightDevice, ociWDP)
+	}
+	for _, dev := range weightDevs {
+		if !seenDev[dev] {
+			newBlockIO.WeightDevice = append(newBlockIO.WeightDevice, OciDeviceWeight{dev.Major, dev.Minor, 0})
+		}
+	}
+
+	maxDevs, err := readIODevices(cgroupsDir, ioMaxFiles)
+	errs = append(errs, err)
+	old := OciDeviceRates{}
+	for _, dev := range maxDevs {
+		old.Append(dev.Major, dev.Minor, 0)
+	}
+	newBlockIO.ThrottleReadBpsDevice = resetDevRates(old, blockIO.ThrottleReadBpsDevice)
+	newBlockIO.ThrottleWriteBpsDevice = resetDevRates(old, blockIO.ThrottleWriteBpsDevice)
+	newBlockIO.ThrottleReadIOPSDevice = resetDevRates(old, blockIO.ThrottleReadIOPSDevice)
+	newBlockIO.ThrottleWriteIOPSDevice = resetDevRates(old, blockIO.ThrottleWriteIOPSDevice)
+
+	errs = append(errs, SetIOParameters(cgroupsDir, newBlockIO))
+	return errors.Join(errs...)
+}
+
+// writeIOWeight writes an OCI weight for a device (or "default") to
+// io.bfq.weight, falling back to a converted weight in io.weight.
+func writeIOWeight(cgroupsDir, dev string, weight int64) error {
+	bfqErr := writeToFileInDir(cgroupsDir, ioBfqWeightFiles, dev+" "+ioBfqWeight(weight))
+	if bfqErr == nil {
+		return nil
+	}
+	err := writeToFileInDir(cgroupsDir, ioCoreWeightFiles, dev+" "+ioWeight(weight))
+	if err == nil {
+		return nil
+	}
+	return errors.Join(bfqErr, err)
+}
+
+// ioBfqWeight converts an OCI weight to a cgroup v2 BFQ io weight.
+func ioBfqWeight(weight int64) string {
+	if weight <= 0 {
+		return strconv.Itoa(ioBfqDefaultWeight)
+	}
+	return strconv.FormatInt(weight, 10)
+}
+
+// ioWeight converts an OCI weight to a cgroup v2 io weight.
+func ioWeight(weight int64) string {
+	if weight <= 0 {
+		return strconv.Itoa(ioDefaultWeight)
+	}
+	return strconv.FormatInt(1+(weight-10)*9999/990, 10)
+}
+
+// readIODevices reads the devices with parameters set in a cgroup v2 io
+// weight or io.max file. Missing files are not considered an error.
+func readIODevices(baseDir string, filenames []string) ([]devMajMin, error) {
+	errs := []error{}
+	contents, err := readFromFileInDir(baseDir, filenames)
+	if err != nil {
+		return nil, nil
+	}
+	devices := []devMajMin{}
+	for _, line := range strings.Split(contents, "\n") {
+		if line == "" || strings.HasPrefix(line, "default ") {
+			continue
+		}
+		// Expect syntax MAJOR:MINOR ...
+		majMin, _, _ := strings.Cut(line, " ")
+		major, minor, ok := strings.Cut(majMin, ":")
+		if !ok {
+			errs = append(errs, fmt.Errorf("invalid line %q, single colon expected before space", line))
+			continue
+		}
+		maj, majErr := strconv.ParseInt(major, 10, 64)
+		min, minErr := strconv.ParseInt(minor, 10, 64)
+		if majErr != nil || minErr != nil {
+			errs = append(errs, fmt.Errorf("invalid device number in line %q", line))
+			continue
+		}
+		devices = append(devices, devMajMin{maj, min})
+	}
+	return devices, errors.Join(errs...)
+}
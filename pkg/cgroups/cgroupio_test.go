@@ -0,0 +1,175 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgroups
+
+import (
+	"testing"
+
+	"github.com/containers/nri-plugins/pkg/testutils"
+)
+
+func TestSetIOParameters(t *testing.T) {
+	tcases := []struct {
+		name                    string
+		cgroupsDir              string
+		blockIO                 OciBlockIOParameters
+		writesFail              int
+		expectedFsWrites        map[string]string
+		expectedErrorCount      int
+		expectedErrorSubstrings []string
+	}{
+		{
+			name:       "write full OCI struct",
+			cgroupsDir: "/my/full",
+			blockIO: OciBlockIOParameters{
+				Weight:                  10,
+				WeightDevice:            OciDeviceWeights{{Major: 1, Minor: 2, Weight: 1000}},
+				ThrottleReadBpsDevice:   OciDeviceRates{{Major: 11, Minor: 12, Rate: 13}},
+				ThrottleWriteBpsDevice:  OciDeviceRates{{Major: 11, Minor: 12, Rate: 23}},
+				ThrottleReadIOPSDevice:  OciDeviceRates{{Major: 31, Minor: 32, Rate: 33}},
+				ThrottleWriteIOPSDevice: OciDeviceRates{{Major: 11, Minor: 12, Rate: 0}},
+			},
+			expectedFsWrites: map[string]string{
+				"/my/full/io.bfq.weight": "default 10+1:2 1000",
+				"/my/full/io.max":        "11:12 rbps=13 wbps=23 wiops=max+31:32 riops=33",
+			},
+		},
+		{
+			name:       "write empty struct",
+			cgroupsDir: "/my/empty",
+			blockIO:    OciBlockIOParameters{},
+			expectedFsWrites: map[string]string{
+				"/my/empty/io.bfq.weight": "default 100",
+			},
+		},
+		{
+			name:             "no bfq.weight",
+			cgroupsDir:       "/my/nobfq",
+			blockIO:          OciBlockIOParameters{Weight: 500},
+			writesFail:       1,
+			expectedFsWrites: map[string]string{"/my/nobfq/io.weight": "default 4950"},
+		},
+		{
+			name:       "no bfq.weight for devices",
+			cgroupsDir: "/my/nobfqdev",
+			blockIO: OciBlockIOParameters{
+				Weight:       -1,
+				WeightDevice: OciDeviceWeights{{Major: 1, Minor: 2, Weight: 1000}},
+			},
+			writesFail:       1,
+			expectedFsWrites: map[string]string{"/my/nobfqdev/io.weight": "1:2 10000"},
+		},
+		{
+			name:       "all writes fail",
+			cgroupsDir: "/my/writesfail",
+			blockIO: OciBlockIOParameters{
+				Weight:                -1,
+				ThrottleReadBpsDevice: OciDeviceRates{{1, 0, 100}},
+			},
+			writesFail:         9999,
+			expectedErrorCount: 1,
+			expectedErrorSubstrings: []string{
+				"could not write content \"1:0 rbps=100\" to any of files",
+				"\"io.max\"",
+			},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			mpf := mockPlatform{
+				fsWrites:   make(map[string]string),
+				writesFail: tc.writesFail,
+			}
+			currentPlatform = &mpf
+			err := SetIOParameters(tc.cgroupsDir, tc.blockIO)
+			testutils.VerifyError(t, err, tc.expectedErrorCount, tc.expectedErrorSubstrings)
+			if tc.expectedFsWrites != nil {
+				testutils.VerifyDeepEqual(t, "filesystem writes", tc.expectedFsWrites, mpf.fsWrites)
+			}
+		})
+	}
+}
+
+func TestResetIOParameters(t *testing.T) {
+	tcases := []struct {
+		name                    string
+		cgroupsDir              string
+		fsContent               map[string]string
+		blockIO                 OciBlockIOParameters
+		expectedFsWrites        map[string]string
+		expectedErrorCount      int
+		expectedErrorSubstrings []string
+	}{
+		{
+			name:       "reset devices missing from parameters",
+			cgroupsDir: "/my/reset",
+			fsContent: map[string]string{
+				"/my/reset/io.bfq.weight": "default 100\n1:2 500\n3:4 200\n",
+				"/my/reset/io.max":        "5:6 rbps=100 wbps=max riops=max wiops=max\n7:8 rbps=max wbps=max riops=10 wiops=max\n",
+			},
+			blockIO: OciBlockIOParameters{
+				Weight:                 -1,
+				WeightDevice:           OciDeviceWeights{{Major: 1, Minor: 2, Weight: 10}},
+				ThrottleReadBpsDevice:  OciDeviceRates{{Major: 5, Minor: 6, Rate: 200}},
+				ThrottleReadIOPSDevice: OciDeviceRates{{Major: 9, Minor: 10, Rate: 20}},
+			},
+			expectedFsWrites: map[string]string{
+				"/my/reset/io.bfq.weight": "1:2 10+3:4 100",
+				"/my/reset/io.max": "5:6 rbps=200 wbps=max riops=max wiops=max+" +
+					"7:8 rbps=max wbps=max riops=max wiops=max+" +
+					"9:10 riops=20",
+			},
+		},
+		{
+			name:       "missing files",
+			cgroupsDir: "/my/missing",
+			blockIO: OciBlockIOParameters{
+				Weight:                 100,
+				ThrottleWriteBpsDevice: OciDeviceRates{{Major: 1, Minor: 2, Rate: 3}},
+			},
+			expectedFsWrites: map[string]string{
+				"/my/missing/io.bfq.weight": "default 100",
+				"/my/missing/io.max":        "1:2 wbps=3",
+			},
+		},
+		{
+			name:       "invalid device",
+			cgroupsDir: "/my/invalid",
+			fsContent: map[string]string{
+				"/my/invalid/io.max": "5-6 rbps=100\n",
+			},
+			blockIO:            NewOciBlockIOParameters(),
+			expectedFsWrites:   map[string]string{},
+			expectedErrorCount: 1,
+			expectedErrorSubstrings: []string{
+				"invalid line \"5-6 rbps=100\"",
+			},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			mpf := mockPlatform{
+				fsOrigContent: tc.fsContent,
+				fsWrites:      make(map[string]string),
+			}
+			currentPlatform = &mpf
+			err := ResetIOParameters(tc.cgroupsDir, tc.blockIO)
+			testutils.VerifyError(t, err, tc.expectedErrorCount, tc.expectedErrorSubstrings)
+			testutils.VerifyDeepEqual(t, "filesystem writes", tc.expectedFsWrites, mpf.fsWrites)
+		})
+	}
+}
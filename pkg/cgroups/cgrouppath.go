@@ -16,6 +16,7 @@ package cgroups
 
 import (
 	"flag"
+	"os"
 	"path"
 	"path/filepath"
 )
@@ -26,6 +27,8 @@ const (
 	Tasks = "tasks"
 	// Procs is cgroup's "cgroup.procs" entry.
 	Procs = "cgroup.procs"
+	// Threads is a cgroup v2 "cgroup.threads" entry.
+	Threads = "cgroup.threads"
 	// CpuShares is the cpu controller's "cpu.shares" entry.
 	CpuShares = "cpu.shares"
 	// CpuPeriod is the cpu controller's "cpu.cfs_period_us" entry.
@@ -75,6 +78,18 @@ func SetV2Dir(dir string) {
 	}
 }
 
+// FindV2Dir returns the absolute path of the given cgroup in the
+// unified cgroup v2 hierarchy, or an empty string if it is not found.
+func FindV2Dir(group string) string {
+	for _, root := range []string{mountDir, v2Dir} {
+		dir := path.Join(root, group)
+		if _, err := os.Stat(path.Join(dir, "cgroup.controllers")); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
 func init() {
 	flag.StringVar(&mountDir, "cgroup-mount", mountDir,
 		"directory under which cgroup v1 controllers are mounted")
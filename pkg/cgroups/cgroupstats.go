@@ -38,6 +38,13 @@ type BlkioThrottleBytes struct {
 	TotalBytes  int64
 }
 
+// IODeviceStat has a parsed device line of a cgroup v2 io.stat file.
+type IODeviceStat struct {
+	Major int
+	Minor int
+	Stats map[string]int64
+}
+
 // CPUAcctUsage has a parsed line of cpuacct.usage_all file
 type CPUAcctUsage struct {
 	CPU    int
@@ -45,6 +52,30 @@ type CPUAcctUsage struct {
 	System int64
 }
 
+// CPUStat has parsed contents of a cgroup v2 cpu.stat file.
+type CPUStat struct {
+	UsageUsec     int64
+	UserUsec      int64
+	SystemUsec    int64
+	NrPeriods     int64
+	NrThrottled   int64
+	ThrottledUsec int64
+}
+
+// PressureLine represents one line in a pressure stall information file.
+type PressureLine struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  int64
+}
+
+// Pressure has parsed contents of a pressure stall information file.
+type Pressure struct {
+	Some PressureLine
+	Full PressureLine
+}
+
 // HugetlbUsage has parsed contents of huge pages usage in bytes.
 type HugetlbUsage struct {
 	Size     string
@@ -265,6 +296,174 @@ func GetCPUAcctStats(cgroupPath string) ([]CPUAcctUsage, error) {
 	return result, nil
 }
 
+// GetCPUStat retrieves cgroup v2 CPU usage and throttling statistics.
+func GetCPUStat(cgroupPath string) (CPUStat, error) {
+
+	// File looks like this:
+	//
+	// usage_usec 1326540
+	// user_usec 790834
+	// system_usec 535706
+	// nr_periods 20
+	// nr_throttled 3
+	// throttled_usec 41525
+
+	lines, err := readCgroupFileLines(path.Join(cgroupPath, "cpu.stat"))
+	if err != nil {
+		return CPUStat{}, err
+	}
+
+	result := CPUStat{}
+	fields := map[string]*int64{
+		"usage_usec":     &result.UsageUsec,
+		"user_usec":      &result.UserUsec,
+		"system_usec":    &result.SystemUsec,
+		"nr_periods":     &result.NrPeriods,
+		"nr_throttled":   &result.NrThrottled,
+		"throttled_usec": &result.ThrottledUsec,
+	}
+
+	for _, line := range lines {
+		tokens := strings.Fields(line)
+		if len(tokens) != 2 {
+			continue
+		}
+		ptr, ok := fields[tokens[0]]
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseInt(tokens[1], 10, 64)
+		if err != nil {
+			return CPUStat{}, err
+		}
+		*ptr = value
+	}
+
+	return result, nil
+}
+
+// GetCPUPressure retrieves CPU pressure stall information for a given cgroup.
+func GetCPUPressure(cgroupPath string) (Pressure, error) {
+	return getPressure(path.Join(cgroupPath, "cpu.pressure"))
+}
+
+func getPressure(filePath string) (Pressure, error) {
+
+	// File looks like this:
+	//
+	// some avg10=0.00 avg60=0.12 avg300=0.05 total=2356120
+	// full avg10=0.00 avg60=0.00 avg300=0.00 total=1148300
+
+	lines, err := readCgroupFileLines(filePath)
+	if err != nil {
+		return Pressure{}, err
+	}
+
+	result := Pressure{}
+
+	for _, line := range lines {
+		tokens := strings.Fields(line)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		var psi *PressureLine
+		switch tokens[0] {
+		case "some":
+			psi = &result.Some
+		case "full":
+			psi = &result.Full
+		default:
+			return Pressure{}, fmt.Errorf("error parsing file %s", filePath)
+		}
+
+		for _, token := range tokens[1:] {
+			key, value, ok := strings.Cut(token, "=")
+			if !ok {
+				return Pressure{}, fmt.Errorf("error parsing file %s", filePath)
+			}
+			switch key {
+			case "avg10":
+				psi.Avg10, err = strconv.ParseFloat(value, 64)
+			case "avg60":
+				psi.Avg60, err = strconv.ParseFloat(value, 64)
+			case "avg300":
+				psi.Avg300, err = strconv.ParseFloat(value, 64)
+			case "total":
+				psi.Total, err = strconv.ParseInt(value, 10, 64)
+			}
+			if err != nil {
+				return Pressure{}, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// GetMemoryPressure retrieves memory pressure stall information for a given cgroup.
+func GetMemoryPressure(cgroupPath string) (Pressure, error) {
+	return getPressure(path.Join(cgroupPath, "memory.pressure"))
+}
+
+// GetIOPressure retrieves I/O pressure stall information for a given cgroup.
+func GetIOPressure(cgroupPath string) (Pressure, error) {
+	return getPressure(path.Join(cgroupPath, "io.pressure"))
+}
+
+// GetIOStat retrieves cgroup v2 per-device I/O statistics.
+func GetIOStat(cgroupPath string) ([]IODeviceStat, error) {
+
+	// File looks like this:
+	//
+	// 8:16 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+	// 8:0 rbytes=90430464 wbytes=299008000 rios=8950 wios=1252 dbytes=50331648 dios=3021
+
+	entry := path.Join(cgroupPath, "io.stat")
+	lines, err := readCgroupFileLines(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]IODeviceStat, 0, len(lines))
+
+	for _, line := range lines {
+		tokens := strings.Fields(line)
+		majmin := strings.Split(tokens[0], ":")
+		if len(majmin) != 2 {
+			return nil, fmt.Errorf("error parsing file %s", entry)
+		}
+		major, err := strconv.ParseInt(majmin[0], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		minor, err := strconv.ParseInt(majmin[1], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+
+		dev := IODeviceStat{
+			Major: int(major),
+			Minor: int(minor),
+			Stats: make(map[string]int64),
+		}
+		for _, token := range tokens[1:] {
+			key, value, ok := strings.Cut(token, "=")
+			if !ok {
+				return nil, fmt.Errorf("error parsing file %s", entry)
+			}
+			number, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			dev.Stats[key] = number
+		}
+		result = append(result, dev)
+	}
+
+	return result, nil
+}
+
 // GetCPUSetMemoryMigrate returns boolean indicating whether memory migration is enabled.
 func GetCPUSetMemoryMigrate(cgroupPath string) (bool, error) {
 
@@ -287,6 +486,41 @@ func GetCPUSetMemoryMigrate(cgroupPath string) (bool, error) {
 	return false, fmt.Errorf("error parsing file")
 }
 
+// GetCpuset returns the CPUs and memory nodes of a cgroup. With cgroup v2,
+// the effective CPUs or memory nodes are returned for the ones not set.
+func GetCpuset(cgroupPath string) (string, string, error) {
+
+	// Files look like this:
+	//
+	// 0-3,8-11
+
+	read := func(entry string) (string, error) {
+		data, err := os.ReadFile(path.Join(cgroupPath, entry))
+		if err != nil {
+			return "", err
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "" {
+			data, err = os.ReadFile(path.Join(cgroupPath, entry+".effective"))
+			if err == nil {
+				value = strings.TrimSpace(string(data))
+			}
+		}
+		return value, nil
+	}
+
+	cpus, err := read(CpusetCpus)
+	if err != nil {
+		return "", "", err
+	}
+	mems, err := read(CpusetMems)
+	if err != nil {
+		return "", "", err
+	}
+
+	return cpus, mems, nil
+}
+
 // GetHugetlbUsage retrieves huge pages statistics for a given cgroup.
 func GetHugetlbUsage(cgroupPath string) ([]HugetlbUsage, error) {
 	const (
@@ -330,6 +564,114 @@ func GetHugetlbUsage(cgroupPath string) ([]HugetlbUsage, error) {
 	return result, nil
 }
 
+// GetHugetlbCurrent retrieves cgroup v2 huge pages usage for a given cgroup.
+// Cgroup v2 does not track maximum usage, so MaxBytes is always zero.
+func GetHugetlbCurrent(cgroupPath string) ([]HugetlbUsage, error) {
+	const (
+		prefix        = "/hugetlb."
+		currentSuffix = ".current"
+	)
+
+	// Files look like this:
+	//
+	// 2097152
+
+	currentFiles, err := filepath.Glob(path.Join(cgroupPath, prefix+"*"+currentSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]HugetlbUsage, 0, len(currentFiles))
+
+	for _, file := range currentFiles {
+		if strings.Contains(filepath.Base(file), ".rsvd") {
+			// Skip reservations files.
+			continue
+		}
+		size := strings.SplitN(filepath.Base(file), ".", 3)[1]
+		bytes, err := readCgroupSingleNumber(file)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, HugetlbUsage{
+			Size:  size,
+			Bytes: bytes,
+		})
+	}
+
+	return result, nil
+}
+
+// GetMemoryStat retrieves cgroup v2 memory statistics.
+func GetMemoryStat(cgroupPath string) (map[string]int64, error) {
+
+	// File looks like this:
+	//
+	// anon 2363392
+	// file 8192000
+	// kernel 1605632
+	// ...
+
+	lines, err := readCgroupFileLines(path.Join(cgroupPath, "memory.stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]int64, len(lines))
+
+	for _, line := range lines {
+		tokens := strings.Fields(line)
+		if len(tokens) != 2 {
+			continue
+		}
+		value, err := strconv.ParseInt(tokens[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		result[tokens[0]] = value
+	}
+
+	return result, nil
+}
+
+// GetMemoryNumaStat retrieves cgroup v2 per NUMA node memory statistics,
+// in bytes per node (N0, N1, ...) per statistic.
+func GetMemoryNumaStat(cgroupPath string) (map[string]map[string]int64, error) {
+
+	// File looks like this:
+	//
+	// anon N0=1994752 N1=368640
+	// file N0=6684672 N1=1507328
+	// ...
+
+	entry := path.Join(cgroupPath, "memory.numa_stat")
+	lines, err := readCgroupFileLines(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]map[string]int64, len(lines))
+
+	for _, line := range lines {
+		tokens := strings.Fields(line)
+		nodes := make(map[string]int64, len(tokens)-1)
+		for _, token := range tokens[1:] {
+			node, amount, ok := strings.Cut(token, "=")
+			if !ok {
+				return nil, fmt.Errorf("error parsing file %s", entry)
+			}
+			number, err := strconv.ParseInt(amount, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing file %s: %v", entry, err)
+			}
+			nodes[node] = number
+		}
+		result[tokens[0]] = nodes
+	}
+
+	return result, nil
+}
+
 // GetMemoryUsage retrieves cgroup memory usage.
 func GetMemoryUsage(cgroupPath string) (MemoryUsage, error) {
 
@@ -0,0 +1,195 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgroups
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetCPUStat(t *testing.T) {
+	dir := t.TempDir()
+	data := "usage_usec 1326540\nuser_usec 790834\nsystem_usec 535706\n" +
+		"core_sched.force_idle_usec 0\nnr_periods 20\nnr_throttled 3\nthrottled_usec 41525\n"
+	if err := os.WriteFile(filepath.Join(dir, "cpu.stat"), []byte(data), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	stat, err := GetCPUStat(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := CPUStat{
+		UsageUsec:     1326540,
+		UserUsec:      790834,
+		SystemUsec:    535706,
+		NrPeriods:     20,
+		NrThrottled:   3,
+		ThrottledUsec: 41525,
+	}
+	if stat != expected {
+		t.Errorf("expected %+v, got %+v", expected, stat)
+	}
+
+	if _, err := GetCPUStat(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("expected an error for a missing cgroup")
+	}
+}
+
+func TestGetCPUPressure(t *testing.T) {
+	dir := t.TempDir()
+	data := "some avg10=1.50 avg60=0.12 avg300=0.05 total=2356120\n" +
+		"full avg10=0.00 avg60=0.00 avg300=0.00 total=1148300\n"
+	if err := os.WriteFile(filepath.Join(dir, "cpu.pressure"), []byte(data), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	psi, err := GetCPUPressure(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := Pressure{
+		Some: PressureLine{Avg10: 1.50, Avg60: 0.12, Avg300: 0.05, Total: 2356120},
+		Full: PressureLine{Total: 1148300},
+	}
+	if psi != expected {
+		t.Errorf("expected %+v, got %+v", expected, psi)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "cpu.pressure"), []byte("bogus avg10=0.00\n"), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	if _, err := GetCPUPressure(dir); err == nil {
+		t.Errorf("expected an error for malformed pressure data")
+	}
+}
+
+func writeStatFiles(t *testing.T, dir string, files map[string]string) {
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+	}
+}
+
+func TestGetMemoryStat(t *testing.T) {
+	dir := t.TempDir()
+	writeStatFiles(t, dir, map[string]string{
+		"memory.stat": "anon 2363392\nfile 8192000\nkernel 1605632\n",
+		"memory.numa_stat": "anon N0=1994752 N1=368640\n" +
+			"file N0=6684672 N1=1507328\n",
+	})
+
+	stat, err := GetMemoryStat(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]int64{"anon": 2363392, "file": 8192000, "kernel": 1605632}
+	if !reflect.DeepEqual(stat, expected) {
+		t.Errorf("expected %+v, got %+v", expected, stat)
+	}
+
+	numa, err := GetMemoryNumaStat(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedNuma := map[string]map[string]int64{
+		"anon": {"N0": 1994752, "N1": 368640},
+		"file": {"N0": 6684672, "N1": 1507328},
+	}
+	if !reflect.DeepEqual(numa, expectedNuma) {
+		t.Errorf("expected %+v, got %+v", expectedNuma, numa)
+	}
+
+	writeStatFiles(t, dir, map[string]string{"memory.numa_stat": "anon N0\n"})
+	if _, err := GetMemoryNumaStat(dir); err == nil {
+		t.Errorf("expected an error for malformed NUMA stats")
+	}
+}
+
+func TestGetIOStat(t *testing.T) {
+	dir := t.TempDir()
+	writeStatFiles(t, dir, map[string]string{
+		"io.stat": "8:16 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0\n" +
+			"259:0 rbytes=90430464 wbytes=299008000 rios=8950 wios=1252\n",
+	})
+
+	stat, err := GetIOStat(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []IODeviceStat{
+		{
+			Major: 8, Minor: 16,
+			Stats: map[string]int64{"rbytes": 1459200, "wbytes": 314773504, "rios": 192, "wios": 353, "dbytes": 0, "dios": 0},
+		},
+		{
+			Major: 259, Minor: 0,
+			Stats: map[string]int64{"rbytes": 90430464, "wbytes": 299008000, "rios": 8950, "wios": 1252},
+		},
+	}
+	if !reflect.DeepEqual(stat, expected) {
+		t.Errorf("expected %+v, got %+v", expected, stat)
+	}
+
+	writeStatFiles(t, dir, map[string]string{"io.stat": "8 rbytes=0\n"})
+	if _, err := GetIOStat(dir); err == nil {
+		t.Errorf("expected an error for malformed I/O stats")
+	}
+}
+
+func TestGetHugetlbCurrent(t *testing.T) {
+	dir := t.TempDir()
+	writeStatFiles(t, dir, map[string]string{
+		"hugetlb.2MB.current":      "4194304\n",
+		"hugetlb.2MB.rsvd.current": "2097152\n",
+		"hugetlb.1GB.current":      "0\n",
+	})
+
+	usage, err := GetHugetlbCurrent(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []HugetlbUsage{
+		{Size: "1GB", Bytes: 0},
+		{Size: "2MB", Bytes: 4194304},
+	}
+	if !reflect.DeepEqual(usage, expected) {
+		t.Errorf("expected %+v, got %+v", expected, usage)
+	}
+}
+
+func TestGetCpuset(t *testing.T) {
+	dir := t.TempDir()
+	writeStatFiles(t, dir, map[string]string{
+		"cpuset.cpus":           "0-3\n",
+		"cpuset.mems":           "\n",
+		"cpuset.mems.effective": "0-1\n",
+	})
+
+	cpus, mems, err := GetCpuset(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cpus != "0-3" || mems != "0-1" {
+		t.Errorf("expected cpus 0-3, mems 0-1, got cpus %s, mems %s", cpus, mems)
+	}
+
+	if _, _, err := GetCpuset(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("expected an error for missing cgroup")
+	}
+}
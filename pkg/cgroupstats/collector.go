@@ -18,13 +18,13 @@ import (
 	"flag"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strconv"
-	"strings"
 	"sync"
 
 	"github.com/containers/nri-plugins/pkg/cgroups"
 	logger "github.com/containers/nri-plugins/pkg/log"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -36,65 +36,127 @@ const (
 	cpuAcctUsageDesc
 	hugeTlbUsageDesc
 	blkioDeviceUsageDesc
+	memoryStatDesc
+	cpuStatDesc
+	ioStatDesc
+	pressureAvgDesc
+	pressureTotalDesc
 	numDescriptors
 )
 
+// containerLabels are the labels identifying the container of a metric.
+var containerLabels = []string{
+	"container_id",
+	"namespace",
+	"pod",
+	"container",
+}
+
+// WithContainerLabels returns the container labels followed by the given ones.
+func WithContainerLabels(labels ...string) []string {
+	return append(slices.Clone(containerLabels), labels...)
+}
+
+// ContainerLabelValues returns the container label values for a container.
+func ContainerLabelValues(ctr cache.Container) []string {
+	pod := ""
+	if p, ok := ctr.GetPod(); ok {
+		pod = p.GetName()
+	}
+	return []string{ctr.GetID(), ctr.GetNamespace(), pod, ctr.GetName()}
+}
+
 var descriptors = [numDescriptors]*prometheus.Desc{
 	numaStatsDesc: prometheus.NewDesc(
 		"numa_stats",
 		"NUMA statistics for a given container and pod.",
-		[]string{
-			// cgroup path
-			"container_id",
+		WithContainerLabels(
 			// NUMA node ID
 			"numa_node_id",
 			// NUMA memory type
 			"type",
-		}, nil,
+		), nil,
 	),
 	memoryUsageDesc: prometheus.NewDesc(
 		"memory_usage",
 		"Memory usage statistics for a given container and pod.",
-		[]string{
-			"container_id",
+		WithContainerLabels(
 			"type",
-		}, nil,
+		), nil,
 	),
 	memoryMigrateDesc: prometheus.NewDesc(
 		"memory_migrate",
 		"Memory migrate status for a given container and pod.",
-		[]string{
-			"container_id",
-		}, nil,
+		WithContainerLabels(), nil,
 	),
 	cpuAcctUsageDesc: prometheus.NewDesc(
 		"cpu_acct",
 		"CPU accounting for a given container and pod.",
-		[]string{
-			"container_id",
+		WithContainerLabels(
 			// CPU ID
 			"cpu",
 			"type",
-		}, nil,
+		), nil,
 	),
 	hugeTlbUsageDesc: prometheus.NewDesc(
 		"hugetlb_usage",
 		"Hugepages usage for a given container and pod.",
-		[]string{
-			"container_id",
+		WithContainerLabels(
 			"size",
 			"type",
-		}, nil,
+		), nil,
 	),
 	blkioDeviceUsageDesc: prometheus.NewDesc(
 		"blkio_device_usage",
 		"Blkio Device bytes usage for a given container and pod.",
-		[]string{
-			"container_id",
+		WithContainerLabels(
 			"major",
 			"minor",
 			"operation",
-		}, nil,
+		), nil,
+	),
+	memoryStatDesc: prometheus.NewDesc(
+		"memory_stat",
+		"Memory statistics (cgroup v2 memory.stat) for a given container and pod.",
+		WithContainerLabels(
+			"type",
+		), nil,
+	),
+	cpuStatDesc: prometheus.NewDesc(
+		"cpu_stat",
+		"CPU usage and throttling (cgroup v2 cpu.stat) for a given container and pod.",
+		WithContainerLabels(
+			"type",
+		), nil,
+	),
+	ioStatDesc: prometheus.NewDesc(
+		"io_stat",
+		"Block device I/O (cgroup v2 io.stat) for a given container and pod.",
+		WithContainerLabels(
+			"major",
+			"minor",
+			"type",
+		), nil,
+	),
+	pressureAvgDesc: prometheus.NewDesc(
+		"pressure_avg",
+		"Average percentage of time stalled on a resource for a given container and pod.",
+		WithContainerLabels(
+			// cpu, memory, or io
+			"resource",
+			// some or full
+			"kind",
+			// averaging window: 10s, 60s, or 300s
+			"window",
+		), nil,
+	),
+	pressureTotalDesc: prometheus.NewDesc(
+		"pressure_total_usec",
+		"Total time stalled on a resource for a given container and pod.",
+		WithContainerLabels(
+			"resource",
+			"kind",
+		), nil,
 	),
 }
 
@@ -105,16 +167,18 @@ var (
 	log = logger.NewLogger("cgroupstats")
 )
 
-const (
-	kubepodsDir = "kubepods.slice"
-)
-
 type collector struct {
+	cache cache.Cache
+	lock  sync.Locker
 }
 
-// NewCollector creates new Prometheus collector
-func NewCollector() prometheus.Collector {
-	return &collector{}
+// NewCollector creates new Prometheus collector for the containers in the cache.
+// The given lock is held while the containers are looked up from the cache.
+func NewCollector(cache cache.Cache, lock sync.Locker) prometheus.Collector {
+	return &collector{
+		cache: cache,
+		lock:  lock,
+	}
 }
 
 // Describe implements prometheus.Collector interface
@@ -124,30 +188,30 @@ func (c *collector) Describe(ch chan<- *prometheus.Desc) {
 	}
 }
 
-func updateCPUAcctUsageMetric(ch chan<- prometheus.Metric, path string, metric []cgroups.CPUAcctUsage) {
+func updateCPUAcctUsageMetric(ch chan<- prometheus.Metric, labels []string, metric []cgroups.CPUAcctUsage) {
 	for i, acct := range metric {
 		ch <- prometheus.MustNewConstMetric(
 			descriptors[cpuAcctUsageDesc],
 			prometheus.CounterValue,
 			float64(acct.CPU),
-			path, strconv.FormatInt(int64(i), 10), "CPU",
+			labelValues(labels, strconv.FormatInt(int64(i), 10), "CPU")...,
 		)
 		ch <- prometheus.MustNewConstMetric(
 			descriptors[cpuAcctUsageDesc],
 			prometheus.CounterValue,
 			float64(acct.User),
-			path, strconv.FormatInt(int64(i), 10), "User",
+			labelValues(labels, strconv.FormatInt(int64(i), 10), "User")...,
 		)
 		ch <- prometheus.MustNewConstMetric(
 			descriptors[cpuAcctUsageDesc],
 			prometheus.CounterValue,
 			float64(acct.System),
-			path, strconv.FormatInt(int64(i), 10), "System",
+			labelValues(labels, strconv.FormatInt(int64(i), 10), "System")...,
 		)
 	}
 }
 
-func updateMemoryMigrateMetric(ch chan<- prometheus.Metric, path string, migrate bool) {
+func updateMemoryMigrateMetric(ch chan<- prometheus.Metric, labels []string, migrate bool) {
 	migrateValue := 0
 	if migrate {
 		migrateValue = 1
@@ -156,26 +220,26 @@ func updateMemoryMigrateMetric(ch chan<- prometheus.Metric, path string, migrate
 		descriptors[memoryMigrateDesc],
 		prometheus.GaugeValue,
 		float64(migrateValue),
-		path,
+		labels...,
 	)
 }
 
-func updateMemoryUsageMetric(ch chan<- prometheus.Metric, path string, metric cgroups.MemoryUsage) {
+func updateMemoryUsageMetric(ch chan<- prometheus.Metric, labels []string, metric cgroups.MemoryUsage) {
 	ch <- prometheus.MustNewConstMetric(
 		descriptors[memoryUsageDesc],
 		prometheus.GaugeValue,
 		float64(metric.Bytes),
-		path, "Bytes",
+		labelValues(labels, "Bytes")...,
 	)
 	ch <- prometheus.MustNewConstMetric(
 		descriptors[memoryUsageDesc],
 		prometheus.GaugeValue,
 		float64(metric.MaxBytes),
-		path, "MaxBytes",
+		labelValues(labels, "MaxBytes")...,
 	)
 }
 
-func updateNumaStatMetric(ch chan<- prometheus.Metric, path string, metric cgroups.NumaStat) {
+func updateNumaStatMetric(ch chan<- prometheus.Metric, labels []string, metric cgroups.NumaStat) {
 	// TODO: use "reflect" to iterate through the struct fields of NumaStat?
 
 	for key, value := range metric.Total.Nodes {
@@ -183,7 +247,7 @@ func updateNumaStatMetric(ch chan<- prometheus.Metric, path string, metric cgrou
 			descriptors[numaStatsDesc],
 			prometheus.GaugeValue,
 			float64(value),
-			path, key, "Total",
+			labelValues(labels, key, "Total")...,
 		)
 	}
 	for key, value := range metric.File.Nodes {
@@ -191,7 +255,7 @@ func updateNumaStatMetric(ch chan<- prometheus.Metric, path string, metric cgrou
 			descriptors[numaStatsDesc],
 			prometheus.GaugeValue,
 			float64(value),
-			path, key, "File",
+			labelValues(labels, key, "File")...,
 		)
 	}
 	for key, value := range metric.Anon.Nodes {
@@ -199,7 +263,7 @@ func updateNumaStatMetric(ch chan<- prometheus.Metric, path string, metric cgrou
 			descriptors[numaStatsDesc],
 			prometheus.GaugeValue,
 			float64(value),
-			path, key, "Anon",
+			labelValues(labels, key, "Anon")...,
 		)
 	}
 	for key, value := range metric.Unevictable.Nodes {
@@ -207,7 +271,7 @@ func updateNumaStatMetric(ch chan<- prometheus.Metric, path string, metric cgrou
 			descriptors[numaStatsDesc],
 			prometheus.GaugeValue,
 			float64(value),
-			path, key, "Unevictable",
+			labelValues(labels, key, "Unevictable")...,
 		)
 	}
 	for key, value := range metric.HierarchicalTotal.Nodes {
@@ -215,7 +279,7 @@ func updateNumaStatMetric(ch chan<- prometheus.Metric, path string, metric cgrou
 			descriptors[numaStatsDesc],
 			prometheus.GaugeValue,
 			float64(value),
-			path, key, "HierarchicalTotal",
+			labelValues(labels, key, "HierarchicalTotal")...,
 		)
 	}
 	for key, value := range metric.HierarchicalFile.Nodes {
@@ -223,7 +287,7 @@ func updateNumaStatMetric(ch chan<- prometheus.Metric, path string, metric cgrou
 			descriptors[numaStatsDesc],
 			prometheus.GaugeValue,
 			float64(value),
-			path, key, "HierarchicalFile",
+			labelValues(labels, key, "HierarchicalFile")...,
 		)
 	}
 	for key, value := range metric.HierarchicalAnon.Nodes {
@@ -231,7 +295,7 @@ func updateNumaStatMetric(ch chan<- prometheus.Metric, path string, metric cgrou
 			descriptors[numaStatsDesc],
 			prometheus.GaugeValue,
 			float64(value),
-			path, key, "HierarchicalAnon",
+			labelValues(labels, key, "HierarchicalAnon")...,
 		)
 	}
 	for key, value := range metric.HierarchicalUnevictable.Nodes {
@@ -239,164 +303,320 @@ func updateNumaStatMetric(ch chan<- prometheus.Metric, path string, metric cgrou
 			descriptors[numaStatsDesc],
 			prometheus.GaugeValue,
 			float64(value),
-			path, key, "HierarchicalUnevictable",
+			labelValues(labels, key, "HierarchicalUnevictable")...,
 		)
 	}
 }
 
-func updateHugeTlbUsageMetric(ch chan<- prometheus.Metric, path string, metric []cgroups.HugetlbUsage) {
+func updateHugeTlbUsageMetric(ch chan<- prometheus.Metric, labels []string, metric []cgroups.HugetlbUsage) {
 	// One HugeTlbUsage for each size.
 	for _, hugeTlbUsage := range metric {
 		ch <- prometheus.MustNewConstMetric(
 			descriptors[hugeTlbUsageDesc],
 			prometheus.GaugeValue,
 			float64(hugeTlbUsage.Bytes),
-			path, hugeTlbUsage.Size, "Bytes",
+			labelValues(labels, hugeTlbUsage.Size, "Bytes")...,
 		)
 		ch <- prometheus.MustNewConstMetric(
 			descriptors[hugeTlbUsageDesc],
 			prometheus.GaugeValue,
 			float64(hugeTlbUsage.MaxBytes),
-			path, hugeTlbUsage.Size, "MaxBytes",
+			labelValues(labels, hugeTlbUsage.Size, "MaxBytes")...,
 		)
 	}
 }
 
-func updateBlkioDeviceUsageMetric(ch chan<- prometheus.Metric, path string, metric cgroups.BlkioThrottleBytes) {
+func updateBlkioDeviceUsageMetric(ch chan<- prometheus.Metric, labels []string, metric cgroups.BlkioThrottleBytes) {
 	for _, deviceBytes := range metric.DeviceBytes {
 		for operation, val := range deviceBytes.Operations {
 			ch <- prometheus.MustNewConstMetric(
 				descriptors[blkioDeviceUsageDesc],
 				prometheus.CounterValue,
 				float64(val),
-				path, strconv.FormatInt(int64(deviceBytes.Major), 10),
-				strconv.FormatInt(int64(deviceBytes.Minor), 10), operation,
+				labelValues(labels, strconv.FormatInt(int64(deviceBytes.Major), 10),
+					strconv.FormatInt(int64(deviceBytes.Minor), 10), operation)...,
 			)
 		}
 	}
 }
 
-func walkCgroups() []string {
-	// XXX TODO: add support for kubelet cgroupfs cgroup driver.
+func updateCPUStatMetric(ch chan<- prometheus.Metric, labels []string, metric cgroups.CPUStat) {
+	for typ, val := range map[string]int64{
+		"usage_usec":     metric.UsageUsec,
+		"user_usec":      metric.UserUsec,
+		"system_usec":    metric.SystemUsec,
+		"nr_periods":     metric.NrPeriods,
+		"nr_throttled":   metric.NrThrottled,
+		"throttled_usec": metric.ThrottledUsec,
+	} {
+		ch <- prometheus.MustNewConstMetric(
+			descriptors[cpuStatDesc],
+			prometheus.CounterValue,
+			float64(val),
+			labelValues(labels, typ)...,
+		)
+	}
+}
 
-	containerDirs := []string{}
+func updateMemoryStatMetric(ch chan<- prometheus.Metric, labels []string, metric map[string]int64) {
+	for typ, val := range metric {
+		ch <- prometheus.MustNewConstMetric(
+			descriptors[memoryStatDesc],
+			prometheus.GaugeValue,
+			float64(val),
+			labelValues(labels, typ)...,
+		)
+	}
+}
 
-	cpuset := filepath.Join(cgroupRoot, "cpuset")
-	err := filepath.Walk(filepath.Join(cpuset, kubepodsDir),
-		func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				if os.IsNotExist(err) {
-					return nil
-				}
-				return err
-			}
-			if !info.IsDir() {
-				return nil
-			}
+func updateMemoryNumaStatMetric(ch chan<- prometheus.Metric, labels []string, metric map[string]map[string]int64) {
+	for typ, nodes := range metric {
+		for node, val := range nodes {
+			ch <- prometheus.MustNewConstMetric(
+				descriptors[numaStatsDesc],
+				prometheus.GaugeValue,
+				float64(val),
+				labelValues(labels, node, typ)...,
+			)
+		}
+	}
+}
 
-			dir := info.Name()
-			if !strings.HasSuffix(dir, ".scope") {
-				return nil
-			}
+func updateIOStatMetric(ch chan<- prometheus.Metric, labels []string, metric []cgroups.IODeviceStat) {
+	for _, dev := range metric {
+		for typ, val := range dev.Stats {
+			ch <- prometheus.MustNewConstMetric(
+				descriptors[ioStatDesc],
+				prometheus.CounterValue,
+				float64(val),
+				labelValues(labels, strconv.Itoa(dev.Major), strconv.Itoa(dev.Minor), typ)...,
+			)
+		}
+	}
+}
 
-			switch {
-			case strings.HasPrefix(dir, "cri-containerd-"):
-				break
-			case strings.HasPrefix(dir, "crio-"):
-				break
-			case strings.HasPrefix(dir, "docker-"):
-				break
-			default:
-				return filepath.SkipDir
-			}
+func updateHugetlbCurrentMetric(ch chan<- prometheus.Metric, labels []string, metric []cgroups.HugetlbUsage) {
+	for _, hugeTlbUsage := range metric {
+		ch <- prometheus.MustNewConstMetric(
+			descriptors[hugeTlbUsageDesc],
+			prometheus.GaugeValue,
+			float64(hugeTlbUsage.Bytes),
+			labelValues(labels, hugeTlbUsage.Size, "Bytes")...,
+		)
+	}
+}
 
-			path = strings.TrimPrefix(path, cpuset+"/")
-			containerDirs = append(containerDirs, path)
+func updatePressureMetric(ch chan<- prometheus.Metric, labels []string, resource string, metric cgroups.Pressure) {
+	for kind, psi := range map[string]cgroups.PressureLine{
+		"some": metric.Some,
+		"full": metric.Full,
+	} {
+		for window, val := range map[string]float64{
+			"10s":  psi.Avg10,
+			"60s":  psi.Avg60,
+			"300s": psi.Avg300,
+		} {
+			ch <- prometheus.MustNewConstMetric(
+				descriptors[pressureAvgDesc],
+				prometheus.GaugeValue,
+				val,
+				labelValues(labels, resource, kind, window)...,
+			)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			descriptors[pressureTotalDesc],
+			prometheus.CounterValue,
+			float64(psi.Total),
+			labelValues(labels, resource, kind)...,
+		)
+	}
+}
+
+// labelValues returns the container label values followed by the given ones.
+func labelValues(labels []string, values ...string) []string {
+	return append(slices.Clip(labels), values...)
+}
+
+// containerCgroup is the cgroup of a container we collect statistics for.
+type containerCgroup struct {
+	labels []string // container label values
+	dir    string   // relative cgroup (v1) path
+	v2Dir  string   // absolute cgroup v2 path, if the container has one
+}
 
-			return nil
+// containerCgroups returns the cgroups of all running containers in the cache.
+func (c *collector) containerCgroups() []*containerCgroup {
+	result := []*containerCgroup{}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, ctr := range c.cache.GetContainers() {
+		if ctr.GetState() != cache.ContainerStateRunning {
+			continue
+		}
+		dir := ctr.GetCgroupDir()
+		if dir == "" {
+			log.Debug("no cgroup directory for %s, skipping it", ctr.PrettyName())
+			continue
+		}
+
+		result = append(result, &containerCgroup{
+			labels: ContainerLabelValues(ctr),
+			dir:    dir,
+			v2Dir:  cgroups.FindV2Dir(dir),
 		})
-	if err != nil {
-		log.Warnf("cgroupfs walk failed: %v", err)
 	}
 
-	return containerDirs
+	return result
 }
 
 func cgroupPath(controller, path string) string {
 	return filepath.Join(cgroupRoot, controller, path)
 }
 
-// Collect implements prometheus.Collector interface
-func (c collector) Collect(ch chan<- prometheus.Metric) {
-	var wg sync.WaitGroup
-
-	// We don't bail out on errors because those can happen if there is a race condition between
-	// the destruction of a container and us getting to read the cgroup data. We just don't report
-	// the values we don't get.
-
-	collectors := []func(string, *regexp.Regexp){
-		func(path string, re *regexp.Regexp) {
-			defer wg.Done()
-			numa, err := cgroups.GetNumaStats(cgroupPath("memory", path))
+// v1Collectors returns collectors for cgroup v1 statistics.
+func v1Collectors(ch chan<- prometheus.Metric) []func(*containerCgroup) {
+	return []func(*containerCgroup){
+		func(cg *containerCgroup) {
+			numa, err := cgroups.GetNumaStats(cgroupPath("memory", cg.dir))
 			if err == nil {
-				updateNumaStatMetric(ch, re.FindStringSubmatch(filepath.Base(path))[0], numa)
+				updateNumaStatMetric(ch, cg.labels, numa)
 			} else {
-				log.Error("failed to collect NUMA stats for %s: %v", path, err)
+				log.Error("failed to collect NUMA stats for %s: %v", cg.dir, err)
 			}
 		},
-		func(path string, re *regexp.Regexp) {
-			defer wg.Done()
-			memory, err := cgroups.GetMemoryUsage(cgroupPath("memory", path))
+		func(cg *containerCgroup) {
+			memory, err := cgroups.GetMemoryUsage(cgroupPath("memory", cg.dir))
 			if err == nil {
-				updateMemoryUsageMetric(ch, re.FindStringSubmatch(filepath.Base(path))[0], memory)
+				updateMemoryUsageMetric(ch, cg.labels, memory)
 			} else {
-				log.Error("failed to collect memory usage stats for %s: %v", path, err)
+				log.Error("failed to collect memory usage stats for %s: %v", cg.dir, err)
 			}
 		},
-		func(path string, re *regexp.Regexp) {
-			defer wg.Done()
-			migrate, err := cgroups.GetCPUSetMemoryMigrate(cgroupPath("cpuset", path))
+		func(cg *containerCgroup) {
+			migrate, err := cgroups.GetCPUSetMemoryMigrate(cgroupPath("cpuset", cg.dir))
 			if err == nil {
-				updateMemoryMigrateMetric(ch, re.FindStringSubmatch(filepath.Base(path))[0], migrate)
+				updateMemoryMigrateMetric(ch, cg.labels, migrate)
 			} else {
-				log.Error("failed to collect memory migration stats for %s: %v", path, err)
+				log.Error("failed to collect memory migration stats for %s: %v", cg.dir, err)
 			}
 		},
-		func(path string, re *regexp.Regexp) {
-			defer wg.Done()
-			cpuAcctUsage, err := cgroups.GetCPUAcctStats(cgroupPath("cpuacct", path))
+		func(cg *containerCgroup) {
+			cpuAcctUsage, err := cgroups.GetCPUAcctStats(cgroupPath("cpuacct", cg.dir))
 			if err == nil {
-				updateCPUAcctUsageMetric(ch, re.FindStringSubmatch(filepath.Base(path))[0], cpuAcctUsage)
+				updateCPUAcctUsageMetric(ch, cg.labels, cpuAcctUsage)
 			} else {
-				log.Error("failed to collect CPU accounting stats for %s: %v", path, err)
+				log.Error("failed to collect CPU accounting stats for %s: %v", cg.dir, err)
 			}
 		},
-		func(path string, re *regexp.Regexp) {
-			defer wg.Done()
-			hugeTlbUsage, err := cgroups.GetHugetlbUsage(cgroupPath("hugetlb", path))
+		func(cg *containerCgroup) {
+			hugeTlbUsage, err := cgroups.GetHugetlbUsage(cgroupPath("hugetlb", cg.dir))
 			if err == nil {
-				updateHugeTlbUsageMetric(ch, re.FindStringSubmatch(filepath.Base(path))[0], hugeTlbUsage)
+				updateHugeTlbUsageMetric(ch, cg.labels, hugeTlbUsage)
 			} else {
-				log.Error("failed to collect hugetlb stats for %s: %v", path, err)
+				log.Error("failed to collect hugetlb stats for %s: %v", cg.dir, err)
 			}
 		},
-		func(path string, re *regexp.Regexp) {
-			defer wg.Done()
-			blkioDeviceUsage, err := cgroups.GetBlkioThrottleBytes(cgroupPath("blkio", path))
+		func(cg *containerCgroup) {
+			blkioDeviceUsage, err := cgroups.GetBlkioThrottleBytes(cgroupPath("blkio", cg.dir))
 			if err == nil {
-				updateBlkioDeviceUsageMetric(ch, re.FindStringSubmatch(filepath.Base(path))[0], blkioDeviceUsage)
+				updateBlkioDeviceUsageMetric(ch, cg.labels, blkioDeviceUsage)
 			} else {
-				log.Error("failed to collect blkio stats for %s: %v", path, err)
+				log.Error("failed to collect blkio stats for %s: %v", cg.dir, err)
 			}
 		},
 	}
+}
 
-	containerIDRegexp := regexp.MustCompile(`[a-z0-9]{64}`)
+// v2Collectors returns collectors for cgroup v2 statistics.
+func v2Collectors(ch chan<- prometheus.Metric) []func(*containerCgroup) {
+	collectors := []func(*containerCgroup){
+		func(cg *containerCgroup) {
+			stat, err := cgroups.GetCPUStat(cg.v2Dir)
+			if err == nil {
+				updateCPUStatMetric(ch, cg.labels, stat)
+			} else {
+				log.Error("failed to collect CPU stats for %s: %v", cg.v2Dir, err)
+			}
+		},
+		func(cg *containerCgroup) {
+			stat, err := cgroups.GetMemoryStat(cg.v2Dir)
+			if err == nil {
+				updateMemoryStatMetric(ch, cg.labels, stat)
+			} else {
+				log.Error("failed to collect memory stats for %s: %v", cg.v2Dir, err)
+			}
+		},
+		func(cg *containerCgroup) {
+			numa, err := cgroups.GetMemoryNumaStat(cg.v2Dir)
+			if err == nil {
+				updateMemoryNumaStatMetric(ch, cg.labels, numa)
+			} else {
+				log.Error("failed to collect NUMA stats for %s: %v", cg.v2Dir, err)
+			}
+		},
+		func(cg *containerCgroup) {
+			stat, err := cgroups.GetIOStat(cg.v2Dir)
+			if err == nil {
+				updateIOStatMetric(ch, cg.labels, stat)
+			} else {
+				log.Error("failed to collect I/O stats for %s: %v", cg.v2Dir, err)
+			}
+		},
+		func(cg *containerCgroup) {
+			hugeTlbUsage, err := cgroups.GetHugetlbCurrent(cg.v2Dir)
+			if err == nil {
+				updateHugetlbCurrentMetric(ch, cg.labels, hugeTlbUsage)
+			} else {
+				log.Error("failed to collect hugetlb stats for %s: %v", cg.v2Dir, err)
+			}
+		},
+	}
 
-	for _, path := range walkCgroups() {
+	for resource, getPressure := range map[string]func(string) (cgroups.Pressure, error){
+		"cpu":    cgroups.GetCPUPressure,
+		"memory": cgroups.GetMemoryPressure,
+		"io":     cgroups.GetIOPressure,
+	} {
+		collectors = append(collectors, func(cg *containerCgroup) {
+			psi, err := getPressure(cg.v2Dir)
+			if err == nil {
+				updatePressureMetric(ch, cg.labels, resource, psi)
+			} else if !os.IsNotExist(err) {
+				// PSI may be disabled in the kernel, only complain about other errors.
+				log.Error("failed to collect %s pressure for %s: %v", resource, cg.v2Dir, err)
+			}
+		})
+	}
+
+	return collectors
+}
+
+// Collect implements prometheus.Collector interface
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+
+	// We don't bail out on errors because those can happen if there is a race condition between
+	// the destruction of a container and us getting to read the cgroup data. We just don't report
+	// the values we don't get.
+
+	var (
+		v1 = v1Collectors(ch)
+		v2 = v2Collectors(ch)
+	)
+
+	for _, cg := range c.containerCgroups() {
+		collectors := v1
+		if cg.v2Dir != "" {
+			collectors = v2
+		}
 		wg.Add(len(collectors))
 		for _, fn := range collectors {
-			go fn(path, containerIDRegexp)
+			go func() {
+				defer wg.Done()
+				fn(cg)
+			}()
 		}
 	}
 
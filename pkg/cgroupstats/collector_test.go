@@ -0,0 +1,152 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgroupstats
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	nri "github.com/containerd/nri/pkg/api"
+	"github.com/prometheus/client_golang/prometheus"
+	model "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/containers/nri-plugins/pkg/cgroups"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+)
+
+func TestCollectV2(t *testing.T) {
+	dir := t.TempDir()
+
+	mountDir := cgroups.GetMountDir()
+	cgroups.SetMountDir(filepath.Join(dir, "cgroup"))
+	t.Cleanup(func() { cgroups.SetMountDir(mountDir) })
+
+	cgroupDir := filepath.Join(dir, "cgroup", "kubepods/burstable/pod0/ctr0")
+	require.NoError(t, os.MkdirAll(cgroupDir, 0755))
+	for name, data := range map[string]string{
+		"cgroup.controllers":  "cpuset cpu io memory hugetlb\n",
+		"cpu.stat":            "usage_usec 1000\nuser_usec 600\nsystem_usec 400\n",
+		"memory.stat":         "anon 4096\nfile 8192\n",
+		"memory.numa_stat":    "anon N0=4096 N1=0\n",
+		"io.stat":             "8:0 rbytes=512 wbytes=1024 rios=1 wios=2\n",
+		"hugetlb.2MB.current": "2097152\n",
+		"cpu.pressure":        "some avg10=1.50 avg60=0.00 avg300=0.00 total=100\n",
+		"memory.pressure": "some avg10=0.00 avg60=0.00 avg300=0.00 total=200\n" +
+			"full avg10=0.00 avg60=0.00 avg300=0.00 total=300\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(cgroupDir, name), []byte(data), 0644))
+	}
+
+	cch, err := cache.NewCache(cache.Options{CacheDir: filepath.Join(dir, "cache")})
+	require.NoError(t, err)
+	cch.InsertPod(&nri.PodSandbox{
+		Id:        "pod0",
+		Uid:       "uid0",
+		Name:      "pod0",
+		Namespace: "default",
+		Linux: &nri.LinuxPodSandbox{
+			CgroupParent: "/kubepods/burstable/pod0",
+		},
+	}, nil)
+	_, err = cch.InsertContainer(&nri.Container{
+		Id:           "ctr0",
+		PodSandboxId: "pod0",
+		Name:         "ctr0",
+		State:        nri.ContainerState_CONTAINER_RUNNING,
+	})
+	require.NoError(t, err)
+
+	lock := &countingLocker{}
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(NewCollector(cch, lock)))
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	// The cache is looked up under the lock, which is released afterwards.
+	require.Equal(t, 1, lock.locked)
+	require.True(t, lock.TryLock(), "lock not released")
+
+	values := map[string]float64{}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			values[metricKey(t, f.GetName(), m)] = metricValue(m)
+		}
+	}
+
+	for key, value := range map[string]float64{
+		"cpu_stat{type=usage_usec}":                          1000,
+		"cpu_stat{type=nr_throttled}":                        0,
+		"memory_stat{type=file}":                             8192,
+		"numa_stats{numa_node_id=N0,type=anon}":              4096,
+		"io_stat{major=8,minor=0,type=wbytes}":               1024,
+		"hugetlb_usage{size=2MB,type=Bytes}":                 2097152,
+		"pressure_avg{kind=some,resource=cpu,window=10s}":    1.5,
+		"pressure_avg{kind=full,resource=memory,window=60s}": 0,
+		"pressure_total_usec{kind=some,resource=cpu}":        100,
+		"pressure_total_usec{kind=full,resource=memory}":     300,
+	} {
+		v, ok := values[key]
+		require.True(t, ok, "metric %s not collected", key)
+		require.Equal(t, value, v, "metric %s", key)
+	}
+
+	// No I/O pressure, since io.pressure does not exist.
+	for key := range values {
+		require.NotContains(t, key, "resource=io", "unexpected metric %s", key)
+	}
+}
+
+// countingLocker is a mutex which counts how many times it was locked.
+type countingLocker struct {
+	sync.Mutex
+	locked int
+}
+
+func (l *countingLocker) Lock() {
+	l.Mutex.Lock()
+	l.locked++
+}
+
+// metricKey returns a metric name with its non-container labels, checking
+// that the container labels are resolved from the cache.
+func metricKey(t *testing.T, name string, m *model.Metric) string {
+	labels := []string{}
+	for _, l := range m.GetLabel() {
+		switch l.GetName() {
+		case "container_id":
+			require.Equal(t, "ctr0", l.GetValue())
+		case "namespace":
+			require.Equal(t, "default", l.GetValue())
+		case "pod":
+			require.Equal(t, "pod0", l.GetValue())
+		case "container":
+			require.Equal(t, "ctr0", l.GetValue())
+		default:
+			labels = append(labels, l.GetName()+"="+l.GetValue())
+		}
+	}
+	return name + "{" + strings.Join(labels, ",") + "}"
+}
+
+func metricValue(m *model.Metric) float64 {
+	if c := m.GetCounter(); c != nil {
+		return c.GetValue()
+	}
+	return m.GetGauge().GetValue()
+}
@@ -119,6 +119,9 @@ func start() error {
 		metrics.WithExporterDisabled(!cfg.PrometheusExport),
 		metrics.WithReportPeriod(cfg.ReportPeriod.Duration),
 		metrics.WithMetrics(cfg.Metrics),
+		metrics.WithCollectorEndpoint(cfg.MetricsCollector),
+		metrics.WithServiceName(ServiceName),
+		metrics.WithIdentity(identity...),
 	); err != nil {
 		return fmt.Errorf("failed to start metrics: %v", err)
 	}
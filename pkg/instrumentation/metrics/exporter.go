@@ -0,0 +1,151 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	otelprom "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"github.com/containers/nri-plugins/pkg/metrics"
+)
+
+const (
+	// timeout for flushing and shutting down the exporter
+	shutdownTimeout = 10 * time.Second
+)
+
+// otlpExporter periodically pushes gathered metrics to an OTLP collector,
+// using the OpenTelemetry Prometheus bridge to convert them.
+type otlpExporter struct {
+	exporter sdkmetric.Exporter
+	gatherer prometheus.Gatherer
+	resource *resource.Resource
+	period   time.Duration
+	provider *sdkmetric.MeterProvider
+}
+
+func newOtlpExporter(endpoint string, g prometheus.Gatherer, period time.Duration, attrs ...attribute.KeyValue) (*otlpExporter, error) {
+	var (
+		u   *url.URL
+		exp sdkmetric.Exporter
+		err error
+	)
+
+	// Notes:
+	//   Just like for tracing, we allow collector endpoint URLs to be given
+	//   as a plain scheme-prefix, in which case the exporters use defaults
+	//   defined by the OTLP library. These are:
+	//     - otlp-http, http: localhost:4318
+	//     - otlp-grpc, grpc: localhost:4317
+	//
+
+	switch endpoint {
+	case "otlp-http", "http", "otlp-grpc", "grpc":
+		u = &url.URL{Scheme: endpoint}
+	default:
+		u, err = url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metrics endpoint %q: %w", endpoint, err)
+		}
+	}
+
+	switch u.Scheme {
+	case "otlp-http", "http":
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithInsecure()}
+		if u.Host != "" {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(u.Host))
+		}
+		exp, err = otlpmetrichttp.New(context.Background(), opts...)
+	case "otlp-grpc", "grpc":
+		opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithInsecure()}
+		if u.Host != "" {
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(u.Host))
+		}
+		exp, err = otlpmetricgrpc.New(context.Background(), opts...)
+	default:
+		return nil, fmt.Errorf("unsupported metrics endpoint %q", endpoint)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP metrics exporter for %q: %w", endpoint, err)
+	}
+
+	switch {
+	case period == 0:
+		period = metrics.DefaultPollInterval
+	case period < metrics.MinPollInterval:
+		period = metrics.MinPollInterval
+	}
+
+	return &otlpExporter{
+		exporter: exp,
+		gatherer: g,
+		resource: resource.NewSchemaless(attrs...),
+		period:   period,
+	}, nil
+}
+
+// Start starts periodically pushing metrics.
+func (e *otlpExporter) Start() {
+	reader := sdkmetric.NewPeriodicReader(
+		e.exporter,
+		sdkmetric.WithInterval(e.period),
+		sdkmetric.WithProducer(otelprom.NewMetricProducer(otelprom.WithGatherer(e.gatherer))),
+	)
+	e.provider = sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(e.resource),
+	)
+}
+
+// Stop stops pushing metrics and shuts down the exporter.
+func (e *otlpExporter) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var err error
+	if e.provider != nil {
+		err = e.provider.Shutdown(ctx)
+		e.provider = nil
+	} else {
+		err = e.exporter.Shutdown(ctx)
+	}
+
+	if err != nil {
+		log.Warnf("failed to shut down OTLP metrics exporter: %v", err)
+	}
+}
+
+// export gathers metrics and pushes them to the collector immediately.
+func (e *otlpExporter) export() error {
+	if e.provider == nil {
+		return fmt.Errorf("OTLP metrics exporter not started")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return e.provider.ForceFlush(ctx)
+}
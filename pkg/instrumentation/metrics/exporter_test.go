@@ -0,0 +1,178 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/containers/nri-plugins/pkg/metrics"
+)
+
+// collector is a stand-in OTLP collector, serving both OTLP/HTTP and OTLP/gRPC.
+type collector struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	requests chan *colmetricspb.ExportMetricsServiceRequest
+}
+
+func newCollector() *collector {
+	return &collector{
+		requests: make(chan *colmetricspb.ExportMetricsServiceRequest, 16),
+	}
+}
+
+func (c *collector) Export(_ context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	c.requests <- req
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/metrics" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := &colmetricspb.ExportMetricsServiceRequest{}
+	if err := proto.Unmarshal(body, req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.requests <- req
+}
+
+func (c *collector) startHTTP(t *testing.T) string {
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+	return "otlp-http://" + srv.Listener.Addr().String()
+}
+
+func (c *collector) startGRPC(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(srv, c)
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(srv.Stop)
+	return "otlp-grpc://" + l.Addr().String()
+}
+
+func newTestGatherer(t *testing.T) prometheus.Gatherer {
+	reg := prometheus.NewPedanticRegistry()
+
+	counter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "test_counter", Help: "A test counter."},
+		[]string{"label"},
+	)
+	counter.WithLabelValues("a").Add(3)
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_gauge", Help: "A test gauge."})
+	gauge.Set(1.5)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "test_histogram",
+		Help:    "A test histogram.",
+		Buckets: []float64{1, 10},
+	})
+	for _, v := range []float64{0.5, 2, 5, 20} {
+		histogram.Observe(v)
+	}
+
+	require.NoError(t, reg.Register(counter))
+	require.NoError(t, reg.Register(gauge))
+	require.NoError(t, reg.Register(histogram))
+
+	return reg
+}
+
+func TestOtlpExporter(t *testing.T) {
+	for _, scheme := range []string{"http", "grpc"} {
+		t.Run(scheme, func(t *testing.T) {
+			c := newCollector()
+			endpoint := c.startHTTP(t)
+			if scheme == "grpc" {
+				endpoint = c.startGRPC(t)
+			}
+
+			e, err := newOtlpExporter(endpoint, newTestGatherer(t), 0, attribute.String("service.name", "test"))
+			require.NoError(t, err)
+			require.Equal(t, metrics.DefaultPollInterval, e.period)
+			e.Start()
+			defer e.Stop()
+
+			require.NoError(t, e.export())
+
+			var req *colmetricspb.ExportMetricsServiceRequest
+			select {
+			case req = <-c.requests:
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "timeout waiting for metrics")
+			}
+
+			require.Len(t, req.GetResourceMetrics(), 1)
+			rm := req.GetResourceMetrics()[0]
+			attrs := rm.GetResource().GetAttributes()
+			require.Len(t, attrs, 1)
+			require.Equal(t, "service.name", attrs[0].GetKey())
+			require.Equal(t, "test", attrs[0].GetValue().GetStringValue())
+			require.Len(t, rm.GetScopeMetrics(), 1)
+
+			got := map[string]*metricspb.Metric{}
+			for _, m := range rm.GetScopeMetrics()[0].GetMetrics() {
+				got[m.GetName()] = m
+			}
+			require.Len(t, got, 3)
+
+			sum := got["test_counter"].GetSum()
+			require.NotNil(t, sum)
+			require.True(t, sum.GetIsMonotonic())
+			require.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, sum.GetAggregationTemporality())
+			require.Len(t, sum.GetDataPoints(), 1)
+			dp := sum.GetDataPoints()[0]
+			require.Equal(t, 3.0, dp.GetAsDouble())
+			require.Equal(t, "label", dp.GetAttributes()[0].GetKey())
+			require.Equal(t, "a", dp.GetAttributes()[0].GetValue().GetStringValue())
+
+			gauge := got["test_gauge"].GetGauge()
+			require.NotNil(t, gauge)
+			require.Equal(t, 1.5, gauge.GetDataPoints()[0].GetAsDouble())
+
+			histogram := got["test_histogram"].GetHistogram()
+			require.NotNil(t, histogram)
+			hdp := histogram.GetDataPoints()[0]
+			require.Equal(t, uint64(4), hdp.GetCount())
+			require.Equal(t, 27.5, hdp.GetSum())
+			require.Equal(t, []float64{1, 10}, hdp.GetExplicitBounds())
+			require.Equal(t, []uint64{1, 2, 1}, hdp.GetBucketCounts())
+		})
+	}
+
+	_, err := newOtlpExporter("zipkin://localhost:9411", newTestGatherer(t), 0)
+	require.Error(t, err)
+}
@@ -16,10 +16,14 @@ package metrics
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"slices"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.19.0"
 
 	"github.com/containers/nri-plugins/pkg/http"
 	logger "github.com/containers/nri-plugins/pkg/log"
@@ -38,8 +42,12 @@ var (
 	enabled      []string
 	polled       []string
 	reportPeriod time.Duration
+	endpoint     string
+	service      = filepath.Base(os.Args[0])
+	identity     []attribute.KeyValue
 	mux          *http.ServeMux
 	gatherer     *metrics.Gatherer
+	exporter     *otlpExporter
 	log          = logger.Get("metrics")
 )
 
@@ -64,6 +72,30 @@ func WithReportPeriod(v time.Duration) Option {
 	}
 }
 
+// WithCollectorEndpoint sets the OTLP collector endpoint to push metrics to.
+func WithCollectorEndpoint(v string) Option {
+	return func() error {
+		endpoint = v
+		return nil
+	}
+}
+
+// WithServiceName sets the service name reported for metrics pushed with OTLP.
+func WithServiceName(v string) Option {
+	return func() error {
+		service = v
+		return nil
+	}
+}
+
+// WithIdentity sets extra resource attributes for metrics pushed with OTLP.
+func WithIdentity(attrs ...attribute.KeyValue) Option {
+	return func() error {
+		identity = slices.Clone(attrs)
+		return nil
+	}
+}
+
 func WithMetrics(cfg *config.Config) Option {
 	return func() error {
 		if cfg != nil {
@@ -88,11 +120,12 @@ func Start(m *http.ServeMux, options ...Option) error {
 
 	if m == nil {
 		log.Info("no mux provided, metrics exporter disabled")
-		return nil
+	} else if disabled {
+		log.Info("metrics exporter disabled")
 	}
 
-	if disabled {
-		log.Info("metrics exporter disabled")
+	prometheusExport := m != nil && !disabled
+	if !prometheusExport && endpoint == "" {
 		return nil
 	}
 
@@ -109,26 +142,58 @@ func Start(m *http.ServeMux, options ...Option) error {
 
 	gatherer = g
 
-	handlerOpts := promhttp.HandlerOpts{
-		ErrorLog:      log,
-		ErrorHandling: promhttp.ContinueOnError,
+	if endpoint != "" {
+		e, err := newOtlpExporter(endpoint, g, reportPeriod, resourceAttributes()...)
+		if err != nil {
+			Stop()
+			return fmt.Errorf("failed to create OTLP metrics exporter: %v", err)
+		}
+		log.Info("pushing metrics to %s every %s", endpoint, e.period)
+		e.Start()
+		exporter = e
 	}
-	m.Handle("/metrics", promhttp.HandlerFor(g, handlerOpts))
 
-	mux = m
+	if prometheusExport {
+		handlerOpts := promhttp.HandlerOpts{
+			ErrorLog:      log,
+			ErrorHandling: promhttp.ContinueOnError,
+		}
+		m.Handle("/metrics", promhttp.HandlerFor(g, handlerOpts))
+
+		mux = m
+	}
 
 	return nil
 }
 
 func Stop() {
-	if mux == nil {
-		return
+	if mux != nil {
+		mux.Unregister("/metrics")
+		mux = nil
+	}
+
+	if exporter != nil {
+		exporter.Stop()
+		exporter = nil
 	}
 
-	mux.Unregister("/metrics")
-	mux = nil
-	gatherer.Stop()
-	gatherer = nil
+	if gatherer != nil {
+		gatherer.Stop()
+		gatherer = nil
+	}
+}
+
+// resourceAttributes returns the resource attributes for metrics pushed with OTLP.
+func resourceAttributes() []attribute.KeyValue {
+	hostname, _ := os.Hostname()
+	return append(
+		[]attribute.KeyValue{
+			semconv.ServiceName(service),
+			semconv.HostNameKey.String(hostname),
+			semconv.ProcessPIDKey.Int64(int64(os.Getpid())),
+		},
+		identity...,
+	)
 }
 
 func Block() *MetricsBlock {
@@ -0,0 +1,142 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/containers/nri-plugins/pkg/utils/cpuset"
+)
+
+const (
+	// KubeletDir is the default kubelet root directory.
+	KubeletDir = "/var/lib/kubelet"
+	// CPUManagerStateFile is the checkpoint file of the kubelet CPU manager.
+	CPUManagerStateFile = "cpu_manager_state"
+	// MemoryManagerStateFile is the checkpoint file of the kubelet memory manager.
+	MemoryManagerStateFile = "memory_manager_state"
+)
+
+// KubeletAssignment is the CPUs and memory nodes assigned to a container
+// by the kubelet CPU and memory managers.
+type KubeletAssignment struct {
+	// CPUs are the exclusive CPUs assigned by the CPU manager.
+	CPUs cpuset.CPUSet
+	// Mems are the memory nodes assigned by the memory manager.
+	Mems cpuset.CPUSet
+}
+
+// KubeletState is the container assignments read from the checkpoints of
+// the kubelet CPU and memory managers, by pod UID and container name.
+type KubeletState map[string]map[string]*KubeletAssignment
+
+// cpuManagerCheckpoint is the kubelet CPU manager checkpoint. Only the
+// fields we need are decoded.
+type cpuManagerCheckpoint struct {
+	Entries map[string]map[string]string `json:"entries,omitempty"`
+}
+
+// memoryManagerCheckpoint is the kubelet memory manager checkpoint. Only the
+// fields we need are decoded.
+type memoryManagerCheckpoint struct {
+	Entries map[string]map[string][]memoryManagerBlock `json:"entries,omitempty"`
+}
+
+type memoryManagerBlock struct {
+	NUMAAffinity []int `json:"numaAffinity"`
+}
+
+// ReadKubeletState reads the container assignments of the kubelet CPU and
+// memory managers from their checkpoint files in the given kubelet root
+// directory. Missing checkpoint files are not an error. The checksums of
+// the checkpoints are not verified.
+func ReadKubeletState(dir string) (KubeletState, error) {
+	state := KubeletState{}
+
+	cpus := &cpuManagerCheckpoint{}
+	file := filepath.Join(dir, CPUManagerStateFile)
+	if err := readCheckpoint(file, cpus); err != nil {
+		return nil, err
+	}
+	for podUID, containers := range cpus.Entries {
+		for name, cset := range containers {
+			set, err := cpuset.Parse(cset)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cpuset %q for container %s/%s in %s: %w",
+					cset, podUID, name, file, err)
+			}
+			state.assignment(podUID, name).CPUs = set
+		}
+	}
+
+	mems := &memoryManagerCheckpoint{}
+	file = filepath.Join(dir, MemoryManagerStateFile)
+	if err := readCheckpoint(file, mems); err != nil {
+		return nil, err
+	}
+	for podUID, containers := range mems.Entries {
+		for name, blocks := range containers {
+			nodes := []int{}
+			for _, b := range blocks {
+				nodes = append(nodes, b.NUMAAffinity...)
+			}
+			a := state.assignment(podUID, name)
+			a.Mems = a.Mems.Union(cpuset.New(nodes...))
+		}
+	}
+
+	return state, nil
+}
+
+// Get returns the assignment of the given container, if there is one.
+func (s KubeletState) Get(podUID, name string) (*KubeletAssignment, bool) {
+	a, ok := s[podUID][name]
+	return a, ok
+}
+
+func (s KubeletState) assignment(podUID, name string) *KubeletAssignment {
+	containers, ok := s[podUID]
+	if !ok {
+		containers = map[string]*KubeletAssignment{}
+		s[podUID] = containers
+	}
+	a, ok := containers[name]
+	if !ok {
+		a = &KubeletAssignment{CPUs: cpuset.New(), Mems: cpuset.New()}
+		containers[name] = a
+	}
+	return a
+}
+
+func readCheckpoint(file string, checkpoint interface{}) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read kubelet checkpoint: %w", err)
+	}
+
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return fmt.Errorf("failed to parse kubelet checkpoint %s: %w", file, err)
+	}
+
+	return nil
+}
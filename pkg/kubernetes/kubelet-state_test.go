@@ -0,0 +1,97 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testCPUManagerState = `{
+  "policyName": "static",
+  "defaultCpuSet": "0-1,6-7",
+  "entries": {
+    "uid0": {"ctr0": "2-3", "ctr1": "4-5"},
+    "uid1": {"ctr0": "8"}
+  },
+  "checksum": 1234567890
+}`
+	testMemoryManagerState = `{
+  "policyName": "Static",
+  "machineState": {},
+  "entries": {
+    "uid0": {
+      "ctr0": [
+        {"numaAffinity": [0], "type": "memory", "size": 1073741824},
+        {"numaAffinity": [1], "type": "hugepages-2Mi", "size": 4194304}
+      ]
+    },
+    "uid2": {
+      "ctr0": [{"numaAffinity": [1], "type": "memory", "size": 1073741824}]
+    }
+  },
+  "checksum": 1234567890
+}`
+)
+
+func TestReadKubeletState(t *testing.T) {
+	dir := t.TempDir()
+
+	state, err := ReadKubeletState(dir)
+	require.NoError(t, err, "missing checkpoints")
+	require.Empty(t, state)
+
+	writeCheckpoint(t, dir, CPUManagerStateFile, testCPUManagerState)
+	writeCheckpoint(t, dir, MemoryManagerStateFile, testMemoryManagerState)
+
+	state, err = ReadKubeletState(dir)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		podUID string
+		name   string
+		cpus   string
+		mems   string
+	}{
+		{podUID: "uid0", name: "ctr0", cpus: "2-3", mems: "0-1"},
+		{podUID: "uid0", name: "ctr1", cpus: "4-5", mems: ""},
+		{podUID: "uid1", name: "ctr0", cpus: "8", mems: ""},
+		{podUID: "uid2", name: "ctr0", cpus: "", mems: "1"},
+	} {
+		a, ok := state.Get(tc.podUID, tc.name)
+		require.True(t, ok, "%s/%s", tc.podUID, tc.name)
+		require.Equal(t, tc.cpus, a.CPUs.String(), "%s/%s", tc.podUID, tc.name)
+		require.Equal(t, tc.mems, a.Mems.String(), "%s/%s", tc.podUID, tc.name)
+	}
+
+	_, ok := state.Get("uid1", "ctr1")
+	require.False(t, ok)
+
+	writeCheckpoint(t, dir, CPUManagerStateFile, `{"entries": {"uid0": {"ctr0": "x"}}}`)
+	_, err = ReadKubeletState(dir)
+	require.Error(t, err, "invalid cpuset")
+
+	writeCheckpoint(t, dir, CPUManagerStateFile, `{"entries":`)
+	_, err = ReadKubeletState(dir)
+	require.Error(t, err, "invalid checkpoint")
+}
+
+func writeCheckpoint(t *testing.T, dir, file, data string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(data), 0o644))
+}
@@ -35,7 +35,13 @@ type Rate struct {
 // ratelimited implements rate-limited logging with a sliding window of unique messages.
 type ratelimited struct {
 	Logger
-	sync.Mutex
+	limiter *Limiter
+}
+
+// Limiter limits the rate of unique messages, or other keys, using a sliding
+// window of the most recently seen ones.
+type Limiter struct {
+	lock   sync.Mutex
 	rate   Rate
 	window []string
 	limits map[string]*goxrate.Limiter
@@ -60,6 +66,14 @@ func Interval(interval time.Duration) Rate {
 
 // RateLimit returns a ratelimited version of the given logger.
 func RateLimit(log Logger, rate Rate) Logger {
+	return &ratelimited{
+		Logger:  log,
+		limiter: NewLimiter(rate),
+	}
+}
+
+// NewLimiter returns a new per-message limiter with the given rate.
+func NewLimiter(rate Rate) *Limiter {
 	switch {
 	case rate.Window == 0:
 		rate.Window = DefaultWindow
@@ -69,59 +83,67 @@ func RateLimit(log Logger, rate Rate) Logger {
 	if rate.Burst < 1 {
 		rate.Burst = 1
 	}
-	return &ratelimited{
-		Logger: log,
+	return &Limiter{
 		rate:   rate,
 		window: make([]string, 0, rate.Window),
 		limits: make(map[string]*goxrate.Limiter),
 	}
 }
 
+// Allow returns true if the given message is allowed by the rate limit.
+func (l *Limiter) Allow(msg string) bool {
+	return l.getMessageLimit(msg).Allow()
+}
+
 func (rl *ratelimited) Debug(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	if limit := rl.getMessageLimit(msg); limit.Allow() {
+	if rl.limiter.Allow(msg) {
 		rl.Logger.Debug("<rate-limited> %s", msg)
 	}
 }
 
 func (rl *ratelimited) Info(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	if limit := rl.getMessageLimit(msg); limit.Allow() {
+	if rl.limiter.Allow(msg) {
 		rl.Logger.Info("<rate-limited> %s", msg)
 	}
 }
 
 func (rl *ratelimited) Warn(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	if limit := rl.getMessageLimit(msg); limit.Allow() {
+	if rl.limiter.Allow(msg) {
 		rl.Logger.Warn("<rate-limited> %s", msg)
 	}
 }
 
 func (rl *ratelimited) Error(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	if limit := rl.getMessageLimit(msg); limit.Allow() {
+	if rl.limiter.Allow(msg) {
 		rl.Logger.Error("<rate-limited> %s", msg)
 	}
 }
 
-// Get existing message limit or create a new one, shifting out the oldest if window is full.
 func (rl *ratelimited) getMessageLimit(msg string) *goxrate.Limiter {
-	rl.Lock()
-	defer rl.Unlock()
+	return rl.limiter.getMessageLimit(msg)
+}
+
+// Get existing message limit or create a new one, shifting out the oldest if window is full.
+func (l *Limiter) getMessageLimit(msg string) *goxrate.Limiter {
+	l.lock.Lock()
+	defer l.lock.Unlock()
 
-	limit, ok := rl.limits[msg]
+	limit, ok := l.limits[msg]
 	if ok {
 		return limit
 	}
 
-	limit = goxrate.NewLimiter(rl.rate.Limit, rl.rate.Burst)
-	if len(rl.limits) == rl.rate.Window {
-		delete(rl.limits, rl.window[0])
-		rl.window = rl.window[1:]
+	limit = goxrate.NewLimiter(l.rate.Limit, l.rate.Burst)
+	if len(l.limits) == l.rate.Window {
+		delete(l.limits, l.window[0])
+		l.window = l.window[1:]
 	}
-	rl.window = append(rl.window, msg)
-	rl.limits[msg] = limit
+	l.window = append(l.window, msg)
+	l.limits[msg] = limit
 
 	return limit
 }
@@ -76,3 +76,17 @@ func TestRateLimit(t *testing.T) {
 		}
 	}
 }
+
+func TestLimiter(t *testing.T) {
+	limiter := NewLimiter(Interval(time.Hour))
+
+	if !limiter.Allow("message #1") {
+		t.Errorf("first message #1 unexpectedly rate-limited")
+	}
+	if !limiter.Allow("message #2") {
+		t.Errorf("first message #2 unexpectedly rate-limited")
+	}
+	if limiter.Allow("message #1") {
+		t.Errorf("repeated message #1 unexpectedly allowed")
+	}
+}
@@ -44,8 +44,14 @@ func NewVersionInfoCollector(v, b string) prometheus.Collector {
 func init() {
 	var (
 		collectors = map[string]prometheus.Collector{
-			"buildinfo":   collectors.NewBuildInfoCollector(),
-			"golang":      collectors.NewGoCollector(),
+			"buildinfo": collectors.NewBuildInfoCollector(),
+			"golang": collectors.NewGoCollector(
+				collectors.WithGoCollectorRuntimeMetrics(
+					collectors.MetricsGC,
+					collectors.MetricsMemory,
+					collectors.MetricsScheduler,
+				),
+			),
 			"process":     collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 			"versioninfo": NewVersionInfoCollector(version.Version, version.Build),
 		}
@@ -0,0 +1,224 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resmgr
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/containers/nri-plugins/pkg/cgroupstats"
+	"github.com/containers/nri-plugins/pkg/metrics"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/sysfs"
+	"github.com/containers/nri-plugins/pkg/utils/cpuset"
+)
+
+var (
+	allocationDesc = prometheus.NewDesc(
+		"container_allocation",
+		"Policy, pool and memory types of the container, as labels.",
+		cgroupstats.WithContainerLabels("policy", "pool", "memory_types"),
+		nil,
+	)
+	allocatedCPUsDesc = prometheus.NewDesc(
+		"container_allocated_cpus",
+		"Number of CPUs allocated to the container, by sharing.",
+		cgroupstats.WithContainerLabels("pool", "sharing"),
+		nil,
+	)
+	allocatedMemsDesc = prometheus.NewDesc(
+		"container_allocated_mem_nodes",
+		"Number of memory nodes allocated to the container, by memory type.",
+		cgroupstats.WithContainerLabels("pool", "memory_type"),
+		nil,
+	)
+)
+
+// allocationCollector exports the resources allocated to containers. The
+// metrics are derived from the cache and the pools of the active policy,
+// so they are the same regardless of the policy in use.
+type allocationCollector struct {
+	m        *resmgr
+	memTypes map[int]string
+}
+
+func (m *resmgr) newAllocationCollector() *allocationCollector {
+	c := &allocationCollector{
+		m:        m,
+		memTypes: map[int]string{},
+	}
+
+	sys, err := sysfs.DiscoverSystem()
+	if err != nil {
+		log.Warnf("failed to discover memory types for allocation metrics: %v", err)
+		return c
+	}
+	for _, id := range sys.NodeIDs() {
+		c.memTypes[id] = sys.Node(id).GetMemoryType().String()
+	}
+
+	return c
+}
+
+// setupAllocationMetrics registers the per-container allocation collector.
+func (m *resmgr) setupAllocationMetrics() {
+	err := metrics.Register(
+		"allocations",
+		m.newAllocationCollector(),
+		metrics.WithGroup("policy"),
+	)
+	if err != nil {
+		log.Error("failed to register policy/allocations collector: %v", err)
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *allocationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- allocationDesc
+	ch <- allocatedCPUsDesc
+	ch <- allocatedMemsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *allocationCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c.collect() {
+		ch <- m
+	}
+}
+
+// collect returns the allocation metrics, looked up under the resource
+// manager lock.
+func (c *allocationCollector) collect() []prometheus.Metric {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	var (
+		policyName = ""
+		poolOf     = map[string]string{}
+		sharedOf   = map[string]cpuset.CPUSet{}
+		containers = []cache.Container{}
+		cpusOf     = map[string]cpuset.CPUSet{}
+		usersOf    = map[int]int{}
+		result     = []prometheus.Metric{}
+	)
+
+	if c.m.policy != nil {
+		policyName = c.m.policy.ActivePolicy()
+		if pools, ok := c.m.policy.GetPools(); ok {
+			for _, pool := range pools {
+				shared, _ := cpuset.Parse(pool.SharedCPUs)
+				for _, id := range pool.Containers {
+					poolOf[id] = pool.Name
+					sharedOf[id] = shared
+				}
+			}
+		}
+	}
+
+	for _, ctr := range c.m.cache.GetContainers() {
+		switch ctr.GetState() {
+		case cache.ContainerStateCreating, cache.ContainerStateCreated, cache.ContainerStateRunning:
+		default:
+			continue
+		}
+		cpus, err := cpuset.Parse(ctr.GetCpusetCpus())
+		if err != nil {
+			log.Warnf("allocation metrics: %s: invalid cpuset %q: %v",
+				ctr.PrettyName(), ctr.GetCpusetCpus(), err)
+			continue
+		}
+		containers = append(containers, ctr)
+		cpusOf[ctr.GetID()] = cpus
+		for _, cpu := range cpus.UnsortedList() {
+			usersOf[cpu]++
+		}
+	}
+
+	for _, ctr := range containers {
+		id := ctr.GetID()
+		cpus := cpusOf[id]
+
+		pool, ok := poolOf[id]
+		if !ok {
+			if d := ctr.GetDecision(); d != nil {
+				pool = d.Choice
+			}
+		}
+
+		// A CPU is shared if it is in the shared CPUs of the pool of the
+		// container, or if any other container can run on it.
+		poolShared := sharedOf[id]
+		exclusive, shared := 0, 0
+		for _, cpu := range cpus.UnsortedList() {
+			if usersOf[cpu] > 1 || poolShared.Contains(cpu) {
+				shared++
+			} else {
+				exclusive++
+			}
+		}
+
+		mems, _ := cpuset.Parse(ctr.GetCpusetMems())
+		memTypes := map[string]int{}
+		for _, node := range mems.List() {
+			memType, ok := c.memTypes[node]
+			if !ok {
+				memType = "unknown"
+			}
+			memTypes[memType]++
+		}
+		types := make([]string, 0, len(memTypes))
+		for memType := range memTypes {
+			types = append(types, memType)
+		}
+		slices.Sort(types)
+
+		labels := cgroupstats.ContainerLabelValues(ctr)
+
+		result = append(result,
+			prometheus.MustNewConstMetric(
+				allocationDesc,
+				prometheus.GaugeValue,
+				1,
+				append(slices.Clip(labels), policyName, pool, strings.Join(types, ","))...,
+			),
+			prometheus.MustNewConstMetric(
+				allocatedCPUsDesc,
+				prometheus.GaugeValue,
+				float64(exclusive),
+				append(slices.Clip(labels), pool, "exclusive")...,
+			),
+			prometheus.MustNewConstMetric(
+				allocatedCPUsDesc,
+				prometheus.GaugeValue,
+				float64(shared),
+				append(slices.Clip(labels), pool, "shared")...,
+			),
+		)
+		for _, memType := range types {
+			result = append(result,
+				prometheus.MustNewConstMetric(
+					allocatedMemsDesc,
+					prometheus.GaugeValue,
+					float64(memTypes[memType]),
+					append(slices.Clip(labels), pool, memType)...,
+				),
+			)
+		}
+	}
+
+	return result
+}
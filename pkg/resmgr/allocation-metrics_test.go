@@ -0,0 +1,82 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resmgr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/containers/nri-plugins/pkg/resmgr/introspect"
+)
+
+func TestAllocationMetrics(t *testing.T) {
+	m := newIntrospectionTestResmgr(t, &mockPolicy{
+		name: "topology-aware",
+		pools: []*introspect.Pool{
+			{
+				Name:       "NUMA node #0",
+				CPUs:       "0-3",
+				SharedCPUs: "1",
+				Mems:       "0",
+				Containers: []string{"ctr0-id"},
+			},
+		},
+	})
+
+	ctr0, ok := m.cache.LookupContainer("ctr0-id")
+	require.True(t, ok)
+	ctr0.SetCpusetCpus("0-3")
+	ctr0.SetCpusetMems("0-1")
+	ctr1, ok := m.cache.LookupContainer("ctr1-id")
+	require.True(t, ok)
+	ctr1.SetCpusetCpus("3")
+
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(&allocationCollector{
+		m:        m,
+		memTypes: map[int]string{0: "DRAM"},
+	}))
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	values := map[string]float64{}
+	for _, f := range families {
+		for _, metric := range f.GetMetric() {
+			labels := []string{}
+			for _, l := range metric.GetLabel() {
+				labels = append(labels, l.GetName()+"="+l.GetValue())
+			}
+			key := f.GetName() + "{" + strings.Join(labels, ",") + "}"
+			values[key] = metric.GetGauge().GetValue()
+		}
+	}
+
+	for key, value := range map[string]float64{
+		"container_allocation{container=ctr0,container_id=ctr0-id,memory_types=DRAM,unknown,namespace=default,pod=pod0,policy=topology-aware,pool=NUMA node #0}": 1,
+		"container_allocated_cpus{container=ctr0,container_id=ctr0-id,namespace=default,pod=pod0,pool=NUMA node #0,sharing=exclusive}":                           2,
+		"container_allocated_cpus{container=ctr0,container_id=ctr0-id,namespace=default,pod=pod0,pool=NUMA node #0,sharing=shared}":                              2,
+		"container_allocated_mem_nodes{container=ctr0,container_id=ctr0-id,memory_type=DRAM,namespace=default,pod=pod0,pool=NUMA node #0}":                       1,
+		"container_allocated_mem_nodes{container=ctr0,container_id=ctr0-id,memory_type=unknown,namespace=default,pod=pod0,pool=NUMA node #0}":                    1,
+		"container_allocated_cpus{container=ctr1,container_id=ctr1-id,namespace=default,pod=pod0,pool=,sharing=exclusive}":                                       0,
+		"container_allocated_cpus{container=ctr1,container_id=ctr1-id,namespace=default,pod=pod0,pool=,sharing=shared}":                                          1,
+	} {
+		v, ok := values[key]
+		require.True(t, ok, "metric %s not collected", key)
+		require.Equal(t, value, v, "metric %s", key)
+	}
+}
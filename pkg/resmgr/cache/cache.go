@@ -325,6 +325,11 @@ type Container interface {
 	SetTag(string, string) (string, bool)
 	// DeleteTag deletes the given tag, returning its deleted value.
 	DeleteTag(string) (string, bool)
+
+	// SetDecision records the placement decision of the policy for this container.
+	SetDecision(*Decision)
+	// GetDecision returns the last recorded placement decision for this container.
+	GetDecision() *Decision
 }
 
 // A cached container.
@@ -348,6 +353,8 @@ type container struct {
 	BlockIOClass string // Block I/O class this container is assigned to.
 	ToptierLimit int64  // Top tier memory limit.
 
+	Decision *Decision // last placement decision of the policy
+
 	pending map[string]struct{} // controllers with pending changes for this container
 
 	prettyName string    // cached PrettyName()
@@ -421,11 +428,16 @@ type Cache interface {
 
 	// Save requests a cache save.
 	Save() error
+	// Flush writes any pending save to disk immediately.
+	Flush() error
 
 	// RefreshPods purges/inserts stale/new pods/containers using a pod sandbox list response.
 	RefreshPods([]*nri.PodSandbox, <-chan *podresapi.PodResourcesList) ([]Pod, []Pod, []Container)
 	// RefreshContainers purges/inserts stale/new containers using a container list response.
 	RefreshContainers([]*nri.Container) ([]Container, []Container)
+	// RefreshCpusets updates the cpusets of cached containers to the ones in a
+	// container list response.
+	RefreshCpusets([]*nri.Container)
 
 	// Get the container (data) directory for a container.
 	ContainerDirectory(string) string
@@ -460,6 +472,8 @@ type cache struct {
 	sync.Mutex `json:"-"` // we're lockable
 	filePath   string     // where to store to/load from
 	dataDir    string     // container data directory
+	stateless  bool       // don't store to/load from filePath
+	persister  *persister // asynchronous saving, if enabled
 
 	Pods       map[string]*pod       // known/cached pods
 	Containers map[string]*container // known/cache containers
@@ -481,6 +495,15 @@ var _ Cache = &cache{}
 type Options struct {
 	// CacheDir is the directory the cache should save its state in.
 	CacheDir string
+	// Stateless disables saving the cache to disk. The state is rebuilt
+	// from the runtime instead, and any previously saved state is removed.
+	Stateless bool
+	// SaveDelay enables asynchronous saving. Saves requested within this
+	// delay of each other are coalesced into a single write.
+	SaveDelay time.Duration
+	// Locker serializes asynchronous saves with updates to the cache. It
+	// must be held while the cache is updated, and when calling Flush().
+	Locker sync.Locker
 }
 
 // NewCache instantiates a new cache. Load it from the given path if it exists.
@@ -488,6 +511,7 @@ func NewCache(options Options) (Cache, error) {
 	cch := &cache{
 		filePath:   filepath.Join(options.CacheDir, "cache"),
 		dataDir:    filepath.Join(options.CacheDir, "containers"),
+		stateless:  options.Stateless,
 		Pods:       make(map[string]*pod),
 		Containers: make(map[string]*container),
 		NextID:     1,
@@ -505,6 +529,21 @@ func NewCache(options Options) (Cache, error) {
 	if err := cch.mkdirAll("container", cch.dataDir, dataDirPerm); err != nil {
 		return nil, err
 	}
+
+	if options.SaveDelay > 0 && !cch.stateless {
+		if options.Locker == nil {
+			return nil, cacheError("asynchronous saving requires a locker")
+		}
+		cch.persister = newPersister(cch, options.Locker, options.SaveDelay)
+	}
+
+	if cch.stateless {
+		if err := os.Remove(cch.filePath); err != nil && !os.IsNotExist(err) {
+			return nil, cacheError("failed to remove stale cache file %q: %v", cch.filePath, err)
+		}
+		return cch, nil
+	}
+
 	if err := cch.Load(); err != nil {
 		return nil, err
 	}
@@ -721,6 +760,16 @@ func (cch *cache) RefreshContainers(containers []*nri.Container) ([]Container, [
 	return add, del
 }
 
+// RefreshCpusets updates the cpusets of cached containers to the ones in a
+// container list response.
+func (cch *cache) RefreshCpusets(containers []*nri.Container) {
+	for _, c := range containers {
+		if existing, ok := cch.Containers[c.Id]; ok {
+			existing.refreshCpuset(c)
+		}
+	}
+}
+
 // Mark a container as having pending changes.
 func (cch *cache) markPending(c *container) {
 	if cch.pending == nil {
@@ -1117,6 +1166,15 @@ func (cch *cache) Restore(data []byte) error {
 
 // Save the state of the cache.
 func (cch *cache) Save() error {
+	if cch.stateless {
+		return nil
+	}
+
+	if cch.persister != nil {
+		cch.persister.request()
+		return nil
+	}
+
 	log.Debug("saving cache to file '%s'...", cch.filePath)
 
 	data, err := cch.Snapshot()
@@ -1124,16 +1182,18 @@ func (cch *cache) Save() error {
 		return cacheError("failed to save cache: %v", err)
 	}
 
-	tmpPath := cch.filePath + ".saving"
-	if err = os.WriteFile(tmpPath, data, cacheFilePerm.prefer); err != nil {
-		return cacheError("failed to write cache to file %q: %v", tmpPath, err)
-	}
-	if err := os.Rename(tmpPath, cch.filePath); err != nil {
-		return cacheError("failed to rename %q to %q: %v",
-			tmpPath, cch.filePath, err)
+	return writeFileAtomic(cch.filePath, data, cacheFilePerm.prefer)
+}
+
+// Flush writes any pending asynchronous save of the cache immediately.
+func (cch *cache) Flush() error {
+	if cch.persister == nil {
+		return nil
 	}
 
-	return nil
+	log.Debug("flushing cache to file '%s'...", cch.filePath)
+
+	return cch.persister.flush()
 }
 
 // Load loads the last saved state of the cache.
@@ -16,6 +16,7 @@ package cache_test
 
 import (
 	"fmt"
+	"path/filepath"
 
 	nri "github.com/containerd/nri/pkg/api"
 	. "github.com/onsi/ginkgo/v2"
@@ -85,6 +86,28 @@ var _ = Describe("Cache", func() {
 		Expect(pod).To(BeNil())
 		Expect(ok).To(BeFalse())
 	})
+
+	It("neither restores nor saves state when stateless", func() {
+		dir := GinkgoT().TempDir()
+
+		c, err := cache.NewCache(cache.Options{CacheDir: dir})
+		Expect(err).To(BeNil())
+		c.InsertPod(makePod(), nil)
+		Expect(filepath.Join(dir, "cache")).To(BeARegularFile())
+
+		c, err = cache.NewCache(cache.Options{CacheDir: dir, Stateless: true})
+		Expect(err).To(BeNil())
+		Expect(filepath.Join(dir, "cache")).ToNot(BeAnExistingFile())
+		Expect(c.GetPods()).To(BeEmpty())
+
+		nriPod := makePod()
+		c.InsertPod(nriPod, nil)
+		ctr, err := c.InsertContainer(makeCtr(WithCtrPodID(nriPod.Id)))
+		Expect(err).To(BeNil())
+		Expect(c.Save()).To(Succeed())
+		Expect(filepath.Join(dir, "cache")).ToNot(BeAnExistingFile())
+		Expect(c.ContainerDirectory(ctr.GetID())).To(BeADirectory())
+	})
 })
 
 func makeCache() cache.Cache {
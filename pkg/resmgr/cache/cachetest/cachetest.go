@@ -0,0 +1,102 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cachetest provides fixtures for testing code which handles
+// cached containers and their cgroups.
+package cachetest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	nri "github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/containers/nri-plugins/pkg/cgroups"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+)
+
+const (
+	// PodID is the ID of the pod created by NewCache.
+	PodID = "pod0"
+	// PodCgroupParent is the cgroup parent of the pod created by NewCache.
+	PodCgroupParent = "/kubepods/burstable/pod0"
+)
+
+// WriteFiles creates the given files with their content under dir.
+func WriteFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+// ReadFile returns the content of a file under dir.
+func ReadFile(t testing.TB, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	require.NoError(t, err)
+	return string(data)
+}
+
+// NewCache creates a cache with a single Burstable pod and sets up a fake
+// cgroup v2 hierarchy for the duration of the test.
+func NewCache(t testing.TB) cache.Cache {
+	t.Helper()
+	dir := t.TempDir()
+
+	mountDir := cgroups.GetMountDir()
+	cgroups.SetMountDir(filepath.Join(dir, "cgroup"))
+	t.Cleanup(func() { cgroups.SetMountDir(mountDir) })
+
+	cch, err := cache.NewCache(cache.Options{CacheDir: filepath.Join(dir, "cache")})
+	require.NoError(t, err)
+	cch.InsertPod(&nri.PodSandbox{
+		Id:        PodID,
+		Uid:       "uid0",
+		Name:      "pod0",
+		Namespace: "default",
+		Linux: &nri.LinuxPodSandbox{
+			CgroupParent: PodCgroupParent,
+		},
+	}, nil)
+
+	return cch
+}
+
+// AddContainer adds a running container to the pod of a cache created by
+// NewCache. The container gets a cgroup with the given files in addition
+// to cgroup.controllers. It returns the container and its cgroup directory.
+func AddContainer(t testing.TB, cch cache.Cache, name string, files map[string]string) (cache.Container, string) {
+	t.Helper()
+
+	dir := filepath.Join(cgroups.GetMountDir(), PodCgroupParent, name)
+	WriteFiles(t, dir, map[string]string{
+		"cgroup.controllers": "cpuset cpu io memory\n",
+	})
+	WriteFiles(t, dir, files)
+
+	c, err := cch.InsertContainer(&nri.Container{
+		Id:           name,
+		PodSandboxId: PodID,
+		Name:         name,
+		State:        nri.ContainerState_CONTAINER_RUNNING,
+	})
+	require.NoError(t, err)
+
+	return c, dir
+}
@@ -541,6 +541,10 @@ func mergeNRIResources(u *nri.LinuxResources, orig *nri.LinuxResources) *nri.Lin
 		}
 	}
 
+	if len(u.HugepageLimits) == 0 {
+		u.HugepageLimits = orig.GetHugepageLimits()
+	}
+
 	log.Debug("merged resource update: %+v", u)
 
 	return u
@@ -637,6 +641,18 @@ func (c *container) ensureLinuxResourcesCPU() {
 	}
 }
 
+// refreshCpuset updates the cpuset of the container to the one the runtime
+// reports as currently set, without making it a pending change.
+func (c *container) refreshCpuset(ctr *nri.Container) {
+	cpu := ctr.GetLinux().GetResources().GetCpu()
+	if cpu == nil {
+		return
+	}
+	c.ensureLinuxResourcesCPU()
+	c.Ctr.Linux.Resources.Cpu.Cpus = cpu.GetCpus()
+	c.Ctr.Linux.Resources.Cpu.Mems = cpu.GetMems()
+}
+
 func (c *container) ensureLinuxResourcesMemory() {
 	c.ensureLinuxResources()
 	if c.Ctr.Linux.Resources.Memory == nil {
@@ -1005,6 +1021,14 @@ func (c *container) DeleteTag(key string) (string, bool) {
 	return value, ok
 }
 
+func (c *container) SetDecision(d *Decision) {
+	c.Decision = d
+}
+
+func (c *container) GetDecision() *Decision {
+	return c.Decision
+}
+
 func (c *container) implicitAffinities(hasExplicit bool) []*Affinity {
 	affinities := []*Affinity{}
 	for name, generate := range c.cache.implicit {
@@ -0,0 +1,131 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containers/nri-plugins/pkg/topology"
+)
+
+// Decision records how a policy decided where to place a container. It
+// lists the candidates, for instance pools or balloon types, the policy
+// considered, why the rejected ones lost, and the final choice.
+type Decision struct {
+	// Policy is the name of the policy which made the decision.
+	Policy string `json:"policy"`
+	// Time is the time the decision was made.
+	Time time.Time `json:"time"`
+	// Request is a description of the resource request of the container.
+	Request string `json:"request,omitempty"`
+	// Match describes the rule which selected the pool, or pool type, of
+	// the container, for policies which match containers against rules.
+	Match string `json:"match,omitempty"`
+	// Hints are the topology hints which were taken into account.
+	Hints []string `json:"hints,omitempty"`
+	// Annotations are the effective annotations which affected the decision.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Expressions are the evaluated expressions with their results.
+	Expressions []string `json:"expressions,omitempty"`
+	// Candidates are the candidates considered, best first.
+	Candidates []*Candidate `json:"candidates,omitempty"`
+	// Choice is the name of the chosen candidate.
+	Choice string `json:"choice"`
+	// Reason describes why the chosen candidate was chosen.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Candidate is a placement candidate considered in a Decision.
+type Candidate struct {
+	// Name is the name of the candidate.
+	Name string `json:"name"`
+	// Score is the policy-specific score of the candidate, if any.
+	Score string `json:"score,omitempty"`
+	// Reason describes why the candidate was rejected, if it was.
+	Reason string `json:"reason,omitempty"`
+}
+
+// NewDecision creates a new, empty decision for the given policy.
+func NewDecision(policy string) *Decision {
+	return &Decision{
+		Policy: policy,
+		Time:   time.Now(),
+	}
+}
+
+// SetHints records the topology hints which were taken into account.
+func (d *Decision) SetHints(hints topology.Hints) {
+	d.Hints = nil
+	for _, hint := range hints {
+		d.Hints = append(d.Hints, hint.String())
+	}
+	sort.Strings(d.Hints)
+}
+
+// AddAnnotation records an annotation which affected the decision.
+func (d *Decision) AddAnnotation(key, value string) {
+	if d.Annotations == nil {
+		d.Annotations = map[string]string{}
+	}
+	d.Annotations[key] = value
+}
+
+// AddExpression records an evaluated expression, or other matching rule,
+// with its result.
+func (d *Decision) AddExpression(expr string, result bool) {
+	d.Expressions = append(d.Expressions, fmt.Sprintf("%s: %v", expr, result))
+}
+
+// AddCandidate records a considered candidate.
+func (d *Decision) AddCandidate(name, score, reason string) {
+	d.Candidates = append(d.Candidates, &Candidate{
+		Name:   name,
+		Score:  score,
+		Reason: reason,
+	})
+}
+
+// Choose records the final choice and the reason for it.
+func (d *Decision) Choose(name, reason string) {
+	d.Choice = name
+	d.Reason = reason
+}
+
+// String returns the decision as a single-line string.
+func (d *Decision) String() string {
+	if d == nil {
+		return "<no decision>"
+	}
+
+	rejected := []string{}
+	for _, c := range d.Candidates {
+		if c.Name != d.Choice && c.Reason != "" {
+			rejected = append(rejected, c.Name+": "+c.Reason)
+		}
+	}
+
+	str := fmt.Sprintf("%s chose %s", d.Policy, d.Choice)
+	if d.Reason != "" {
+		str += " (" + d.Reason + ")"
+	}
+	if len(rejected) > 0 {
+		str += ", rejected " + strings.Join(rejected, ", ")
+	}
+
+	return str
+}
@@ -0,0 +1,67 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache_test
+
+import (
+	"testing"
+
+	nri "github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/topology"
+)
+
+func TestDecision(t *testing.T) {
+	d := cache.NewDecision("test")
+	d.SetHints(topology.Hints{
+		"/dev/b": {Provider: "/dev/b", NUMAs: "1"},
+		"/dev/a": {Provider: "/dev/a", CPUs: "0-3"},
+	})
+	d.AddExpression("<name In ctr0>", false)
+	d.AddCandidate("pool0", "score 1", "")
+	d.AddCandidate("pool1", "score 2", "lower affinity")
+	d.Choose("pool0", "best score")
+
+	require.Equal(t, []string{"<hints CPUs:0-3 (from /dev/a)>", "<hints NUMAs:1 (from /dev/b)>"}, d.Hints)
+	require.Equal(t, []string{"<name In ctr0>: false"}, d.Expressions)
+	require.Equal(t, "test chose pool0 (best score), rejected pool1: lower affinity", d.String())
+	require.Equal(t, "<no decision>", (*cache.Decision)(nil).String())
+}
+
+func TestDecisionIsSaved(t *testing.T) {
+	dir := t.TempDir()
+
+	cch, err := cache.NewCache(cache.Options{CacheDir: dir})
+	require.NoError(t, err)
+
+	cch.InsertPod(&nri.PodSandbox{Id: "pod0", Uid: "uid0", Name: "pod0", Namespace: "default"}, nil)
+	c, err := cch.InsertContainer(&nri.Container{Id: "ctr0-id", PodSandboxId: "pod0", Name: "ctr0"})
+	require.NoError(t, err)
+
+	d := cache.NewDecision("test")
+	d.AddAnnotation("balloon.balloons.resource-policy.nri.io", "high-prio")
+	d.Choose("high-prio[0]", "annotation")
+	c.SetDecision(d)
+	require.NoError(t, cch.Save())
+
+	cch, err = cache.NewCache(cache.Options{CacheDir: dir})
+	require.NoError(t, err)
+	c, ok := cch.LookupContainer("ctr0-id")
+	require.True(t, ok)
+	require.NotNil(t, c.GetDecision())
+	require.Equal(t, "high-prio[0]", c.GetDecision().Choice)
+	require.Equal(t, d.Annotations, c.GetDecision().Annotations)
+}
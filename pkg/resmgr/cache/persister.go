@@ -0,0 +1,166 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// persister saves the cache in the background. All saves requested within
+// a delay of the first one are coalesced into a single write. Snapshots of
+// the cache are taken with the locker held, while the file is written with
+// the locker released.
+type persister struct {
+	sync.Mutex
+	cch     *cache
+	locker  sync.Locker
+	delay   time.Duration
+	timer   *time.Timer
+	pending bool       // whether a save is pending
+	seq     uint64     // sequence number of the last snapshot
+	wlock   sync.Mutex // serializes writes
+	written uint64     // sequence number of the last written snapshot
+}
+
+func newPersister(cch *cache, locker sync.Locker, delay time.Duration) *persister {
+	return &persister{
+		cch:    cch,
+		locker: locker,
+		delay:  delay,
+	}
+}
+
+// request requests a save, unless one is already pending.
+func (p *persister) request() {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.pending {
+		return
+	}
+
+	p.pending = true
+	p.timer = time.AfterFunc(p.delay, p.save)
+}
+
+// save takes a snapshot of the cache and writes it, if a save is pending.
+func (p *persister) save() {
+	p.locker.Lock()
+	seq, ok := p.take()
+	if !ok {
+		p.locker.Unlock()
+		return
+	}
+	data, err := p.cch.Snapshot()
+	p.locker.Unlock()
+
+	if err != nil {
+		log.Error("failed to save cache: %v", err)
+		return
+	}
+	if err := p.write(seq, data); err != nil {
+		log.Error("failed to save cache: %v", err)
+	}
+}
+
+// flush writes any pending save immediately. The locker must be held by
+// the caller.
+func (p *persister) flush() error {
+	seq, ok := p.take()
+	if !ok {
+		return nil
+	}
+
+	data, err := p.cch.Snapshot()
+	if err != nil {
+		return cacheError("failed to save cache: %v", err)
+	}
+
+	return p.write(seq, data)
+}
+
+// take marks a pending save as being taken care of, allocating a sequence
+// number for its snapshot.
+func (p *persister) take() (uint64, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	if !p.pending {
+		return 0, false
+	}
+
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	p.pending = false
+	p.seq++
+
+	return p.seq, true
+}
+
+// write writes a snapshot, unless a more recent one has already been written.
+func (p *persister) write(seq uint64, data []byte) error {
+	p.wlock.Lock()
+	defer p.wlock.Unlock()
+
+	if seq < p.written {
+		return nil
+	}
+
+	if err := writeFileAtomic(p.cch.filePath, data, cacheFilePerm.prefer); err != nil {
+		return err
+	}
+	p.written = seq
+
+	return nil
+}
+
+// writeFileAtomic writes data to a file, replacing it atomically. The data
+// is synced to disk before the file is replaced.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath := path + ".saving"
+
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return cacheError("failed to open %q for writing: %v", tmpPath, err)
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return cacheError("failed to write cache to file %q: %v", tmpPath, err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return cacheError("failed to rename %q to %q: %v", tmpPath, path, err)
+	}
+
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		err = dir.Sync()
+		dir.Close()
+		if err != nil {
+			return cacheError("failed to sync directory of %q: %v", path, err)
+		}
+	}
+
+	return nil
+}
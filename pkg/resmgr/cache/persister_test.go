@@ -0,0 +1,137 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	nri "github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+)
+
+func TestAsyncSave(t *testing.T) {
+	_, err := cache.NewCache(cache.Options{CacheDir: t.TempDir(), SaveDelay: time.Second})
+	require.Error(t, err, "asynchronous saving without a locker")
+
+	lock := &sync.Mutex{}
+
+	// Saves are deferred until flushed.
+	dir := t.TempDir()
+	cch, err := cache.NewCache(cache.Options{CacheDir: dir, SaveDelay: time.Hour, Locker: lock})
+	require.NoError(t, err)
+
+	lock.Lock()
+	insertPod(t, cch, 0)
+	require.NoError(t, cch.Save())
+	lock.Unlock()
+	require.NoFileExists(t, filepath.Join(dir, "cache"))
+
+	lock.Lock()
+	require.NoError(t, cch.Flush())
+	lock.Unlock()
+	requireSavedPods(t, dir, 1)
+
+	// Saves are written once the delay expires.
+	dir = t.TempDir()
+	cch, err = cache.NewCache(cache.Options{CacheDir: dir, SaveDelay: 10 * time.Millisecond, Locker: lock})
+	require.NoError(t, err)
+
+	lock.Lock()
+	for i := 0; i < 3; i++ {
+		insertPod(t, cch, i)
+	}
+	lock.Unlock()
+	require.Eventually(t, func() bool {
+		saved, err := cache.NewCache(cache.Options{CacheDir: dir})
+		return err == nil && len(saved.GetPods()) == 3
+	}, time.Second, 5*time.Millisecond)
+}
+
+func insertPod(t testing.TB, cch cache.Cache, i int) {
+	id, uid := "pod"+strconv.Itoa(i), "uid"+strconv.Itoa(i)
+	cch.InsertPod(&nri.PodSandbox{
+		Id:        id,
+		Uid:       uid,
+		Name:      id,
+		Namespace: "default",
+		Linux: &nri.LinuxPodSandbox{
+			CgroupParent: "/kubepods/besteffort/pod" + uid,
+		},
+	}, nil)
+	_, err := cch.InsertContainer(&nri.Container{Id: id + "-ctr0", PodSandboxId: id, Name: "ctr0"})
+	require.NoError(t, err)
+}
+
+func loadPods(t *testing.T, dir string) []cache.Pod {
+	cch, err := cache.NewCache(cache.Options{CacheDir: dir})
+	require.NoError(t, err)
+	return cch.GetPods()
+}
+
+func requireSavedPods(t *testing.T, dir string, cnt int) {
+	require.FileExists(t, filepath.Join(dir, "cache"))
+	require.Len(t, loadPods(t, dir), cnt)
+}
+
+// BenchmarkPodStartup measures the latency of caching a new pod and its
+// container, as done when handling NRI requests, on a node already running
+// a number of pods.
+func BenchmarkPodStartup(b *testing.B) {
+	for _, pods := range []int{100, 500} {
+		for _, delay := range []time.Duration{0, time.Second} {
+			mode := "sync"
+			if delay != 0 {
+				mode = "async"
+			}
+			b.Run(fmt.Sprintf("%d-pods/%s", pods, mode), func(b *testing.B) {
+				lock := &sync.Mutex{}
+				cch, err := cache.NewCache(cache.Options{
+					CacheDir:  b.TempDir(),
+					SaveDelay: delay,
+					Locker:    lock,
+				})
+				require.NoError(b, err)
+
+				for i := 0; i < pods; i++ {
+					insertPod(b, cch, i)
+				}
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					lock.Lock()
+					insertPod(b, cch, pods)
+					lock.Unlock()
+
+					lock.Lock()
+					cch.DeleteContainer("pod" + strconv.Itoa(pods) + "-ctr0")
+					cch.DeletePod("pod" + strconv.Itoa(pods))
+					lock.Unlock()
+				}
+				b.StopTimer()
+
+				lock.Lock()
+				require.NoError(b, cch.Flush())
+				lock.Unlock()
+			})
+		}
+	}
+}
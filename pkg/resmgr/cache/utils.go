@@ -59,6 +59,20 @@ func estimateResourceRequirements(r *nri.LinuxResources, qosClass corev1.PodQOSC
 		resources.Limits[corev1.ResourceMemory] = *qty
 	}
 
+	// get hugepage limits, which are also the requests
+	for _, l := range r.GetHugepageLimits() {
+		if l.GetLimit() == 0 {
+			continue
+		}
+		name, ok := hugePageResourceName(l.GetPageSize())
+		if !ok {
+			continue
+		}
+		qty := resapi.NewQuantity(int64(l.GetLimit()), resapi.BinarySI)
+		resources.Limits[name] = *qty
+		resources.Requests[name] = *qty
+	}
+
 	// calculate CPU limit, set memory request if known
 	switch qosClass {
 	case corev1.PodQOSGuaranteed:
@@ -78,6 +92,31 @@ func estimateResourceRequirements(r *nri.LinuxResources, qosClass corev1.PodQOSC
 	return resources
 }
 
+// hugePageResourceName returns the resource name for an OCI hugepage size,
+// for instance hugepages-2Mi for 2MB.
+func hugePageResourceName(pageSize string) (corev1.ResourceName, bool) {
+	var unit int64
+
+	switch {
+	case strings.HasSuffix(pageSize, "KB"):
+		unit = 1 << 10
+	case strings.HasSuffix(pageSize, "MB"):
+		unit = 1 << 20
+	case strings.HasSuffix(pageSize, "GB"):
+		unit = 1 << 30
+	default:
+		return "", false
+	}
+
+	size, err := strconv.ParseInt(pageSize[:len(pageSize)-2], 10, 64)
+	if err != nil || size <= 0 {
+		return "", false
+	}
+
+	qty := resapi.NewQuantity(size*unit, resapi.BinarySI)
+	return corev1.ResourceName(corev1.ResourceHugePagesPrefix + qty.String()), true
+}
+
 // IsPodQOSClassName returns true if the given class is one of the Pod QOS classes.
 func IsPodQOSClassName(class string) bool {
 	switch corev1.PodQOSClass(class) {
@@ -131,24 +170,26 @@ func findContainerDir(podCgroupDir, podID, ID string) string {
 		return ""
 	}
 
-	cpusetDir := cgroups.Cpuset.Path()
-
-	dirs = []string{
-		path.Join(cpusetDir, podCgroupDir, ID),
-		// containerd, systemd
-		path.Join(cpusetDir, podCgroupDir, "cri-containerd-"+ID+".scope"),
-		// containerd, cgroupfs
-		path.Join(cpusetDir, podCgroupDir, "cri-containerd-"+ID),
-		// crio, systemd
-		path.Join(cpusetDir, podCgroupDir, "crio-"+ID+".scope"),
-		// crio, cgroupfs
-		path.Join(cpusetDir, podCgroupDir, "crio-"+ID),
-	}
+	// Look for the container in the cpuset controller hierarchy of
+	// cgroup v1 first, then in the unified cgroup v2 hierarchy.
+	for _, root := range []string{cgroups.Cpuset.Path(), cgroups.GetMountDir()} {
+		dirs = []string{
+			path.Join(root, podCgroupDir, ID),
+			// containerd, systemd
+			path.Join(root, podCgroupDir, "cri-containerd-"+ID+".scope"),
+			// containerd, cgroupfs
+			path.Join(root, podCgroupDir, "cri-containerd-"+ID),
+			// crio, systemd
+			path.Join(root, podCgroupDir, "crio-"+ID+".scope"),
+			// crio, cgroupfs
+			path.Join(root, podCgroupDir, "crio-"+ID),
+		}
 
-	for _, dir := range dirs {
-		if info, err := os.Stat(dir); err == nil {
-			if info.Mode().IsDir() {
-				return strings.TrimPrefix(dir, cpusetDir)
+		for _, dir := range dirs {
+			if info, err := os.Stat(dir); err == nil {
+				if info.Mode().IsDir() {
+					return strings.TrimPrefix(dir, root)
+				}
 			}
 		}
 	}
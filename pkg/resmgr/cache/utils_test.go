@@ -15,7 +15,13 @@
 package cache_test
 
 import (
+	"testing"
+
+	nri "github.com/containerd/nri/pkg/api"
 	. "github.com/onsi/ginkgo/v2"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	resapi "k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/containers/nri-plugins/pkg/kubernetes"
 	"github.com/containers/nri-plugins/pkg/resmgr/cache"
@@ -87,3 +93,37 @@ var _ = Describe("CPU limit", func() {
 		}
 	})
 })
+
+func TestHugePageRequirements(t *testing.T) {
+	cch, err := cache.NewCache(cache.Options{CacheDir: t.TempDir()})
+	require.NoError(t, err)
+
+	cch.InsertPod(&nri.PodSandbox{Id: "pod0", Uid: "uid0", Name: "pod0", Namespace: "default"}, nil)
+	c, err := cch.InsertContainer(&nri.Container{
+		Id:           "ctr0-id",
+		PodSandboxId: "pod0",
+		Name:         "ctr0",
+		Linux: &nri.LinuxContainer{
+			Resources: &nri.LinuxResources{
+				HugepageLimits: []*nri.HugepageLimit{
+					{PageSize: "2MB", Limit: 256 << 20},
+					{PageSize: "1GB", Limit: 0},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	req := c.GetResourceRequirements()
+	qty, ok := req.Requests[corev1.ResourceName("hugepages-2Mi")]
+	require.True(t, ok)
+	require.Equal(t, 0, qty.Cmp(resapi.MustParse("256Mi")))
+	require.Equal(t, qty, req.Limits[corev1.ResourceName("hugepages-2Mi")])
+	_, ok = req.Requests[corev1.ResourceName("hugepages-1Gi")]
+	require.False(t, ok)
+
+	require.False(t, c.SetResourceUpdates(&nri.LinuxResources{}))
+	upd, ok := c.GetResourceUpdates()
+	require.True(t, ok)
+	require.Equal(t, qty, upd.Requests[corev1.ResourceName("hugepages-2Mi")])
+}
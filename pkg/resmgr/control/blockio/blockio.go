@@ -0,0 +1,282 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blockio
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control"
+	cfgblockio "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control/blockio"
+	"github.com/containers/nri-plugins/pkg/cgroups"
+	logger "github.com/containers/nri-plugins/pkg/log"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/resmgr/control"
+)
+
+const (
+	// ConfigModuleName is the configuration section for the block I/O controller.
+	ConfigModuleName = "blockio"
+
+	// BlockIOController is the name of the block I/O controller.
+	BlockIOController = cache.BlockIO
+)
+
+// blockioctl encapsulates the runtime state of our block I/O enforcement/controller.
+type blockioctl struct {
+	cache   cache.Cache                             // resource manager cache
+	classes map[string]cgroups.OciBlockIOParameters // configured classes
+}
+
+type Class = cfgblockio.Class
+
+var log logger.Logger = logger.NewLogger(BlockIOController)
+
+// Controller singleton instance.
+var singleton *blockioctl
+
+// getBlockIOController returns the (singleton) block I/O controller instance.
+func getBlockIOController() *blockioctl {
+	if singleton == nil {
+		singleton = &blockioctl{}
+	}
+	return singleton
+}
+
+// Check if our configuration is effectively empty.
+func isEmptyConfig(cfg *cfgapi.Config) bool {
+	return cfg == nil || cfg.BlockIO == nil || len(cfg.BlockIO.Classes) == 0
+}
+
+// Start initializes the controller for enforcing decisions.
+func (ctl *blockioctl) Start(cache cache.Cache, cfg *cfgapi.Config) (bool, error) {
+	ctl.classes = nil
+
+	if isEmptyConfig(cfg) {
+		log.Info("empty configuration, disabling controller")
+		return false, nil
+	}
+
+	ctl.cache = cache
+
+	if err := ctl.configure(cfg.BlockIO.Classes); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Stop shuts down the controller.
+func (ctl *blockioctl) Stop() {
+}
+
+// PreCreateHook handler for the block I/O controller.
+func (ctl *blockioctl) PreCreateHook(c cache.Container) error {
+	return nil
+}
+
+// PreStartHook handler for the block I/O controller.
+func (ctl *blockioctl) PreStartHook(c cache.Container) error {
+	return nil
+}
+
+// PostStartHook handler for the block I/O controller.
+func (ctl *blockioctl) PostStartHook(c cache.Container) error {
+	return ctl.assign(c, false)
+}
+
+// PostUpdateHook handler for the block I/O controller.
+func (ctl *blockioctl) PostUpdateHook(c cache.Container) error {
+	if !c.HasPending(BlockIOController) {
+		return nil
+	}
+	return ctl.assign(c, true)
+}
+
+// PostStopHook handler for the block I/O controller.
+func (ctl *blockioctl) PostStopHook(c cache.Container) error {
+	return nil
+}
+
+// configure resolves the parameters of the given classes and applies them
+// to all running containers.
+func (ctl *blockioctl) configure(classes map[string]Class) error {
+	names := make([]string, 0, len(classes))
+	for name := range classes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var errs []error
+	params := make(map[string]cgroups.OciBlockIOParameters, len(classes))
+	for _, name := range names {
+		p, err := classParameters(classes[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("block I/O class %q: %w", name, err))
+			continue
+		}
+		params[name] = p
+		log.Info("block I/O class %q configured with parameters %+v", name, p)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	ctl.classes = params
+
+	for _, c := range ctl.cache.GetContainers() {
+		if c.GetState() != cache.ContainerStateRunning {
+			continue
+		}
+		if err := ctl.assign(c, false); err != nil {
+			log.Warn("%v", err)
+		}
+	}
+
+	return nil
+}
+
+// assign applies the parameters of the block I/O class of a container to
+// its cgroup. If the class is not configured, the cgroup is left alone
+// unless reset is true, in which case all device limits are removed.
+func (ctl *blockioctl) assign(c cache.Container, reset bool) error {
+	class := c.GetBlockIOClass()
+	params, ok := ctl.classes[class]
+	if !ok {
+		if !reset {
+			return nil
+		}
+		params = cgroups.NewOciBlockIOParameters()
+	}
+
+	dir := c.GetCgroupDir()
+	if dir == "" {
+		return fmt.Errorf("failed to assign %s to block I/O class %q: unknown cgroup directory",
+			c.PrettyName(), class)
+	}
+
+	var err error
+	if v2Dir := cgroups.FindV2Dir(dir); v2Dir != "" {
+		err = cgroups.ResetIOParameters(v2Dir, params)
+	} else {
+		err = cgroups.ResetBlkioParameters(string(cgroups.Blkio.Group(dir)), params)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to assign %s to block I/O class %q: %w", c.PrettyName(), class, err)
+	}
+
+	log.Debug("assigned %s to block I/O class %q", c.PrettyName(), class)
+
+	return nil
+}
+
+// classParameters resolves the cgroup block I/O parameters of a class.
+// Device parameters given later in the class override earlier ones.
+func classParameters(class Class) (cgroups.OciBlockIOParameters, error) {
+	params := cgroups.NewOciBlockIOParameters()
+	if class.Weight != 0 {
+		params.Weight = int64(class.Weight)
+	}
+
+	for _, dev := range class.Devices {
+		readBps, err := parseRate(dev.ReadBps)
+		if err != nil {
+			return params, err
+		}
+		writeBps, err := parseRate(dev.WriteBps)
+		if err != nil {
+			return params, err
+		}
+
+		for _, pattern := range dev.Paths {
+			paths, err := filepath.Glob(pattern)
+			if err != nil {
+				return params, fmt.Errorf("invalid device path %q: %w", pattern, err)
+			}
+			if len(paths) == 0 {
+				log.Warn("no block devices found matching %q", pattern)
+				continue
+			}
+
+			for _, path := range paths {
+				major, minor, err := blockDevice(path)
+				if err != nil {
+					log.Warn("ignoring %s: %v", path, err)
+					continue
+				}
+				if dev.Weight != 0 {
+					params.WeightDevice.Update(major, minor, int64(dev.Weight))
+				}
+				if readBps != 0 {
+					params.ThrottleReadBpsDevice.Update(major, minor, readBps)
+				}
+				if writeBps != 0 {
+					params.ThrottleWriteBpsDevice.Update(major, minor, writeBps)
+				}
+				if dev.ReadIOPS != 0 {
+					params.ThrottleReadIOPSDevice.Update(major, minor, int64(dev.ReadIOPS))
+				}
+				if dev.WriteIOPS != 0 {
+					params.ThrottleWriteIOPSDevice.Update(major, minor, int64(dev.WriteIOPS))
+				}
+			}
+		}
+	}
+
+	return params, nil
+}
+
+// parseRate parses a throttling rate given as a quantity, for instance 100Mi.
+func parseRate(rate string) (int64, error) {
+	if rate == "" {
+		return 0, nil
+	}
+	qty, err := resource.ParseQuantity(rate)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q: %w", rate, err)
+	}
+	if qty.Sign() < 0 {
+		return 0, fmt.Errorf("invalid negative rate %q", rate)
+	}
+	return qty.Value(), nil
+}
+
+// blockDevice returns the major and minor number of a block device.
+var blockDevice = func(path string) (int64, int64, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	if mode := fi.Mode(); mode&os.ModeDevice == 0 || mode&os.ModeCharDevice != 0 {
+		return 0, 0, fmt.Errorf("not a block device")
+	}
+	rdev := fi.Sys().(*syscall.Stat_t).Rdev
+	return int64(unix.Major(rdev)), int64(unix.Minor(rdev)), nil
+}
+
+// Register us as a controller.
+func init() {
+	err := control.Register(BlockIOController, "block I/O controller", getBlockIOController())
+	if err != nil {
+		log.Warnf("failed to register block I/O controller: %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blockio
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control"
+	cfgblockio "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control/blockio"
+	"github.com/containers/nri-plugins/pkg/cgroups"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache/cachetest"
+)
+
+// fakeDevices sets up fake block devices sda (8:0), sdb (8:16), and
+// nvme0n1 (259:0), and a fake character device tty0.
+func fakeDevices(t *testing.T) string {
+	dir := filepath.Join(t.TempDir(), "dev")
+	devices := map[string][2]int64{
+		"sda":     {8, 0},
+		"sdb":     {8, 16},
+		"nvme0n1": {259, 0},
+	}
+	cachetest.WriteFiles(t, dir, map[string]string{"sda": "", "sdb": "", "nvme0n1": "", "tty0": ""})
+
+	orig := blockDevice
+	blockDevice = func(path string) (int64, int64, error) {
+		dev, ok := devices[filepath.Base(path)]
+		if !ok {
+			return 0, 0, fmt.Errorf("not a block device")
+		}
+		return dev[0], dev[1], nil
+	}
+	t.Cleanup(func() { blockDevice = orig })
+
+	return dir
+}
+
+// fakeContainer creates a running Burstable container in a fake cgroup v2
+// hierarchy and returns its cgroup directory.
+func fakeContainer(t *testing.T) (cache.Cache, cache.Container, string) {
+	cch := cachetest.NewCache(t)
+	c, dir := cachetest.AddContainer(t, cch, "ctr0", map[string]string{
+		"io.weight": "",
+		"io.max":    "",
+	})
+	return cch, c, dir
+}
+
+func TestClassParameters(t *testing.T) {
+	dev := fakeDevices(t)
+
+	params, err := classParameters(Class{
+		Weight: 200,
+		Devices: []cfgblockio.Device{
+			{
+				Paths:     []string{filepath.Join(dev, "sd*"), filepath.Join(dev, "tty0")},
+				Weight:    50,
+				ReadBps:   "100Mi",
+				WriteIOPS: 1000,
+			},
+			{
+				Paths:    []string{filepath.Join(dev, "sdb"), filepath.Join(dev, "nvme*n1")},
+				ReadBps:  "200Mi",
+				WriteBps: "1G",
+				ReadIOPS: 2000,
+			},
+			{
+				Paths:  []string{filepath.Join(dev, "missing")},
+				Weight: 1000,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	expected := cgroups.NewOciBlockIOParameters()
+	expected.Weight = 200
+	expected.WeightDevice = cgroups.OciDeviceWeights{{Major: 8, Minor: 0, Weight: 50}, {Major: 8, Minor: 16, Weight: 50}}
+	expected.ThrottleReadBpsDevice = cgroups.OciDeviceRates{
+		{Major: 8, Minor: 0, Rate: 100 << 20},
+		{Major: 8, Minor: 16, Rate: 200 << 20},
+		{Major: 259, Minor: 0, Rate: 200 << 20},
+	}
+	expected.ThrottleWriteBpsDevice = cgroups.OciDeviceRates{
+		{Major: 8, Minor: 16, Rate: 1000000000},
+		{Major: 259, Minor: 0, Rate: 1000000000},
+	}
+	expected.ThrottleReadIOPSDevice = cgroups.OciDeviceRates{
+		{Major: 8, Minor: 16, Rate: 2000},
+		{Major: 259, Minor: 0, Rate: 2000},
+	}
+	expected.ThrottleWriteIOPSDevice = cgroups.OciDeviceRates{
+		{Major: 8, Minor: 0, Rate: 1000},
+		{Major: 8, Minor: 16, Rate: 1000},
+	}
+	require.Equal(t, expected, params)
+
+	_, err = classParameters(Class{
+		Devices: []cfgblockio.Device{{Paths: []string{"/dev/sda"}, ReadBps: "fast"}},
+	})
+	require.Error(t, err)
+}
+
+func TestBlockIOController(t *testing.T) {
+	dev := fakeDevices(t)
+	cch, c, cgroupDir := fakeContainer(t)
+
+	ctl := &blockioctl{}
+	enabled, err := ctl.Start(cch, &cfgapi.Config{})
+	require.NoError(t, err)
+	require.False(t, enabled)
+
+	enabled, err = ctl.Start(cch, &cfgapi.Config{
+		BlockIO: &cfgblockio.Config{
+			Classes: map[string]Class{
+				"Burstable": {
+					Weight: 500,
+					Devices: []cfgblockio.Device{
+						{
+							Paths:   []string{filepath.Join(dev, "sda")},
+							ReadBps: "10M",
+						},
+					},
+				},
+				"slow": {
+					Devices: []cfgblockio.Device{
+						{
+							Paths:    []string{filepath.Join(dev, "nvme0n1")},
+							WriteBps: "1M",
+						},
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, enabled)
+
+	// By default containers are assigned to the class of their pod QoS class.
+	require.Equal(t, "Burstable", c.GetBlockIOClass())
+	require.Equal(t, "default 4950", cachetest.ReadFile(t, cgroupDir, "io.weight"))
+	require.Equal(t, "8:0 rbps=10000000", cachetest.ReadFile(t, cgroupDir, "io.max"))
+
+	// Changing the class removes limits on devices not throttled by the new one.
+	cachetest.WriteFiles(t, cgroupDir, map[string]string{"io.max": "259:0 rbps=10000000 wbps=max riops=max wiops=max\n"})
+	c.SetBlockIOClass("slow")
+	require.NoError(t, ctl.PostUpdateHook(c))
+	require.Equal(t, "259:0 rbps=max wbps=1000000 riops=max wiops=max", cachetest.ReadFile(t, cgroupDir, "io.max"))
+	c.ClearPending(BlockIOController)
+
+	// Without a pending change updates leave the cgroup alone.
+	cachetest.WriteFiles(t, cgroupDir, map[string]string{"io.max": ""})
+	require.NoError(t, ctl.PostUpdateHook(c))
+	require.Equal(t, "", cachetest.ReadFile(t, cgroupDir, "io.max"))
+
+	require.NoError(t, ctl.PostStartHook(c))
+	require.Equal(t, "259:0 wbps=1000000", cachetest.ReadFile(t, cgroupDir, "io.max"))
+
+	// Unknown classes remove all limits.
+	cachetest.WriteFiles(t, cgroupDir, map[string]string{"io.max": "259:0 rbps=max wbps=1000000 riops=max wiops=max\n"})
+	c.SetBlockIOClass("unknown")
+	require.NoError(t, ctl.PostUpdateHook(c))
+	require.Equal(t, "259:0 rbps=max wbps=max riops=max wiops=max", cachetest.ReadFile(t, cgroupDir, "io.max"))
+}
@@ -0,0 +1,266 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdt
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control"
+	cfgrdt "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control/rdt"
+	"github.com/containers/nri-plugins/pkg/cgroups"
+	logger "github.com/containers/nri-plugins/pkg/log"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/resmgr/control"
+)
+
+const (
+	// ConfigModuleName is the configuration section for the RDT controller.
+	ConfigModuleName = "rdt"
+
+	// RDTController is the name of the RDT controller.
+	RDTController = cache.RDT
+)
+
+// rdtctl encapsulates the runtime state of our RDT enforcement/controller.
+type rdtctl struct {
+	cache   cache.Cache         // resource manager cache
+	resctrl *resctrl            // resctrl filesystem
+	classes map[string]Class    // configured RDT classes
+	groups  map[string]struct{} // resctrl groups we have configured
+}
+
+type Class = cfgrdt.Class
+
+var log logger.Logger = logger.NewLogger(RDTController)
+
+// Controller singleton instance.
+var singleton *rdtctl
+
+// getRDTController returns the (singleton) RDT controller instance.
+func getRDTController() *rdtctl {
+	if singleton == nil {
+		singleton = &rdtctl{}
+	}
+	return singleton
+}
+
+// Check if our configuration is effectively empty.
+func isEmptyConfig(cfg *cfgapi.Config) bool {
+	return cfg == nil || cfg.RDT == nil || len(cfg.RDT.Classes) == 0
+}
+
+// Start initializes the controller for enforcing decisions.
+func (ctl *rdtctl) Start(cache cache.Cache, cfg *cfgapi.Config) (bool, error) {
+	ctl.classes = nil
+
+	if isEmptyConfig(cfg) {
+		log.Info("empty configuration, disabling controller")
+		ctl.removeGroups(nil)
+		return false, nil
+	}
+
+	r, err := discoverResctrl(cfg.RDT.GetRoot())
+	if err != nil {
+		return false, fmt.Errorf("failed to discover resctrl: %w", err)
+	}
+
+	if ctl.resctrl != nil && ctl.resctrl.root != r.root {
+		ctl.removeGroups(nil)
+	}
+
+	ctl.cache = cache
+	ctl.resctrl = r
+
+	if err := ctl.configure(cfg.RDT.Classes); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Stop shuts down the controller. On reconfiguration Stop is followed by
+// Start, which removes the groups of classes no longer configured, or all
+// of them if the controller gets disabled. Groups of classes which stay
+// configured are kept to avoid moving their containers back and forth.
+func (ctl *rdtctl) Stop() {
+}
+
+// PreCreateHook handler for the RDT controller.
+func (ctl *rdtctl) PreCreateHook(c cache.Container) error {
+	return nil
+}
+
+// PreStartHook handler for the RDT controller.
+func (ctl *rdtctl) PreStartHook(c cache.Container) error {
+	return nil
+}
+
+// PostStartHook handler for the RDT controller.
+func (ctl *rdtctl) PostStartHook(c cache.Container) error {
+	return ctl.assign(c, false)
+}
+
+// PostUpdateHook handler for the RDT controller.
+func (ctl *rdtctl) PostUpdateHook(c cache.Container) error {
+	if !c.HasPending(RDTController) {
+		return nil
+	}
+	return ctl.assign(c, true)
+}
+
+// PostStopHook handler for the RDT controller.
+func (ctl *rdtctl) PostStopHook(c cache.Container) error {
+	return nil
+}
+
+// configure creates resctrl groups for the given classes and moves all
+// running containers to the groups of their classes.
+func (ctl *rdtctl) configure(classes map[string]Class) error {
+	names := make([]string, 0, len(classes))
+	for name := range classes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var errs []error
+	for _, name := range names {
+		if err := validateClassName(name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		schemata, err := ctl.resctrl.schemata(classes[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("RDT class %q: %w", name, err))
+			continue
+		}
+		if err := ctl.resctrl.createGroup(name, schemata); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ctl.groups == nil {
+			ctl.groups = map[string]struct{}{}
+		}
+		ctl.groups[name] = struct{}{}
+		log.Info("RDT class %q configured with schemata %q", name, schemata)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	ctl.classes = classes
+	ctl.removeGroups(classes)
+
+	for _, c := range ctl.cache.GetContainers() {
+		if c.GetState() != cache.ContainerStateRunning {
+			continue
+		}
+		if err := ctl.assign(c, false); err != nil {
+			log.Warn("%v", err)
+		}
+	}
+
+	return nil
+}
+
+// removeGroups removes the groups we have configured for classes which are
+// not among the given ones. The tasks of removed groups are moved back to
+// the root group.
+func (ctl *rdtctl) removeGroups(keep map[string]Class) {
+	for name := range ctl.groups {
+		if _, ok := keep[name]; ok {
+			continue
+		}
+		if err := ctl.resctrl.removeGroup(name); err != nil {
+			log.Warn("%v", err)
+			continue
+		}
+		delete(ctl.groups, name)
+		log.Info("removed resctrl group of RDT class %q", name)
+	}
+}
+
+// assign moves the tasks of a container to the group of its RDT class. If
+// the class is not configured, tasks are left alone unless reset is true,
+// in which case they are moved to the root group.
+func (ctl *rdtctl) assign(c cache.Container, reset bool) error {
+	class := ctl.containerClass(c)
+	if class == "" && !reset {
+		return nil
+	}
+
+	tasks, err := containerTasks(c)
+	if err != nil {
+		return fmt.Errorf("failed to get tasks of %s: %w", c.PrettyName(), err)
+	}
+
+	if err := ctl.resctrl.addTasks(class, tasks...); err != nil {
+		return fmt.Errorf("failed to assign %s to RDT class %q: %w", c.PrettyName(), class, err)
+	}
+
+	log.Debug("assigned %s to RDT class %q", c.PrettyName(), class)
+
+	return nil
+}
+
+// containerClass returns the configured RDT class of a container, or an
+// empty string if it has none.
+func (ctl *rdtctl) containerClass(c cache.Container) string {
+	class := c.GetRDTClass()
+	if class == cache.RDTClassPodQoS {
+		class = string(c.GetQOSClass())
+		if _, ok := ctl.classes[class]; !ok {
+			return ""
+		}
+		return class
+	}
+
+	if class == "" {
+		return ""
+	}
+	if _, ok := ctl.classes[class]; !ok {
+		log.Warn("%s: unknown RDT class %q", c.PrettyName(), class)
+		return ""
+	}
+
+	return class
+}
+
+// containerTasks returns the IDs of all threads of a container.
+func containerTasks(c cache.Container) ([]string, error) {
+	if dir := cgroups.FindV2Dir(c.GetCgroupDir()); dir != "" {
+		return cgroups.AsGroup(dir).GetThreads()
+	}
+	return c.GetTasks()
+}
+
+// validateClassName checks that a class name can be used as a group name.
+func validateClassName(name string) error {
+	if name == "" || name == "." || name == ".." || name == infoDir ||
+		strings.ContainsAny(name, "/\n") {
+		return fmt.Errorf("invalid RDT class name %q", name)
+	}
+	return nil
+}
+
+// Register us as a controller.
+func init() {
+	err := control.Register(RDTController, "RDT controller", getRDTController())
+	if err != nil {
+		log.Warnf("failed to register RDT controller: %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdt
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	cfgapi "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control"
+	cfgrdt "github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control/rdt"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache/cachetest"
+)
+
+// fakeResctrl sets up a fake resctrl filesystem with 11 L3 cache ways and
+// memory bandwidth allocation on two domains.
+func fakeResctrl(t *testing.T) string {
+	root := filepath.Join(t.TempDir(), "resctrl")
+	cachetest.WriteFiles(t, root, map[string]string{
+		"schemata":              "    L3:0=7ff;1=7ff\n    MB:0=100;1=100\n",
+		"tasks":                 "",
+		"info/L3/cbm_mask":      "7ff\n",
+		"info/L3/min_cbm_bits":  "2\n",
+		"info/MB/min_bandwidth": "10\n",
+	})
+	return root
+}
+
+// fakeContainer creates a running container with two threads in a fake
+// cgroup v2 hierarchy.
+func fakeContainer(t *testing.T) (cache.Cache, cache.Container) {
+	cch := cachetest.NewCache(t)
+	c, _ := cachetest.AddContainer(t, cch, "ctr0", map[string]string{
+		"cgroup.threads": "101\n102\n",
+	})
+	return cch, c
+}
+
+func TestRDTController(t *testing.T) {
+	root := fakeResctrl(t)
+	cch, c := fakeContainer(t)
+
+	ctl := &rdtctl{}
+	enabled, err := ctl.Start(cch, &cfgapi.Config{
+		RDT: &cfgrdt.Config{
+			Root: root,
+			Classes: map[string]Class{
+				"gold": {
+					L3Ways:     "0-3",
+					MBAPercent: 50,
+				},
+				"Burstable": {
+					MBAPercent: 20,
+				},
+				"bronze": {
+					L3Ways: "9-10",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, enabled)
+
+	require.Equal(t, "L3:0=f;1=f\nMB:0=50;1=50\n", cachetest.ReadFile(t, root, "gold/schemata"))
+	require.Equal(t, "MB:0=20;1=20\n", cachetest.ReadFile(t, root, "Burstable/schemata"))
+	require.Equal(t, "L3:0=600;1=600\n", cachetest.ReadFile(t, root, "bronze/schemata"))
+
+	// By default containers are assigned to the class of their pod QoS class.
+	require.Equal(t, cache.RDTClassPodQoS, c.GetRDTClass())
+	require.Equal(t, "101\n102\n", cachetest.ReadFile(t, root, "Burstable/tasks"))
+
+	c.SetRDTClass("gold")
+	require.NoError(t, ctl.PostUpdateHook(c))
+	require.Equal(t, "101\n102\n", cachetest.ReadFile(t, root, "gold/tasks"))
+	c.ClearPending(RDTController)
+
+	// Without a pending change updates leave tasks alone.
+	require.NoError(t, ctl.PostUpdateHook(c))
+	require.Equal(t, "", cachetest.ReadFile(t, root, "tasks"))
+
+	// Containers of unknown classes are moved back to the root group.
+	c.SetRDTClass("silver")
+	require.NoError(t, ctl.PostUpdateHook(c))
+	require.Equal(t, "101\n102\n", cachetest.ReadFile(t, root, "tasks"))
+	c.ClearPending(RDTController)
+
+	c.SetRDTClass("bronze")
+	require.NoError(t, ctl.PostStartHook(c))
+	require.Equal(t, "101\n102\n", cachetest.ReadFile(t, root, "bronze/tasks"))
+}
+
+func TestRDTControllerConfig(t *testing.T) {
+	cch, _ := fakeContainer(t)
+
+	for _, tc := range []struct {
+		name    string
+		classes map[string]Class
+		noL3    bool
+		enabled bool
+		fail    bool
+	}{
+		{
+			name:    "empty configuration",
+			enabled: false,
+		},
+		{
+			name:    "valid cache ways",
+			classes: map[string]Class{"a": {L3Ways: "9-10"}, "b": {L3Ways: "0-10"}},
+			enabled: true,
+		},
+		{
+			name:    "too many cache ways",
+			classes: map[string]Class{"a": {L3Ways: "0-11"}},
+			fail:    true,
+		},
+		{
+			name:    "too few cache ways",
+			classes: map[string]Class{"a": {L3Ways: "3-3"}},
+			fail:    true,
+		},
+		{
+			name:    "invalid cache ways",
+			classes: map[string]Class{"a": {L3Ways: "3-1"}},
+			fail:    true,
+		},
+		{
+			name:    "too little memory bandwidth",
+			classes: map[string]Class{"a": {MBAPercent: 5}},
+			fail:    true,
+		},
+		{
+			name:    "no cache allocation",
+			classes: map[string]Class{"a": {L3Ways: "0-3"}},
+			noL3:    true,
+			fail:    true,
+		},
+		{
+			name:    "invalid class name",
+			classes: map[string]Class{"info": {MBAPercent: 50}},
+			fail:    true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			root := fakeResctrl(t)
+			if tc.noL3 {
+				cachetest.WriteFiles(t, root, map[string]string{"schemata": "MB:0=100;1=100\n"})
+			}
+
+			cfg := &cfgapi.Config{}
+			if tc.classes != nil {
+				cfg.RDT = &cfgrdt.Config{Root: root, Classes: tc.classes}
+			}
+
+			enabled, err := (&rdtctl{}).Start(cch, cfg)
+			if tc.fail {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.enabled, enabled)
+		})
+	}
+
+	t.Run("resctrl not mounted", func(t *testing.T) {
+		_, err := (&rdtctl{}).Start(cch, &cfgapi.Config{
+			RDT: &cfgrdt.Config{
+				Root:    t.TempDir(),
+				Classes: map[string]Class{"a": {MBAPercent: 50}},
+			},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestRDTControllerReconfigure(t *testing.T) {
+	root := fakeResctrl(t)
+	cch, c := fakeContainer(t)
+	c.SetRDTClass("gold")
+
+	config := func(classes map[string]Class) *cfgapi.Config {
+		if classes == nil {
+			return &cfgapi.Config{}
+		}
+		return &cfgapi.Config{RDT: &cfgrdt.Config{Root: root, Classes: classes}}
+	}
+
+	ctl := &rdtctl{}
+	enabled, err := ctl.Start(cch, config(map[string]Class{
+		"gold":   {MBAPercent: 50},
+		"bronze": {MBAPercent: 10},
+	}))
+	require.NoError(t, err)
+	require.True(t, enabled)
+	require.Equal(t, "101\n102\n", cachetest.ReadFile(t, root, "gold/tasks"))
+
+	// Groups of removed classes are removed, moving their tasks to the root group.
+	ctl.Stop()
+	enabled, err = ctl.Start(cch, config(map[string]Class{
+		"bronze": {MBAPercent: 20},
+	}))
+	require.NoError(t, err)
+	require.True(t, enabled)
+	require.NoDirExists(t, filepath.Join(root, "gold"))
+	require.Equal(t, "101\n102\n", cachetest.ReadFile(t, root, "tasks"))
+	require.Equal(t, "MB:0=20;1=20\n", cachetest.ReadFile(t, root, "bronze/schemata"))
+
+	// Disabling the controller removes all of our groups.
+	cachetest.WriteFiles(t, root, map[string]string{"bronze/tasks": "103\n"})
+	ctl.Stop()
+	enabled, err = ctl.Start(cch, config(nil))
+	require.NoError(t, err)
+	require.False(t, enabled)
+	require.NoDirExists(t, filepath.Join(root, "bronze"))
+	require.Equal(t, "101\n102\n103\n", cachetest.ReadFile(t, root, "tasks"))
+}
@@ -0,0 +1,289 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdt
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// resctrl filesystem entries.
+const (
+	schemataEntry     = "schemata"
+	tasksEntry        = "tasks"
+	infoDir           = "info"
+	l3CbmMaskEntry    = "info/L3/cbm_mask"
+	l3MinCbmBitsEntry = "info/L3/min_cbm_bits"
+	mbMinBwEntry      = "info/MB/min_bandwidth"
+)
+
+// resctrl is a mounted resctrl filesystem.
+type resctrl struct {
+	root string  // mount point
+	l3   *l3Info // L3 cache allocation, nil if not available
+	mb   *mbInfo // memory bandwidth allocation, nil if not available
+}
+
+// l3Info describes L3 cache allocation capabilities.
+type l3Info struct {
+	cbmMask uint64   // mask of all allocatable cache ways
+	minBits int      // minimum number of ways in an allocation
+	domains []string // cache domain IDs
+}
+
+// mbInfo describes memory bandwidth allocation capabilities.
+type mbInfo struct {
+	minBandwidth uint     // minimum allocatable bandwidth in percents
+	domains      []string // memory bandwidth domain IDs
+}
+
+// discoverResctrl discovers the capabilities of resctrl mounted at root.
+func discoverResctrl(root string) (*resctrl, error) {
+	r := &resctrl{root: root}
+
+	if _, err := os.Stat(filepath.Join(root, infoDir)); err != nil {
+		return nil, fmt.Errorf("resctrl not mounted at %s: %w", root, err)
+	}
+
+	schemata, err := r.read(schemataEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(schemata, "\n") {
+		resource, domains, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		ids := []string{}
+		for _, domain := range strings.Split(domains, ";") {
+			if id, _, ok := strings.Cut(domain, "="); ok {
+				ids = append(ids, strings.TrimSpace(id))
+			}
+		}
+
+		switch resource {
+		case "L3":
+			if r.l3, err = r.discoverL3(ids); err != nil {
+				return nil, err
+			}
+		case "MB":
+			if r.mb, err = r.discoverMB(ids); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return r, nil
+}
+
+func (r *resctrl) discoverL3(domains []string) (*l3Info, error) {
+	data, err := r.read(l3CbmMaskEntry)
+	if err != nil {
+		return nil, err
+	}
+	mask, err := strconv.ParseUint(data, 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid L3 cache bitmask %q: %w", data, err)
+	}
+
+	minBits := 1
+	if data, err := r.read(l3MinCbmBitsEntry); err == nil {
+		if minBits, err = strconv.Atoi(data); err != nil {
+			return nil, fmt.Errorf("invalid L3 minimum cache bits %q: %w", data, err)
+		}
+	}
+
+	return &l3Info{
+		cbmMask: mask,
+		minBits: minBits,
+		domains: domains,
+	}, nil
+}
+
+func (r *resctrl) discoverMB(domains []string) (*mbInfo, error) {
+	minBw := uint64(0)
+	if data, err := r.read(mbMinBwEntry); err == nil {
+		if minBw, err = strconv.ParseUint(data, 10, 32); err != nil {
+			return nil, fmt.Errorf("invalid minimum memory bandwidth %q: %w", data, err)
+		}
+	}
+
+	return &mbInfo{
+		minBandwidth: uint(minBw),
+		domains:      domains,
+	}, nil
+}
+
+// schemata returns the resctrl schemata for a class.
+func (r *resctrl) schemata(class Class) (string, error) {
+	lines := []string{}
+
+	if class.L3Ways != "" {
+		if r.l3 == nil {
+			return "", fmt.Errorf("L3 cache allocation is not available")
+		}
+		mask, err := r.l3.waysToMask(class.L3Ways)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, schemataLine("L3", r.l3.domains, strconv.FormatUint(mask, 16)))
+	}
+
+	if class.MBAPercent != 0 {
+		if r.mb == nil {
+			return "", fmt.Errorf("memory bandwidth allocation is not available")
+		}
+		if class.MBAPercent < r.mb.minBandwidth || class.MBAPercent > 100 {
+			return "", fmt.Errorf("invalid memory bandwidth %d%%, should be within [%d, 100]",
+				class.MBAPercent, r.mb.minBandwidth)
+		}
+		lines = append(lines, schemataLine("MB", r.mb.domains, strconv.FormatUint(uint64(class.MBAPercent), 10)))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// waysToMask converts a range of cache ways to a cache bitmask.
+func (l3 *l3Info) waysToMask(ways string) (uint64, error) {
+	lo, hi, err := parseRange(ways)
+	if err != nil {
+		return 0, fmt.Errorf("invalid L3 cache ways %q: %w", ways, err)
+	}
+
+	total := bits.Len64(l3.cbmMask)
+	if hi >= total {
+		return 0, fmt.Errorf("invalid L3 cache ways %q, only %d ways available", ways, total)
+	}
+	if n := hi - lo + 1; n < l3.minBits {
+		return 0, fmt.Errorf("invalid L3 cache ways %q, at least %d ways needed", ways, l3.minBits)
+	}
+
+	return ((uint64(1) << (hi - lo + 1)) - 1) << lo, nil
+}
+
+// parseRange parses a single number or a range of numbers, for instance 0-3.
+func parseRange(s string) (int, int, error) {
+	first, last, isRange := strings.Cut(s, "-")
+	lo, err := strconv.Atoi(first)
+	if err != nil {
+		return 0, 0, err
+	}
+	hi := lo
+	if isRange {
+		if hi, err = strconv.Atoi(last); err != nil {
+			return 0, 0, err
+		}
+	}
+	if lo < 0 || hi < lo {
+		return 0, 0, fmt.Errorf("invalid range")
+	}
+	return lo, hi, nil
+}
+
+// schemataLine returns a schemata line setting value for all domains.
+func schemataLine(resource string, domains []string, value string) string {
+	entries := make([]string, 0, len(domains))
+	for _, id := range domains {
+		entries = append(entries, id+"="+value)
+	}
+	return resource + ":" + strings.Join(entries, ";")
+}
+
+// groupDir returns the directory of a group, the root one for an empty name.
+func (r *resctrl) groupDir(group string) string {
+	return filepath.Join(r.root, group)
+}
+
+// createGroup creates a group if necessary and updates its schemata.
+func (r *resctrl) createGroup(group, schemata string) error {
+	dir := r.groupDir(group)
+	if err := os.Mkdir(dir, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("failed to create resctrl group %q: %w", group, err)
+	}
+
+	if schemata == "" {
+		return nil
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, schemataEntry), []byte(schemata+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write schemata of resctrl group %q: %w", group, err)
+	}
+
+	return nil
+}
+
+// removeGroup moves the tasks of a group to the root group and removes it.
+func (r *resctrl) removeGroup(group string) error {
+	tasks, err := r.read(filepath.Join(group, tasksEntry))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := r.addTasks("", strings.Fields(tasks)...); err != nil {
+		return err
+	}
+
+	dir := r.groupDir(group)
+	err = os.Remove(dir)
+	if errors.Is(err, syscall.ENOTEMPTY) {
+		// The kernel removes the entries of a group with it. We remove
+		// them first, to be able to test against a plain directory tree.
+		for _, entry := range []string{schemataEntry, tasksEntry} {
+			_ = os.Remove(filepath.Join(dir, entry))
+		}
+		err = os.Remove(dir)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove resctrl group %q: %w", group, err)
+	}
+
+	return nil
+}
+
+// addTasks moves the given tasks to a group.
+func (r *resctrl) addTasks(group string, tasks ...string) error {
+	// Tasks need to be written one by one. The kernel creates the tasks
+	// entry for a group, but we create it if missing, to be able to test
+	// against a plain directory tree.
+	path := filepath.Join(r.groupDir(group), tasksEntry)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open tasks of resctrl group %q: %w", group, err)
+	}
+	defer f.Close()
+
+	for _, task := range tasks {
+		if _, err := f.Write([]byte(task + "\n")); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return fmt.Errorf("failed to move task %s to resctrl group %q: %w", task, group, err)
+		}
+	}
+
+	return nil
+}
+
+// read reads the given resctrl entry.
+func (r *resctrl) read(entry string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(r.root, entry))
+	if err != nil {
+		return "", fmt.Errorf("failed to read resctrl %s: %w", entry, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
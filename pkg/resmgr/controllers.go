@@ -16,6 +16,8 @@ package resmgr
 
 import (
 	// List of controllers to pull in.
+	_ "github.com/containers/nri-plugins/pkg/resmgr/control/blockio"
 	_ "github.com/containers/nri-plugins/pkg/resmgr/control/cpu"
 	_ "github.com/containers/nri-plugins/pkg/resmgr/control/e2e-test"
+	_ "github.com/containers/nri-plugins/pkg/resmgr/control/rdt"
 )
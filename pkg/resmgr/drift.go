@@ -0,0 +1,330 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resmgr
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/containers/nri-plugins/pkg/apis/config/v1alpha1/resmgr/control/drift"
+	"github.com/containers/nri-plugins/pkg/cgroups"
+	"github.com/containers/nri-plugins/pkg/cgroupstats"
+	"github.com/containers/nri-plugins/pkg/metrics"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/resmgr/events"
+	"github.com/containers/nri-plugins/pkg/utils/cpuset"
+)
+
+const (
+	driftCpus = "cpus"
+	driftMems = "mems"
+)
+
+var (
+	cpusetDriftDesc = prometheus.NewDesc(
+		"container_cpuset_drift",
+		"Containers with a cpuset different from the assigned one.",
+		cgroupstats.WithContainerLabels("resource", "expected", "actual"),
+		nil,
+	)
+	driftDetectedDesc = prometheus.NewDesc(
+		"cpuset_drift_detected_total",
+		"Number of times cpuset drift has been detected.",
+		[]string{"resource"},
+		nil,
+	)
+	driftRepairsDesc = prometheus.NewDesc(
+		"cpuset_drift_repairs_total",
+		"Number of containers with their assigned cpuset restored.",
+		nil,
+		nil,
+	)
+)
+
+// driftDetector periodically compares the cpusets of running containers
+// with the ones assigned to them in the cache. It reports any difference
+// as metrics and events, and optionally restores the assigned cpusets.
+type driftDetector struct {
+	sync.Mutex
+	m        *resmgr
+	update   func() error
+	stop     chan struct{}
+	drifted  map[string]*containerDrift
+	detected map[string]int
+	repairs  int
+}
+
+// containerDrift describes the drift of a single container.
+type containerDrift struct {
+	labels  []string
+	entries []*cpusetDrift
+}
+
+// cpusetDrift describes the drift of the CPUs or memory nodes of a container.
+type cpusetDrift struct {
+	resource string
+	expected string
+	actual   string
+}
+
+func newDriftDetector(m *resmgr) *driftDetector {
+	return &driftDetector{
+		m: m,
+		update: func() error {
+			return m.nri.updateContainers()
+		},
+		drifted:  map[string]*containerDrift{},
+		detected: map[string]int{},
+	}
+}
+
+// setupDriftDetection creates the drift detector and registers its metrics.
+func (m *resmgr) setupDriftDetection() {
+	m.drift = newDriftDetector(m)
+
+	err := metrics.Register(
+		"drift",
+		m.drift,
+		metrics.WithGroup("policy"),
+	)
+	if err != nil {
+		log.Error("failed to register policy/drift collector: %v", err)
+	}
+}
+
+// configure (re)starts drift detection with the given configuration,
+// stopping it if the configuration is nil.
+func (d *driftDetector) configure(cfg *drift.Config) {
+	if d.stop != nil {
+		close(d.stop)
+		d.stop = nil
+	}
+
+	if cfg == nil {
+		d.Lock()
+		d.drifted = map[string]*containerDrift{}
+		d.Unlock()
+		return
+	}
+
+	log.Info("checking cpusets for drift every %s (repair: %v)", cfg.GetInterval(), cfg.Repair)
+
+	d.stop = make(chan struct{})
+	go d.run(cfg.GetInterval(), cfg.Repair, d.stop)
+}
+
+func (d *driftDetector) run(interval time.Duration, repair bool, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			d.check(repair)
+		}
+	}
+}
+
+// check checks all running containers for drift, restoring the assigned
+// cpusets if repair is set.
+func (d *driftDetector) check(repair bool) {
+	b := blockMetrics()
+	defer b.Done()
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	var (
+		drifted  = map[string]*containerDrift{}
+		repaired = 0
+	)
+
+	for _, c := range d.m.cache.GetContainers() {
+		if c.GetState() != cache.ContainerStateRunning {
+			continue
+		}
+
+		entries := checkCpusetDrift(c)
+		if len(entries) == 0 {
+			continue
+		}
+
+		cd := &containerDrift{
+			labels:  cgroupstats.ContainerLabelValues(c),
+			entries: entries,
+		}
+		drifted[c.GetID()] = cd
+
+		if prev, ok := d.drifted[c.GetID()]; !ok || prev.String() != cd.String() {
+			log.Warn("%s: cpuset drift detected: %s", c.PrettyName(), cd)
+			d.Lock()
+			for _, e := range entries {
+				d.detected[e.resource]++
+			}
+			d.Unlock()
+			d.sendEvent(c, repair, cd)
+		}
+
+		if repair {
+			for _, e := range entries {
+				switch e.resource {
+				case driftCpus:
+					c.SetCpusetCpus(e.expected)
+				case driftMems:
+					c.SetCpusetMems(e.expected)
+				}
+			}
+			repaired++
+		}
+	}
+
+	if repaired > 0 {
+		log.Info("restoring the assigned cpusets of %d containers...", repaired)
+		if err := d.update(); err != nil {
+			log.Error("failed to restore drifted cpusets: %v", err)
+			repaired = 0
+		}
+	}
+
+	d.Lock()
+	d.drifted = drifted
+	d.repairs += repaired
+	d.Unlock()
+}
+
+// checkCpusetDrift compares the cpuset of the container with the one
+// assigned to it.
+func checkCpusetDrift(c cache.Container) []*cpusetDrift {
+	dir := c.GetCgroupDir()
+	if dir == "" {
+		return nil
+	}
+
+	group := cgroups.FindV2Dir(dir)
+	if group == "" {
+		group = string(cgroups.Cpuset.Group(dir))
+	}
+
+	cpus, mems, err := cgroups.GetCpuset(group)
+	if err != nil {
+		log.Debug("%s: failed to read cpuset: %v", c.PrettyName(), err)
+		return nil
+	}
+
+	entries := []*cpusetDrift{}
+	for _, r := range []struct {
+		resource string
+		expected string
+		actual   string
+	}{
+		{driftCpus, c.GetCpusetCpus(), cpus},
+		{driftMems, c.GetCpusetMems(), mems},
+	} {
+		if r.expected == "" {
+			continue
+		}
+		expected, err := cpuset.Parse(r.expected)
+		if err != nil {
+			continue
+		}
+		actual, err := cpuset.Parse(r.actual)
+		if err != nil || !expected.Equals(actual) {
+			entries = append(entries, &cpusetDrift{
+				resource: r.resource,
+				expected: r.expected,
+				actual:   r.actual,
+			})
+		}
+	}
+
+	return entries
+}
+
+func (cd *containerDrift) String() string {
+	entries := make([]string, 0, len(cd.entries))
+	for _, e := range cd.entries {
+		entries = append(entries, fmt.Sprintf("cpuset.%s is %q instead of %q",
+			e.resource, e.actual, e.expected))
+	}
+	return strings.Join(entries, ", ")
+}
+
+// sendEvent reports drift of a container as a Kubernetes Event.
+func (d *driftDetector) sendEvent(c cache.Container, repair bool, cd *containerDrift) {
+	pod, ok := c.GetPod()
+	if !ok {
+		return
+	}
+
+	msg := "container " + c.GetName() + ": " + cd.String()
+	if repair {
+		msg += ", restoring it"
+	}
+
+	e := &events.Pod{
+		Namespace: pod.GetNamespace(),
+		Name:      pod.GetName(),
+		UID:       pod.GetUID(),
+		Type:      events.Warning,
+		Reason:    events.CpusetDrift,
+		Message:   msg,
+	}
+	if err := d.m.SendEvent(e); err != nil {
+		log.Warn("failed to send %s event for %s: %v", e.Reason, c.PrettyName(), err)
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (d *driftDetector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cpusetDriftDesc
+	ch <- driftDetectedDesc
+	ch <- driftRepairsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (d *driftDetector) Collect(ch chan<- prometheus.Metric) {
+	d.Lock()
+	defer d.Unlock()
+
+	for _, cd := range d.drifted {
+		for _, e := range cd.entries {
+			ch <- prometheus.MustNewConstMetric(
+				cpusetDriftDesc,
+				prometheus.GaugeValue,
+				1,
+				append(slices.Clip(cd.labels), e.resource, e.expected, e.actual)...,
+			)
+		}
+	}
+	for _, resource := range []string{driftCpus, driftMems} {
+		ch <- prometheus.MustNewConstMetric(
+			driftDetectedDesc,
+			prometheus.CounterValue,
+			float64(d.detected[resource]),
+			resource,
+		)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		driftRepairsDesc,
+		prometheus.CounterValue,
+		float64(d.repairs),
+	)
+}
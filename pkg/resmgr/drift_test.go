@@ -0,0 +1,95 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resmgr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/containers/nri-plugins/pkg/cgroups"
+)
+
+func TestDriftDetection(t *testing.T) {
+	dir := t.TempDir()
+
+	mountDir := cgroups.GetMountDir()
+	cgroups.SetMountDir(filepath.Join(dir, "cgroup"))
+	t.Cleanup(func() { cgroups.SetMountDir(mountDir) })
+
+	m := newIntrospectionTestResmgr(t, &mockPolicy{name: "test"})
+
+	// Drain the pending updates of setting up the cpusets.
+	for _, c := range m.cache.GetPendingContainers() {
+		c.GetPendingUpdate()
+		for _, ctrl := range c.GetPending() {
+			c.ClearPending(ctrl)
+		}
+	}
+
+	for id, cpus := range map[string]string{"ctr0-id": "0-1", "ctr1-id": "0-7"} {
+		cgroupDir := filepath.Join(dir, "cgroup", "kubepods/burstable/poduid0", id)
+		require.NoError(t, os.MkdirAll(cgroupDir, 0755))
+		for name, data := range map[string]string{
+			"cgroup.controllers": "cpuset cpu memory\n",
+			"cpuset.cpus":        cpus + "\n",
+			"cpuset.mems":        "0\n",
+		} {
+			require.NoError(t, os.WriteFile(filepath.Join(cgroupDir, name), []byte(data), 0644))
+		}
+	}
+
+	updated := []string{}
+	d := newDriftDetector(m)
+	d.update = func() error {
+		for _, c := range m.cache.GetPendingContainers() {
+			u := c.GetPendingUpdate()
+			require.NotNil(t, u)
+			require.Equal(t, "0-1", u.GetLinux().GetResources().GetCpu().GetCpus())
+			updated = append(updated, c.GetID())
+		}
+		return nil
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(d))
+
+	// Detect drift without repairing it, reporting it only once.
+	d.check(false)
+	d.check(false)
+	require.Empty(t, updated)
+	require.Len(t, d.drifted, 1)
+	require.Contains(t, d.drifted, "ctr1-id")
+	require.Equal(t, 1, d.detected[driftCpus])
+	require.Equal(t, 0, d.detected[driftMems])
+	require.Equal(t, 1, testutil.CollectAndCount(d, "container_cpuset_drift"))
+
+	// Repair the drift.
+	d.check(true)
+	require.Equal(t, []string{"ctr1-id"}, updated)
+	require.Equal(t, 1, d.repairs)
+
+	// No drift left once the cpuset is restored.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cgroup", "kubepods/burstable/poduid0",
+		"ctr1-id", "cpuset.cpus"), []byte("0-1\n"), 0644))
+	d.check(true)
+	require.Empty(t, d.drifted)
+	require.Equal(t, 1, d.repairs)
+	require.Equal(t, 0, testutil.CollectAndCount(d, "container_cpuset_drift"))
+}
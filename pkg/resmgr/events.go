@@ -16,6 +16,7 @@ package resmgr
 
 import (
 	logger "github.com/containers/nri-plugins/pkg/log"
+	"github.com/containers/nri-plugins/pkg/resmgr/events"
 )
 
 // Our logger instance for events.
@@ -67,9 +68,46 @@ func (m *resmgr) processEvent(e interface{}) {
 	switch event := e.(type) {
 	case string:
 		evtlog.Debug("'%s'...", event)
-		//case *events.Policy:
-		//m.DeliverPolicyEvent(event)
+	case *events.Policy:
+		if err := m.deliverPolicyEvent(event); err != nil {
+			evtlog.Error("%v", err)
+		}
+	case *events.Pod:
+		m.postPodEvent(event)
 	default:
 		evtlog.Warn("event of unexpected type %T...", e)
 	}
 }
+
+// deliverPolicyEvent delivers the given event to the active policy.
+func (m *resmgr) deliverPolicyEvent(e *events.Policy) error {
+	b := blockMetrics()
+	defer b.Done()
+	m.Lock()
+	defer m.Unlock()
+
+	changed, err := m.policy.HandleEvent(e)
+	if changed {
+		if err := m.nri.updateContainers(); err != nil {
+			evtlog.Error("failed to update containers after event %s: %v", e.Type, err)
+		}
+		m.updateTopologyZones()
+	}
+
+	if err != nil {
+		return resmgrError("policy failed to handle event %s: %v", e.Type, err)
+	}
+
+	return nil
+}
+
+// postPodEvent posts a Kubernetes Event about a pod.
+func (m *resmgr) postPodEvent(e *events.Pod) {
+	if m.agent == nil {
+		return
+	}
+	if err := m.agent.PostPodEvent(e); err != nil {
+		evtlog.Error("failed to post %s event for pod %s/%s: %v", e.Reason,
+			e.Namespace, e.Name, err)
+	}
+}
@@ -28,7 +28,68 @@ type Policy struct {
 	Data interface{}
 }
 
+// Pod is an event about a pod, to be reported as a Kubernetes Event.
+type Pod struct {
+	// Namespace is the namespace of the pod.
+	Namespace string
+	// Name is the name of the pod.
+	Name string
+	// UID is the Kubernetes UID of the pod.
+	UID string
+	// Type is the type of the event, Normal or Warning.
+	Type string
+	// Reason is the reason for the event.
+	Reason string
+	// Message is a human-readable description of the event.
+	Message string
+}
+
+// Types of Kubernetes Events.
+const (
+	// Normal is the type of informational events.
+	Normal = "Normal"
+	// Warning is the type of events about problems.
+	Warning = "Warning"
+)
+
+// Reasons for Kubernetes Events.
+const (
+	// InsufficientExclusiveCPUs is the reason for running a container
+	// which requested exclusive CPUs on shared ones, or failing to
+	// allocate exclusive CPUs for it.
+	InsufficientExclusiveCPUs = "InsufficientExclusiveCPUs"
+	// InsufficientIsolatedCPUs is the reason for allocating normal
+	// exclusive CPUs for a container which prefers isolated ones.
+	InsufficientIsolatedCPUs = "InsufficientIsolatedCPUs"
+	// InsufficientReservedCPUs is the reason for allocating normal
+	// CPUs for a container which should run on reserved ones.
+	InsufficientReservedCPUs = "InsufficientReservedCPUs"
+	// MemoryTypeUnavailable is the reason for not being able to allocate
+	// the requested type of memory for a container.
+	MemoryTypeUnavailable = "MemoryTypeUnavailable"
+	// InsufficientHugePages is the reason for not being able to allocate
+	// hugepages for a container from the NUMA nodes close to its CPUs.
+	InsufficientHugePages = "InsufficientHugePages"
+	// BalloonLimitReached is the reason for failing to allocate a balloon
+	// for a container because the maximum number of balloons is reached.
+	BalloonLimitReached = "BalloonLimitReached"
+	// AllocationFailed is the reason for failing to allocate resources
+	// for a container for any other reason.
+	AllocationFailed = "AllocationFailed"
+	// Rebalanced is the reason for moving a container to other resources
+	// when the policy was requested to rebalance.
+	Rebalanced = "Rebalanced"
+	// ConfigRejected is the reason for rejecting a configuration.
+	ConfigRejected = "ConfigRejected"
+	// CpusetDrift is the reason for finding the cpuset of a container
+	// changed from the one assigned to it.
+	CpusetDrift = "CpusetDrift"
+)
+
 const (
 	// ContainerStarted is delivered to policies when a StartContainer request succeeds.
 	ContainerStarted = "container-started"
+	// Rebalance is delivered to policies to request reallocating resources
+	// of all containers.
+	Rebalance = "rebalance"
 )
@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/containerd/nri/pkg/api"
+	"github.com/containers/nri-plugins/pkg/kubernetes"
 	"github.com/containers/nri-plugins/pkg/pidfile"
 )
 
@@ -27,15 +28,32 @@ const (
 	defaultPluginIndex = "90"
 )
 
+// Restart modes, how running containers are handled when we (re)start.
+const (
+	// RestartModeReallocate reallocates resources for all running containers.
+	RestartModeReallocate = "reallocate"
+	// RestartModeAdopt keeps the current cpusets of running containers if
+	// they are valid, reallocating resources only for the other containers.
+	RestartModeAdopt = "adopt"
+)
+
 // Options captures our command line parameters.
 type options struct {
-	HostRoot      string
-	StateDir      string
-	PidFile       string
-	MetricsTimer  time.Duration
-	NriPluginName string
-	NriPluginIdx  string
-	NriSocket     string
+	HostRoot       string
+	StateDir       string
+	PidFile        string
+	MetricsTimer   time.Duration
+	NriPluginName  string
+	NriPluginIdx   string
+	NriSocket      string
+	NriRecord      string
+	NriReplay      string
+	RestartMode    string
+	Stateless      bool
+	StateSaveDelay time.Duration
+	ImportKubelet  bool
+	KubeletDir     string
+	ActionsSocket  string
 }
 
 // ResourceManager command line options.
@@ -51,6 +69,15 @@ func init() {
 		"NRI plugin index to register.")
 	flag.StringVar(&opt.NriSocket, "nri-socket", api.DefaultSocketPath,
 		"NRI unix domain socket path to connect to.")
+	flag.StringVar(&opt.NriRecord, "nri-record", "",
+		"Record NRI requests and the responses given to them to this file.")
+	flag.StringVar(&opt.NriReplay, "nri-replay", "",
+		"Replay NRI requests from this recording instead of connecting to the runtime,\n"+
+			"compare the responses to the recorded ones, then exit.")
+
+	flag.StringVar(&opt.RestartMode, "restart-mode", RestartModeReallocate,
+		"How to handle running containers on startup: '"+RestartModeReallocate+"' their resources,\n"+
+			"or '"+RestartModeAdopt+"' their current cpusets if valid and reallocate only the rest.")
 
 	flag.StringVar(&opt.PidFile, "pid-file", pidfile.GetPath(),
 		"PID file to write daemon PID to")
@@ -59,4 +86,19 @@ func init() {
 			"Use the instrumentation section of the CR-based configuration interface instead.")
 	flag.StringVar(&opt.StateDir, "state-dir", "/var/lib/nri-resource-policy",
 		"Permanent storage directory path for the resource manager to store its state in.")
+	flag.BoolVar(&opt.Stateless, "stateless", false,
+		"Don't save state to disk, rebuild it from the runtime on startup instead.\n"+
+			"Requires --restart-mode="+RestartModeAdopt+".")
+	flag.DurationVar(&opt.StateSaveDelay, "state-save-delay", time.Second,
+		"Save state to disk asynchronously, coalescing changes within this delay.\n"+
+			"Use 0 to save state synchronously on every change.")
+	flag.BoolVar(&opt.ImportKubelet, "import-kubelet-state", false,
+		"Adopt the CPUs and memory nodes assigned to running containers by the kubelet\n"+
+			"CPU and memory managers when starting without saved state.\n"+
+			"Can't be used with --stateless.")
+	flag.StringVar(&opt.KubeletDir, "kubelet-dir", kubernetes.KubeletDir,
+		"Kubelet root directory with the CPU and memory manager state, under --host-root.")
+	flag.StringVar(&opt.ActionsSocket, "actions-socket", "",
+		"Serve debugging actions, like rebalancing containers, on this unix domain socket.\n"+
+			"Actions are disabled by default.")
 }
@@ -0,0 +1,157 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package introspect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the default timeout for client requests.
+	DefaultTimeout = 10 * time.Second
+)
+
+// Client is a client for the introspection and action endpoints.
+type Client struct {
+	endpoint string
+	http     *http.Client
+}
+
+// NewClient creates a client for the HTTP server at the given endpoint,
+// for instance http://localhost:8891 or :8891, or on the given unix domain
+// socket, for instance unix:///var/run/nri-resource-policy/actions.sock.
+func NewClient(endpoint string, timeout time.Duration) *Client {
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	cli := &http.Client{Timeout: timeout}
+
+	if socket, ok := strings.CutPrefix(endpoint, "unix://"); ok {
+		cli.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		}
+		endpoint = "http://localhost"
+	}
+	if strings.HasPrefix(endpoint, ":") {
+		endpoint = "localhost" + endpoint
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+
+	return &Client{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		http:     cli,
+	}
+}
+
+// GetPods returns the pods known to the resource manager.
+func (c *Client) GetPods() ([]*Pod, error) {
+	pods := []*Pod{}
+	if err := c.get(PodsPath, &pods); err != nil {
+		return nil, err
+	}
+	return pods, nil
+}
+
+// GetContainers returns the containers known to the resource manager.
+func (c *Client) GetContainers() ([]*Container, error) {
+	containers := []*Container{}
+	if err := c.get(ContainersPath, &containers); err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
+// GetPools returns the pools of the active policy.
+func (c *Client) GetPools() ([]*Pool, error) {
+	pools := []*Pool{}
+	if err := c.get(PoolsPath, &pools); err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// GetDecisions returns the last placement decisions for containers.
+func (c *Client) GetDecisions() ([]*Decision, error) {
+	decisions := []*Decision{}
+	if err := c.get(DecisionsPath, &decisions); err != nil {
+		return nil, err
+	}
+	return decisions, nil
+}
+
+// GetRaw returns the unparsed reply from an introspection endpoint.
+func (c *Client) GetRaw(path string) ([]byte, error) {
+	return c.do(http.MethodGet, path)
+}
+
+// Rebalance requests the active policy to reallocate resources for all
+// containers.
+func (c *Client) Rebalance() error {
+	_, err := c.do(http.MethodPost, RebalancePath)
+	return err
+}
+
+// SaveCache requests the resource manager to save its cache.
+func (c *Client) SaveCache() error {
+	_, err := c.do(http.MethodPost, SaveCachePath)
+	return err
+}
+
+func (c *Client) get(path string, obj interface{}) error {
+	data, err := c.do(http.MethodGet, path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, obj); err != nil {
+		return fmt.Errorf("failed to parse reply from %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Client) do(method, path string) ([]byte, error) {
+	req, err := http.NewRequest(method, c.endpoint+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", path, err)
+	}
+
+	rpl, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request %s %s failed: %w", method, path, err)
+	}
+	defer rpl.Body.Close()
+
+	data, err := io.ReadAll(rpl.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reply from %s: %w", path, err)
+	}
+
+	if rpl.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request %s %s failed: %s: %s", method, path, rpl.Status,
+			strings.TrimSpace(string(data)))
+	}
+
+	return data, nil
+}
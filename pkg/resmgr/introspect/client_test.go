@@ -0,0 +1,71 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package introspect
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	actions := []string{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(ContainersPath, func(w http.ResponseWriter, _ *http.Request) {
+		require.NoError(t, json.NewEncoder(w).Encode([]*Container{
+			{ID: "ctr0-id", Name: "ctr0", CPUs: "0-1", Mems: "0", Pool: "default[0]"},
+		}))
+	})
+	mux.HandleFunc(PoolsPath, func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "policy template does not support pool introspection", http.StatusNotFound)
+	})
+	mux.HandleFunc(ActionPrefix, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		actions = append(actions, req.URL.Path)
+		w.Write([]byte("ok"))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cli := NewClient(srv.URL+"/", 0)
+
+	containers, err := cli.GetContainers()
+	require.NoError(t, err)
+	require.Len(t, containers, 1)
+	require.Equal(t, "ctr0", containers[0].Name)
+	require.Equal(t, "default[0]", containers[0].Pool)
+
+	_, err = cli.GetPools()
+	require.ErrorContains(t, err, "does not support pool introspection")
+
+	require.NoError(t, cli.Rebalance())
+	require.NoError(t, cli.SaveCache())
+	require.Equal(t, []string{RebalancePath, SaveCachePath}, actions)
+}
+
+func TestNewClientEndpoint(t *testing.T) {
+	require.Equal(t, "http://localhost:8891", NewClient(":8891", 0).endpoint)
+	require.Equal(t, "http://node:8891", NewClient("node:8891", 0).endpoint)
+	require.Equal(t, "https://node:8891", NewClient("https://node:8891/", 0).endpoint)
+	require.Equal(t, "http://localhost", NewClient("unix:///run/actions.sock", 0).endpoint)
+}
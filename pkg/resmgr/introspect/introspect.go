@@ -0,0 +1,134 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package introspect defines the data served by the introspection HTTP
+// endpoints of the resource manager.
+package introspect
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+)
+
+const (
+	// PathPrefix is the common prefix of all introspection endpoints.
+	PathPrefix = "/introspect/"
+	// PodsPath is the endpoint for listing pods.
+	PodsPath = PathPrefix + "pods"
+	// ContainersPath is the endpoint for listing containers.
+	ContainersPath = PathPrefix + "containers"
+	// PoolsPath is the endpoint for listing the pools of the active policy.
+	PoolsPath = PathPrefix + "pools"
+	// BalloonsPath is the endpoint for listing balloons, for the balloons policy.
+	BalloonsPath = PathPrefix + "balloons"
+	// ZonesPath is the endpoint for listing the topology zones of the active policy.
+	ZonesPath = PathPrefix + "zones"
+	// DecisionsPath is the endpoint for listing the placement decisions of containers.
+	DecisionsPath = PathPrefix + "decisions"
+	// ConfigStatusPath is the endpoint for the status of the configuration
+	// file, when running standalone without custom resources.
+	ConfigStatusPath = PathPrefix + "config-status"
+
+	// ActionPrefix is the common prefix of all action endpoints.
+	ActionPrefix = "/actions/"
+	// RebalancePath is the endpoint for requesting the active policy to
+	// reallocate resources for all containers.
+	RebalancePath = ActionPrefix + "rebalance"
+	// SaveCachePath is the endpoint for saving the cache to disk.
+	SaveCachePath = ActionPrefix + "save-cache"
+)
+
+// Pod is a pod known to the resource manager.
+type Pod struct {
+	// ID is the pod sandbox ID.
+	ID string `json:"id"`
+	// UID is the Kubernetes UID of the pod.
+	UID string `json:"uid"`
+	// Name is the name of the pod.
+	Name string `json:"name"`
+	// Namespace is the namespace of the pod.
+	Namespace string `json:"namespace"`
+	// QOSClass is the QoS class of the pod.
+	QOSClass string `json:"qosClass"`
+	// Containers are the IDs of the containers of the pod.
+	Containers []string `json:"containers,omitempty"`
+}
+
+// Container is a container known to the resource manager.
+type Container struct {
+	// ID is the container ID.
+	ID string `json:"id"`
+	// PodID is the ID of the pod sandbox of the container.
+	PodID string `json:"podId"`
+	// Name is the name of the container.
+	Name string `json:"name"`
+	// PodName is the name of the pod of the container.
+	PodName string `json:"podName"`
+	// Namespace is the namespace of the pod of the container.
+	Namespace string `json:"namespace"`
+	// QOSClass is the QoS class of the container.
+	QOSClass string `json:"qosClass"`
+	// State is the state of the container.
+	State string `json:"state"`
+	// CPUs is the cpuset assigned to the container.
+	CPUs string `json:"cpus"`
+	// Mems is the memory node set assigned to the container.
+	Mems string `json:"mems"`
+	// Pool is the pool, or balloon, the container is assigned to.
+	Pool string `json:"pool,omitempty"`
+	// Match describes the rule which selected the pool, or pool type, of
+	// the container, for instance a matching expression, if the policy
+	// selects pools by rules.
+	Match string `json:"match,omitempty"`
+	// Resources are the resource requirements of the container.
+	Resources corev1.ResourceRequirements `json:"resources"`
+}
+
+// Pool is a pool, or balloon, of the active policy.
+type Pool struct {
+	// Name is the name of the pool.
+	Name string `json:"name"`
+	// Type is the policy-specific type of the pool, for instance the
+	// balloon type, or the kind of topology-aware pool.
+	Type string `json:"type,omitempty"`
+	// Parent is the name of the parent pool, if any.
+	Parent string `json:"parent,omitempty"`
+	// CPUs is the set of CPUs of the pool.
+	CPUs string `json:"cpus"`
+	// SharedCPUs is the set of CPUs containers in the pool can share.
+	SharedCPUs string `json:"sharedCpus,omitempty"`
+	// ReservedCPUs is the set of reserved CPUs of the pool.
+	ReservedCPUs string `json:"reservedCpus,omitempty"`
+	// IsolatedCPUs is the set of isolated CPUs of the pool.
+	IsolatedCPUs string `json:"isolatedCpus,omitempty"`
+	// Mems is the set of memory nodes of the pool.
+	Mems string `json:"mems"`
+	// Containers are the IDs of the containers assigned to the pool.
+	Containers []string `json:"containers,omitempty"`
+}
+
+// Decision is the last placement decision of the policy for a container.
+type Decision struct {
+	// ID is the container ID.
+	ID string `json:"id"`
+	// Name is the name of the container.
+	Name string `json:"name"`
+	// PodName is the name of the pod of the container.
+	PodName string `json:"podName"`
+	// Namespace is the namespace of the pod of the container.
+	Namespace string `json:"namespace"`
+	// Decision is the recorded decision.
+	Decision *cache.Decision `json:"decision"`
+}
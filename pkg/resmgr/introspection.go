@@ -0,0 +1,396 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resmgr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/containers/nri-plugins/pkg/instrumentation"
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/resmgr/events"
+	"github.com/containers/nri-plugins/pkg/resmgr/introspect"
+	"github.com/containers/nri-plugins/pkg/resmgr/policy"
+)
+
+// setupIntrospection prepares the resource manager for serving introspection
+// requests.
+func (m *resmgr) setupIntrospection() {
+	mux := instrumentation.HTTPServer().GetMux()
+	mux.HandleFunc(introspect.PodsPath, m.serveIntrospection(m.introspectPods))
+	mux.HandleFunc(introspect.ContainersPath, m.serveIntrospection(m.introspectContainers))
+	mux.HandleFunc(introspect.PoolsPath, m.serveIntrospection(m.introspectPools))
+	mux.HandleFunc(introspect.BalloonsPath, m.serveIntrospection(m.introspectBalloons))
+	mux.HandleFunc(introspect.ZonesPath, m.serveIntrospection(m.introspectZones))
+	mux.HandleFunc(introspect.DecisionsPath, m.serveIntrospection(m.introspectDecisions))
+	mux.HandleFunc(introspect.ConfigStatusPath, m.serveIntrospection(m.introspectConfigStatus))
+}
+
+// startActions starts serving debugging action requests if we were asked
+// to. Actions change the state of the plugin, so they are only served on a
+// unix domain socket accessible to root, never on the instrumentation HTTP
+// endpoint.
+func (m *resmgr) startActions() error {
+	if opt.ActionsSocket == "" {
+		return nil
+	}
+
+	if err := os.Remove(opt.ActionsSocket); err != nil && !os.IsNotExist(err) {
+		return resmgrError("failed to remove stale actions socket: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(opt.ActionsSocket), 0o755); err != nil {
+		return resmgrError("failed to create directory for actions socket: %v", err)
+	}
+
+	l, err := net.Listen("unix", opt.ActionsSocket)
+	if err != nil {
+		return resmgrError("failed to create actions socket: %v", err)
+	}
+	if err := os.Chmod(opt.ActionsSocket, 0o600); err != nil {
+		l.Close()
+		return resmgrError("failed to set permissions of actions socket: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(introspect.RebalancePath, m.serveAction(m.rebalance))
+	mux.HandleFunc(introspect.SaveCachePath, m.serveAction(m.saveCache))
+	m.actions = &http.Server{Handler: mux}
+
+	go func(srv *http.Server) {
+		if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("failed to serve actions: %v", err)
+		}
+	}(m.actions)
+
+	log.Info("serving actions on %s", opt.ActionsSocket)
+
+	return nil
+}
+
+// stopActions stops serving debugging action requests.
+func (m *resmgr) stopActions() {
+	if m.actions == nil {
+		return
+	}
+	if err := m.actions.Close(); err != nil {
+		log.Warnf("failed to close actions socket: %v", err)
+	}
+	m.actions = nil
+}
+
+// serveIntrospection returns an HTTP handler serving the data produced by fn
+// as JSON. Errors returned by fn indicate data not available for the active
+// policy.
+func (m *resmgr) serveIntrospection(fn func() (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "introspection is read-only", http.StatusMethodNotAllowed)
+			return
+		}
+
+		m.RLock()
+		data, err := fn()
+		m.RUnlock()
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		reply, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			log.Errorf("failed to marshal introspection data for %s: %v", req.URL.Path, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(reply); err != nil {
+			log.Errorf("failed to write introspection reply for %s: %v", req.URL.Path, err)
+		}
+	}
+}
+
+// serveAction returns an HTTP handler performing the action fn.
+func (m *resmgr) serveAction(fn func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "actions must be requested with POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		log.Infof("performing action %s...", req.URL.Path)
+
+		if err := fn(); err != nil {
+			log.Errorf("action %s failed: %v", req.URL.Path, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := w.Write([]byte("ok")); err != nil {
+			log.Errorf("failed to write reply for %s: %v", req.URL.Path, err)
+		}
+	}
+}
+
+// rebalance requests the active policy to reallocate resources for all containers.
+// Pods with containers which get moved to other CPUs or memory nodes are notified
+// about it by Kubernetes Events.
+func (m *resmgr) rebalance() error {
+	m.RLock()
+	before := m.containerPlacements()
+	m.RUnlock()
+
+	err := m.deliverPolicyEvent(&events.Policy{
+		Type:   events.Rebalance,
+		Source: "resource-manager",
+	})
+	if err != nil {
+		return err
+	}
+
+	m.RLock()
+	after := m.containerPlacements()
+	m.RUnlock()
+
+	for id, p := range after {
+		old, ok := before[id]
+		if !ok || old.resources == p.resources {
+			continue
+		}
+		m.postPodEvent(&events.Pod{
+			Namespace: p.namespace,
+			Name:      p.pod,
+			UID:       p.uid,
+			Type:      events.Normal,
+			Reason:    events.Rebalanced,
+			Message: fmt.Sprintf("container %s: moved from %s to %s",
+				p.container, old.resources, p.resources),
+		})
+	}
+
+	return nil
+}
+
+// placement is the CPUs and memory nodes of a container, together with
+// the pod and container names needed to post events about it.
+type placement struct {
+	namespace string
+	pod       string
+	uid       string
+	container string
+	resources string
+}
+
+// containerPlacements returns the placements of all containers with a pod.
+// It must be called with the resource manager lock held.
+func (m *resmgr) containerPlacements() map[string]*placement {
+	placements := map[string]*placement{}
+	for _, c := range m.cache.GetContainers() {
+		pod, ok := c.GetPod()
+		if !ok {
+			continue
+		}
+		placements[c.GetID()] = &placement{
+			namespace: pod.GetNamespace(),
+			pod:       pod.GetName(),
+			uid:       pod.GetUID(),
+			container: c.GetName(),
+			resources: fmt.Sprintf("cpus %s, mems %s",
+				c.GetCpusetCpus(), c.GetCpusetMems()),
+		}
+	}
+	return placements
+}
+
+// saveCache saves the cache to disk.
+func (m *resmgr) saveCache() error {
+	m.Lock()
+	defer m.Unlock()
+
+	if err := m.cache.Save(); err != nil {
+		return err
+	}
+	return m.cache.Flush()
+}
+
+func (m *resmgr) introspectPods() (interface{}, error) {
+	pods := []*introspect.Pod{}
+	for _, p := range m.cache.GetPods() {
+		pod := &introspect.Pod{
+			ID:        p.GetID(),
+			UID:       p.GetUID(),
+			Name:      p.GetName(),
+			Namespace: p.GetNamespace(),
+			QOSClass:  string(p.GetQOSClass()),
+		}
+		for _, c := range p.GetContainers() {
+			pod.Containers = append(pod.Containers, c.GetID())
+		}
+		sort.Strings(pod.Containers)
+		pods = append(pods, pod)
+	}
+
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].Name < pods[j].Name
+	})
+
+	return pods, nil
+}
+
+func (m *resmgr) introspectContainers() (interface{}, error) {
+	poolOf := map[string]string{}
+	if pools, ok := m.policy.GetPools(); ok {
+		for _, pool := range pools {
+			for _, id := range pool.Containers {
+				poolOf[id] = pool.Name
+			}
+		}
+	}
+
+	containers := []*introspect.Container{}
+	for _, c := range m.cache.GetContainers() {
+		ctr := &introspect.Container{
+			ID:        c.GetID(),
+			PodID:     c.GetPodID(),
+			Name:      c.GetName(),
+			Namespace: c.GetNamespace(),
+			QOSClass:  string(c.GetQOSClass()),
+			State:     containerStateName(c.GetState()),
+			CPUs:      c.GetCpusetCpus(),
+			Mems:      c.GetCpusetMems(),
+			Pool:      poolOf[c.GetID()],
+			Resources: c.GetResourceRequirements(),
+		}
+		if pod, ok := c.GetPod(); ok {
+			ctr.PodName = pod.GetName()
+		}
+		if d := c.GetDecision(); d != nil {
+			ctr.Match = d.Match
+		}
+		containers = append(containers, ctr)
+	}
+
+	sort.Slice(containers, func(i, j int) bool {
+		ci, cj := containers[i], containers[j]
+		if ci.Namespace != cj.Namespace {
+			return ci.Namespace < cj.Namespace
+		}
+		if ci.PodName != cj.PodName {
+			return ci.PodName < cj.PodName
+		}
+		return ci.Name < cj.Name
+	})
+
+	return containers, nil
+}
+
+func (m *resmgr) introspectPools() (interface{}, error) {
+	pools, ok := m.policy.GetPools()
+	if !ok {
+		return nil, resmgrError("policy %s does not support pool introspection",
+			m.policy.ActivePolicy())
+	}
+	if pools == nil {
+		pools = []*introspect.Pool{}
+	}
+	return pools, nil
+}
+
+func (m *resmgr) introspectBalloons() (interface{}, error) {
+	balloons, ok := m.policy.GetBalloons()
+	if !ok {
+		return nil, resmgrError("policy %s has no balloons", m.policy.ActivePolicy())
+	}
+	if balloons == nil {
+		balloons = []*introspect.Pool{}
+	}
+	return balloons, nil
+}
+
+func (m *resmgr) introspectZones() (interface{}, error) {
+	zones := m.policy.GetTopologyZones()
+	if zones == nil {
+		zones = []*policy.TopologyZone{}
+	}
+	return zones, nil
+}
+
+func (m *resmgr) introspectDecisions() (interface{}, error) {
+	decisions := []*introspect.Decision{}
+	for _, c := range m.cache.GetContainers() {
+		d := c.GetDecision()
+		if d == nil {
+			continue
+		}
+		decision := &introspect.Decision{
+			ID:        c.GetID(),
+			Name:      c.GetName(),
+			Namespace: c.GetNamespace(),
+			Decision:  d,
+		}
+		if pod, ok := c.GetPod(); ok {
+			decision.PodName = pod.GetName()
+		}
+		decisions = append(decisions, decision)
+	}
+
+	sort.Slice(decisions, func(i, j int) bool {
+		di, dj := decisions[i], decisions[j]
+		if di.Namespace != dj.Namespace {
+			return di.Namespace < dj.Namespace
+		}
+		if di.PodName != dj.PodName {
+			return di.PodName < dj.PodName
+		}
+		return di.Name < dj.Name
+	})
+
+	return decisions, nil
+}
+
+func (m *resmgr) introspectConfigStatus() (interface{}, error) {
+	if m.agent == nil {
+		return nil, resmgrError("no configuration agent")
+	}
+	return m.agent.ConfigFileStatus()
+}
+
+func containerStateName(state cache.ContainerState) string {
+	switch state {
+	case cache.ContainerStateCreating:
+		return "creating"
+	case cache.ContainerStateCreated:
+		return "created"
+	case cache.ContainerStateRunning:
+		return "running"
+	case cache.ContainerStateExited:
+		return "exited"
+	case cache.ContainerStateStale:
+		return "stale"
+	}
+	return "unknown"
+}
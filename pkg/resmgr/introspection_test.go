@@ -0,0 +1,238 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resmgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+	"github.com/containers/nri-plugins/pkg/resmgr/events"
+	"github.com/containers/nri-plugins/pkg/resmgr/introspect"
+	"github.com/containers/nri-plugins/pkg/resmgr/policy"
+)
+
+type mockPolicy struct {
+	name     string
+	pools    []*introspect.Pool
+	balloons bool   // pools are balloons
+	reject   string // cpuset not to adopt
+}
+
+func (p *mockPolicy) ActivePolicy() string                            { return p.name }
+func (p *mockPolicy) Start(interface{}) error                         { return nil }
+func (p *mockPolicy) Reconfigure(interface{}) error                   { return nil }
+func (p *mockPolicy) Sync([]cache.Container, []cache.Container) error { return nil }
+func (p *mockPolicy) AllocateResources(cache.Container) error         { return nil }
+func (p *mockPolicy) ReleaseResources(cache.Container) error          { return nil }
+func (p *mockPolicy) UpdateResources(cache.Container) error           { return nil }
+func (p *mockPolicy) HandleEvent(*events.Policy) (bool, error)        { return false, nil }
+func (p *mockPolicy) ExportResourceData(cache.Container)              {}
+func (p *mockPolicy) GetTopologyZones() []*policy.TopologyZone        { return nil }
+func (p *mockPolicy) GetPools() ([]*introspect.Pool, bool) {
+	return p.pools, p.pools != nil
+}
+
+func (p *mockPolicy) GetBalloons() ([]*introspect.Pool, bool) {
+	return p.pools, p.balloons
+}
+
+func (p *mockPolicy) AdoptResources(c cache.Container) error {
+	if c.GetCpusetCpus() == "" {
+		return fmt.Errorf("no cpuset to adopt for %s", c.PrettyName())
+	}
+	if p.reject != "" && c.GetCpusetCpus() == p.reject {
+		return fmt.Errorf("cpuset %q of %s rejected", p.reject, c.PrettyName())
+	}
+	return nil
+}
+
+func newIntrospectionTestResmgr(t *testing.T, p *mockPolicy) *resmgr {
+	cch, err := cache.NewCache(cache.Options{CacheDir: t.TempDir()})
+	require.NoError(t, err)
+
+	cch.InsertPod(&api.PodSandbox{
+		Id:        "pod0",
+		Uid:       "uid0",
+		Name:      "pod0",
+		Namespace: "default",
+		Linux: &api.LinuxPodSandbox{
+			CgroupParent: "/kubepods/burstable/poduid0",
+		},
+	}, nil)
+
+	for _, name := range []string{"ctr1", "ctr0"} {
+		c, err := cch.InsertContainer(&api.Container{
+			Id:           name + "-id",
+			PodSandboxId: "pod0",
+			Name:         name,
+			State:        api.ContainerState_CONTAINER_RUNNING,
+		})
+		require.NoError(t, err)
+		c.SetCpusetCpus("0-1")
+		c.SetCpusetMems("0")
+	}
+
+	return &resmgr{
+		cache:  cch,
+		policy: p,
+	}
+}
+
+func introspectionRequest(t *testing.T, m *resmgr, method string, fn func() (interface{}, error), obj interface{}) int {
+	rec := httptest.NewRecorder()
+	m.serveIntrospection(fn)(rec, httptest.NewRequest(method, "/introspect/test", nil))
+	if rec.Code == http.StatusOK && obj != nil {
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), obj))
+	}
+	return rec.Code
+}
+
+func TestIntrospection(t *testing.T) {
+	m := newIntrospectionTestResmgr(t, &mockPolicy{
+		name:     "balloons",
+		balloons: true,
+		pools: []*introspect.Pool{
+			{
+				Name:       "default[0]",
+				Type:       "default",
+				CPUs:       "0-1",
+				Mems:       "0",
+				Containers: []string{"ctr0-id"},
+			},
+		},
+	})
+
+	c, ok := m.cache.LookupContainer("ctr0-id")
+	require.True(t, ok)
+	d := cache.NewDecision("balloons")
+	d.Match = "expression ctr0"
+	c.SetDecision(d)
+
+	pods := []*introspect.Pod{}
+	require.Equal(t, http.StatusOK, introspectionRequest(t, m, http.MethodGet, m.introspectPods, &pods))
+	require.Len(t, pods, 1)
+	require.Equal(t, "pod0", pods[0].Name)
+	require.Equal(t, "Burstable", pods[0].QOSClass)
+	require.Equal(t, []string{"ctr0-id", "ctr1-id"}, pods[0].Containers)
+
+	containers := []*introspect.Container{}
+	require.Equal(t, http.StatusOK, introspectionRequest(t, m, http.MethodGet, m.introspectContainers, &containers))
+	require.Len(t, containers, 2)
+	require.Equal(t, "ctr0", containers[0].Name)
+	require.Equal(t, "pod0", containers[0].PodName)
+	require.Equal(t, "running", containers[0].State)
+	require.Equal(t, "0-1", containers[0].CPUs)
+	require.Equal(t, "0", containers[0].Mems)
+	require.Equal(t, "default[0]", containers[0].Pool)
+	require.Equal(t, "expression ctr0", containers[0].Match)
+	require.Equal(t, "", containers[1].Pool)
+	require.Equal(t, "", containers[1].Match)
+
+	pools := []*introspect.Pool{}
+	require.Equal(t, http.StatusOK, introspectionRequest(t, m, http.MethodGet, m.introspectBalloons, &pools))
+	require.Len(t, pools, 1)
+	require.Equal(t, "default[0]", pools[0].Name)
+
+	require.Equal(t, http.StatusMethodNotAllowed,
+		introspectionRequest(t, m, http.MethodPost, m.introspectPods, nil))
+}
+
+func TestIntrospectDecisions(t *testing.T) {
+	m := newIntrospectionTestResmgr(t, &mockPolicy{name: "balloons"})
+
+	c, ok := m.cache.LookupContainer("ctr1-id")
+	require.True(t, ok)
+	d := cache.NewDecision("balloons")
+	d.AddExpression("balloon type high-prio: <name In ctr1>", true)
+	d.AddCandidate("high-prio[0]", "hint score 0, free 1000 mCPU, 0 containers", "")
+	d.Choose("high-prio[0]", "fill method balanced")
+	c.SetDecision(d)
+
+	decisions := []*introspect.Decision{}
+	require.Equal(t, http.StatusOK, introspectionRequest(t, m, http.MethodGet, m.introspectDecisions, &decisions))
+	require.Len(t, decisions, 1)
+	require.Equal(t, "ctr1", decisions[0].Name)
+	require.Equal(t, "pod0", decisions[0].PodName)
+	require.Equal(t, "high-prio[0]", decisions[0].Decision.Choice)
+	require.Equal(t, d.Expressions, decisions[0].Decision.Expressions)
+}
+
+func TestIntrospectionWithoutPools(t *testing.T) {
+	m := newIntrospectionTestResmgr(t, &mockPolicy{name: "template"})
+
+	require.Equal(t, http.StatusNotFound, introspectionRequest(t, m, http.MethodGet, m.introspectPools, nil))
+	require.Equal(t, http.StatusNotFound, introspectionRequest(t, m, http.MethodGet, m.introspectBalloons, nil))
+
+	zones := []*policy.TopologyZone{}
+	require.Equal(t, http.StatusOK, introspectionRequest(t, m, http.MethodGet, m.introspectZones, &zones))
+	require.Empty(t, zones)
+}
+
+func TestActions(t *testing.T) {
+	m := newIntrospectionTestResmgr(t, &mockPolicy{name: "template"})
+
+	action := func(method string, fn func() error) int {
+		rec := httptest.NewRecorder()
+		m.serveAction(fn)(rec, httptest.NewRequest(method, "/actions/test", nil))
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, action(http.MethodPost, m.saveCache))
+	require.Equal(t, http.StatusOK, action(http.MethodPost, m.rebalance))
+	require.Equal(t, http.StatusMethodNotAllowed, action(http.MethodGet, m.saveCache))
+}
+
+func TestActionsSocket(t *testing.T) {
+	m := newIntrospectionTestResmgr(t, &mockPolicy{name: "template"})
+
+	saved := opt
+	defer func() { opt = saved }()
+
+	// Actions are disabled by default.
+	opt.ActionsSocket = ""
+	require.NoError(t, m.startActions())
+	require.Nil(t, m.actions)
+
+	opt.ActionsSocket = filepath.Join(t.TempDir(), "run", "actions.sock")
+	require.NoError(t, os.MkdirAll(filepath.Dir(opt.ActionsSocket), 0o755))
+	require.NoError(t, os.WriteFile(opt.ActionsSocket, nil, 0o644), "stale socket")
+
+	require.NoError(t, m.startActions())
+	defer m.stopActions()
+
+	info, err := os.Stat(opt.ActionsSocket)
+	require.NoError(t, err)
+	require.Equal(t, os.ModeSocket|0o600, info.Mode()&(os.ModeType|os.ModePerm))
+
+	cli := introspect.NewClient("unix://"+opt.ActionsSocket, 0)
+	require.NoError(t, cli.SaveCache())
+	require.NoError(t, cli.Rebalance())
+	_, err = cli.GetPods()
+	require.ErrorContains(t, err, "404", "introspection is not served on the actions socket")
+
+	m.stopActions()
+	require.NoFileExists(t, opt.ActionsSocket)
+	require.Error(t, cli.SaveCache())
+}
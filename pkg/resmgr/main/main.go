@@ -18,6 +18,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 	"syscall"
 
@@ -68,10 +69,25 @@ func (m *Main) Run() error {
 	}
 	defer m.stopTracing()
 
+	m.setupShutdownSignals()
+
 	err := m.mgr.Start()
 	return err
 }
 
+// setupShutdownSignals stops the resource manager, flushing any pending
+// state to disk, on SIGTERM or SIGINT. Stopping the resource manager lets
+// Run return, which takes care of the rest of the cleanup.
+func (m *Main) setupShutdownSignals() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-ch
+		log.Infof("received signal %s, shutting down...", sig)
+		m.mgr.Stop()
+	}()
+}
+
 func (m *Main) ResourceManager() resmgr.ResourceManager {
 	return m.mgr
 }
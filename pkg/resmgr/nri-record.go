@@ -0,0 +1,237 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resmgr
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/containers/nri-plugins/pkg/resmgr/record"
+)
+
+// startRecording starts recording NRI requests if we were asked to.
+func (p *nriPlugin) startRecording() error {
+	if opt.NriRecord == "" {
+		return nil
+	}
+
+	hdr := &record.Header{
+		Plugin:   opt.NriPluginIdx + "-" + opt.NriPluginName,
+		Policy:   p.resmgr.policy.ActivePolicy(),
+		StateDir: opt.StateDir,
+	}
+
+	w, err := record.Create(opt.NriRecord, hdr)
+	if err != nil {
+		return fmt.Errorf("failed to start recording NRI requests: %w", err)
+	}
+	p.recorder = w
+
+	nri.Info("recording NRI requests to %s...", opt.NriRecord)
+
+	return nil
+}
+
+// stopRecording stops recording NRI requests.
+func (p *nriPlugin) stopRecording() {
+	if err := p.recorder.Close(); err != nil {
+		nri.Error("failed to close NRI recording: %v", err)
+	}
+	p.recorder = nil
+}
+
+// record records an NRI request with our response to it.
+func (p *nriPlugin) record(r *record.Record, err error) {
+	if p.recorder == nil {
+		return
+	}
+
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	if err := p.recorder.Write(r); err != nil {
+		nri.Error("failed to record %s: %v", r.Event, err)
+	}
+}
+
+// setupReplay prepares for replaying a recording if we were asked to. The
+// replay uses a temporary state directory, and no PID file or resource
+// controllers, to leave the host and any running instance untouched.
+func (m *resmgr) setupReplay() error {
+	if opt.NriReplay == "" {
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "nri-replay-")
+	if err != nil {
+		return resmgrError("failed to create state directory for replay: %v", err)
+	}
+
+	if opt.StateDir != "" {
+		log.Info("replay: using state directory %s instead of %s", dir, opt.StateDir)
+	}
+	opt.StateDir = dir
+	m.replayC = make(chan error, 1)
+
+	return nil
+}
+
+// replay replays the recording, then stops the agent to let Start return
+// with the result of the replay.
+func (m *resmgr) replay() {
+	m.replayC <- m.nri.replay(opt.NriReplay)
+	m.agent.Stop()
+}
+
+// finishReplay stops the resource manager, removes the temporary state
+// of the replay and returns its result.
+func (m *resmgr) finishReplay() error {
+	var err error
+
+	select {
+	case err = <-m.replayC:
+	default:
+		err = fmt.Errorf("agent stopped before replay finished")
+	}
+
+	m.Stop()
+	if rmErr := os.RemoveAll(opt.StateDir); rmErr != nil {
+		log.Warnf("failed to remove replay state directory: %v", rmErr)
+	}
+
+	if err != nil {
+		return resmgrError("replay of %s failed: %v", opt.NriReplay, err)
+	}
+
+	log.Info("replay of %s finished, all responses match the recording", opt.NriReplay)
+
+	return nil
+}
+
+// replay feeds the NRI requests of a recording through the plugin,
+// comparing our responses to the recorded ones.
+func (p *nriPlugin) replay(path string) error {
+	hdr, records, err := record.Read(path)
+	if err != nil {
+		return err
+	}
+
+	if policy := p.resmgr.policy.ActivePolicy(); hdr.Policy != "" && hdr.Policy != policy {
+		nri.Warn("replaying recording of policy %s with policy %s", hdr.Policy, policy)
+	}
+
+	var (
+		ctx         = context.Background()
+		replayed    = 0
+		mismatch    = 0
+		recStateDir = hdr.StateDir
+	)
+
+	for i, rec := range records {
+		var (
+			r   = &record.Record{Event: rec.Event}
+			err error
+		)
+
+		switch rec.Event {
+		case Configure:
+			_, err = p.Configure(ctx, "", rec.Runtime, rec.RuntimeVersion)
+		case Synchronize:
+			r.Updates, err = p.Synchronize(ctx, rec.Pods, rec.Containers)
+		case RunPodSandbox:
+			err = p.RunPodSandbox(ctx, rec.Pod)
+		case StopPodSandbox:
+			err = p.StopPodSandbox(ctx, rec.Pod)
+		case RemovePodSandbox:
+			err = p.RemovePodSandbox(ctx, rec.Pod)
+		case CreateContainer:
+			r.Adjustment, r.Updates, err = p.CreateContainer(ctx, rec.Pod, rec.Container)
+		case StartContainer:
+			err = p.StartContainer(ctx, rec.Pod, rec.Container)
+		case UpdateContainer:
+			r.Updates, err = p.UpdateContainer(ctx, rec.Pod, rec.Container, rec.Resources)
+		case StopContainer:
+			r.Updates, err = p.StopContainer(ctx, rec.Pod, rec.Container)
+		case RemoveContainer:
+			err = p.RemoveContainer(ctx, rec.Pod, rec.Container)
+		case UpdateContainers:
+			// Unsolicited updates are triggered by timers and configuration
+			// updates which are not part of the recording.
+			nri.Info("replay: skipping recorded unsolicited container updates (#%d)", i+1)
+			continue
+		default:
+			return fmt.Errorf("record #%d: unknown NRI request %q", i+1, rec.Event)
+		}
+
+		if err != nil {
+			r.Error = err.Error()
+		}
+		if recStateDir != "" && recStateDir != opt.StateDir {
+			relocateMounts(r.Adjustment, opt.StateDir, recStateDir)
+		}
+
+		replayed++
+		if diff := record.Diff(rec, r); diff != "" {
+			mismatch++
+			nri.Error("replay: %s (#%d) response differs from recording:", describe(rec), i+1)
+			nri.ErrorBlock("    ", "%s", strings.TrimSuffix(diff, "\n"))
+		} else {
+			nri.Info("replay: %s (#%d) response matches recording", describe(rec), i+1)
+		}
+	}
+
+	if mismatch > 0 {
+		return fmt.Errorf("%d of %d replayed responses differ from recording", mismatch, replayed)
+	}
+
+	return nil
+}
+
+// relocateMounts rewrites mount sources under one directory to another.
+func relocateMounts(adjust *api.ContainerAdjustment, from, to string) {
+	for _, m := range adjust.GetMounts() {
+		if m.Source == from || strings.HasPrefix(m.Source, from+"/") {
+			m.Source = to + strings.TrimPrefix(m.Source, from)
+		}
+	}
+}
+
+// describe returns a short description of a recorded request.
+func describe(r *record.Record) string {
+	switch {
+	case r.Container != nil:
+		return r.Event + " " + r.Pod.GetNamespace() + "/" + r.Pod.GetName() + "/" + r.Container.GetName()
+	case r.Pod != nil:
+		return r.Event + " " + r.Pod.GetNamespace() + "/" + r.Pod.GetName()
+	}
+	return r.Event
+}
+
+// replayStub is a stand-in for the NRI stub during replay.
+type replayStub struct{}
+
+func (*replayStub) Run(context.Context) error   { return nil }
+func (*replayStub) Start(context.Context) error { return nil }
+func (*replayStub) Stop()                       {}
+func (*replayStub) Wait()                       {}
+
+func (*replayStub) UpdateContainers(updates []*api.ContainerUpdate) ([]*api.ContainerUpdate, error) {
+	nri.Info("replay: ignoring %d unsolicited container updates", len(updates))
+	return nil, nil
+}
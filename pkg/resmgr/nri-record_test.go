@@ -0,0 +1,108 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resmgr
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/containers/nri-plugins/pkg/agent"
+	"github.com/containers/nri-plugins/pkg/resmgr/record"
+)
+
+func TestReplay(t *testing.T) {
+	pod := &api.PodSandbox{
+		Id:        "pod1",
+		Uid:       "uid1",
+		Name:      "pod1",
+		Namespace: "default",
+	}
+
+	tcases := []struct {
+		name    string
+		records []*record.Record
+		err     string
+	}{
+		{
+			name: "matching responses",
+			records: []*record.Record{
+				{Event: RunPodSandbox, Pod: pod},
+				{Event: UpdateContainers},
+				{Event: StopPodSandbox, Pod: pod},
+				{Event: RemovePodSandbox, Pod: pod},
+			},
+		},
+		{
+			name: "differing response",
+			records: []*record.Record{
+				{Event: RunPodSandbox, Pod: pod},
+				{Event: StopPodSandbox, Pod: pod, Error: "failed to stop pod"},
+			},
+			err: "1 of 2 replayed responses differ from recording",
+		},
+		{
+			name: "unknown request",
+			records: []*record.Record{
+				{Event: "Unknown"},
+			},
+			err: `record #1: unknown NRI request "Unknown"`,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			saved := opt
+			defer func() { opt = saved }()
+
+			stateDir := t.TempDir()
+			opt.StateDir = stateDir
+			opt.NriReplay = filepath.Join(t.TempDir(), "nri.rec")
+
+			w, err := record.Create(opt.NriReplay, &record.Header{Policy: "test", StateDir: stateDir})
+			require.NoError(t, err)
+			for _, r := range tc.records {
+				require.NoError(t, w.Write(r))
+			}
+			require.NoError(t, w.Close())
+
+			m := &resmgr{
+				agent:  &agent.Agent{},
+				policy: &mockPolicy{name: "test"},
+			}
+			require.NoError(t, m.setupReplay())
+			require.NotEqual(t, stateDir, opt.StateDir, "replay must not use the given state directory")
+			require.DirExists(t, opt.StateDir)
+
+			require.NoError(t, m.setupCache())
+			m.nri = &nriPlugin{resmgr: m, stub: &replayStub{}}
+			m.drift = newDriftDetector(m)
+
+			// No controllers were set up, starting any would panic.
+			require.NoError(t, m.startControllers())
+
+			m.replay()
+			err = m.finishReplay()
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.NoDirExists(t, opt.StateDir)
+			require.NoFileExists(t, filepath.Join(stateDir, "cache"))
+		})
+	}
+}
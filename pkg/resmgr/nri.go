@@ -18,14 +18,18 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"slices"
+	"sync/atomic"
 	"time"
 
 	"github.com/containers/nri-plugins/pkg/instrumentation/metrics"
 	"github.com/containers/nri-plugins/pkg/instrumentation/tracing"
+	"github.com/containers/nri-plugins/pkg/kubernetes"
 	logger "github.com/containers/nri-plugins/pkg/log"
 	"github.com/containers/nri-plugins/pkg/resmgr/cache"
 	"github.com/containers/nri-plugins/pkg/resmgr/events"
+	"github.com/containers/nri-plugins/pkg/resmgr/record"
 	"sigs.k8s.io/yaml"
 
 	"github.com/containerd/nri/pkg/api"
@@ -35,9 +39,11 @@ import (
 )
 
 type nriPlugin struct {
-	stub   stub.Stub
-	resmgr *resmgr
-	byname map[string]cache.Container
+	stub     stub.Stub
+	resmgr   *resmgr
+	byname   map[string]cache.Container
+	recorder *record.Writer
+	stopping atomic.Bool
 }
 
 var (
@@ -97,8 +103,18 @@ func (p *nriPlugin) start() error {
 		return nil
 	}
 
+	if opt.NriReplay != "" {
+		nri.Info("starting plugin for replaying %s...", opt.NriReplay)
+		p.stub = &replayStub{}
+		return nil
+	}
+
 	nri.Info("starting plugin...")
 
+	if err := p.startRecording(); err != nil {
+		return err
+	}
+
 	if err := p.createStub(); err != nil {
 		return err
 	}
@@ -116,11 +132,18 @@ func (p *nriPlugin) stop() {
 	}
 
 	nri.Info("stopping plugin...")
+	p.stopping.Store(true)
 	p.stub.Stop()
+	p.stopRecording()
 }
 
 func (p *nriPlugin) onClose() {
+	if p.stopping.Load() {
+		return
+	}
+
 	nri.Error("connection to NRI/runtime lost, exiting...")
+	p.resmgr.flushCache()
 	os.Exit(1)
 }
 
@@ -190,6 +213,7 @@ func (p *nriPlugin) Configure(ctx context.Context, cfg, runtime, version string)
 	}()
 
 	p.dump(in, event, runtime, version)
+	defer p.record(&record.Record{Event: event, Runtime: runtime, RuntimeVersion: version}, nil)
 
 	return api.MustParseEventMask(
 		"RunPodSandbox,StopPodSandbox,RemovePodSandbox",
@@ -262,12 +286,16 @@ func (p *nriPlugin) Synchronize(ctx context.Context, pods []*api.PodSandbox, con
 	p.dump(in, event, pods, containers)
 	defer func() {
 		p.dump(out, event, updates, retErr)
+		p.record(&record.Record{Event: event, Pods: pods, Containers: containers, Updates: updates}, retErr)
 	}()
 
+	m := p.resmgr
 	b := metrics.Block()
 	defer b.Done()
+	m.Lock()
+	defer m.Unlock()
 
-	m := p.resmgr
+	firstStart := len(m.cache.GetContainers()) == 0
 
 	allocated, released, err := p.syncWithNRI(pods, containers)
 	if err != nil {
@@ -276,7 +304,25 @@ func (p *nriPlugin) Synchronize(ctx context.Context, pods []*api.PodSandbox, con
 	}
 
 	unmapped := p.syncNamesToContainers(allocated)
-	if err := m.policy.Sync(allocated, append(released, unmapped...)); err != nil {
+	released = append(released, unmapped...)
+
+	if opt.RestartMode == RestartModeAdopt {
+		// Adopt the cpusets the runtime reports, not the ones we saved.
+		m.cache.RefreshCpusets(containers)
+	}
+
+	var imported map[string]*kubernetes.KubeletAssignment
+	if opt.ImportKubelet && firstStart {
+		imported = p.importKubeletState(allocated)
+	}
+
+	switch {
+	case opt.RestartMode == RestartModeAdopt:
+		allocated, released = p.adoptContainers(allocated, released, imported, false)
+	case len(imported) > 0:
+		allocated, released = p.adoptContainers(allocated, released, imported, true)
+	}
+	if err := m.policy.Sync(allocated, released); err != nil {
 		return nil, fmt.Errorf("failed to sync policy %s: %w", m.policy.ActivePolicy(), err)
 	}
 
@@ -285,6 +331,120 @@ func (p *nriPlugin) Synchronize(ctx context.Context, pods []*api.PodSandbox, con
 	return p.getPendingUpdates(nil), nil
 }
 
+// adoptContainers lets the policy adopt the current cpusets of the given
+// containers, or the ones imported from kubelet, returning the containers to
+// allocate and release for the rest. If importedOnly is true, only containers
+// with imported cpusets are adopted.
+func (p *nriPlugin) adoptContainers(allocated, released []cache.Container,
+	imported map[string]*kubernetes.KubeletAssignment, importedOnly bool) ([]cache.Container, []cache.Container) {
+	cache.SortContainers(allocated, cache.ComparePodCtime, cache.CompareContainerCtime)
+
+	adopted := map[string]struct{}{}
+	remaining := []cache.Container{}
+	for _, c := range allocated {
+		a, ok := imported[c.GetID()]
+		if importedOnly && !ok {
+			remaining = append(remaining, c)
+			continue
+		}
+		if err := p.adoptResources(c, a); err != nil {
+			nri.Info("reallocating resources of %s: %v", c.PrettyName(), err)
+			remaining = append(remaining, c)
+			continue
+		}
+		nri.Info("adopted cpuset %q, memset %q of %s", c.GetCpusetCpus(),
+			c.GetCpusetMems(), c.PrettyName())
+		adopted[c.GetID()] = struct{}{}
+	}
+
+	release := []cache.Container{}
+	for _, c := range released {
+		if _, ok := adopted[c.GetID()]; !ok {
+			release = append(release, c)
+		}
+	}
+
+	return remaining, release
+}
+
+// adoptResources lets the policy adopt the current cpusets of a container,
+// or the given ones imported from kubelet. Imported cpusets are only set for
+// the container once the policy has adopted them.
+func (p *nriPlugin) adoptResources(c cache.Container, a *kubernetes.KubeletAssignment) error {
+	m := p.resmgr
+
+	if a == nil {
+		return m.policy.AdoptResources(c)
+	}
+
+	cpus, mems := c.GetCpusetCpus(), c.GetCpusetMems()
+	importedCpus, importedMems := cpus, mems
+	if !a.CPUs.IsEmpty() {
+		importedCpus = a.CPUs.String()
+	}
+	if !a.Mems.IsEmpty() {
+		importedMems = a.Mems.String()
+	}
+
+	// Let the policy see the imported cpusets without updating the container.
+	m.cache.RefreshCpusets([]*api.Container{cpusetContainer(c.GetID(), importedCpus, importedMems)})
+	if err := m.policy.AdoptResources(c); err != nil {
+		m.cache.RefreshCpusets([]*api.Container{cpusetContainer(c.GetID(), cpus, mems)})
+		return err
+	}
+
+	if !a.CPUs.IsEmpty() {
+		c.SetCpusetCpus(importedCpus)
+	}
+	if !a.Mems.IsEmpty() {
+		c.SetCpusetMems(importedMems)
+	}
+
+	return nil
+}
+
+// cpusetContainer returns a container list entry with the given cpusets.
+func cpusetContainer(id, cpus, mems string) *api.Container {
+	return &api.Container{
+		Id: id,
+		Linux: &api.LinuxContainer{
+			Resources: &api.LinuxResources{
+				Cpu: &api.LinuxCPU{Cpus: cpus, Mems: mems},
+			},
+		},
+	}
+}
+
+// importKubeletState returns the CPUs and memory nodes assigned to the given
+// containers by the kubelet CPU and memory managers, by container ID.
+func (p *nriPlugin) importKubeletState(containers []cache.Container) map[string]*kubernetes.KubeletAssignment {
+	dir := filepath.Join(opt.HostRoot, opt.KubeletDir)
+	state, err := kubernetes.ReadKubeletState(dir)
+	if err != nil {
+		nri.Error("failed to import kubelet state: %v", err)
+		return nil
+	}
+
+	nri.Info("importing kubelet CPU and memory manager state from %s...", dir)
+
+	imported := map[string]*kubernetes.KubeletAssignment{}
+	for _, c := range containers {
+		pod, ok := c.GetPod()
+		if !ok {
+			continue
+		}
+		a, ok := state.Get(pod.GetUID(), c.GetName())
+		if !ok {
+			continue
+		}
+		nri.Info("imported kubelet cpuset %q, memset %q of %s", a.CPUs, a.Mems,
+			c.PrettyName())
+		imported[c.GetID()] = a
+	}
+
+	return imported
+}
+
 func (p *nriPlugin) RunPodSandbox(ctx context.Context, pod *api.PodSandbox) (retErr error) {
 	event := RunPodSandbox
 
@@ -303,12 +463,13 @@ func (p *nriPlugin) RunPodSandbox(ctx context.Context, pod *api.PodSandbox) (ret
 	p.dump(in, event, pod)
 	defer func() {
 		p.dump(out, event, retErr)
+		p.record(&record.Record{Event: event, Pod: pod}, retErr)
 	}()
 
-	m.Lock()
-	defer m.Unlock()
 	b := metrics.Block()
 	defer b.Done()
+	m.Lock()
+	defer m.Unlock()
 
 	m.cache.InsertPod(pod, podResCh)
 
@@ -344,6 +505,7 @@ func (p *nriPlugin) StopPodSandbox(ctx context.Context, podSandbox *api.PodSandb
 	p.dump(in, event, podSandbox)
 	defer func() {
 		p.dump(out, event, retErr)
+		p.record(&record.Record{Event: event, Pod: podSandbox}, retErr)
 	}()
 
 	return nil
@@ -364,6 +526,7 @@ func (p *nriPlugin) RemovePodSandbox(ctx context.Context, podSandbox *api.PodSan
 	p.dump(in, event, podSandbox)
 	defer func() {
 		p.dump(out, event, retErr)
+		p.record(&record.Record{Event: event, Pod: podSandbox}, retErr)
 	}()
 
 	m := p.resmgr
@@ -376,10 +539,10 @@ func (p *nriPlugin) RemovePodSandbox(ctx context.Context, podSandbox *api.PodSan
 			event, pod.GetName(), err)
 	}
 
-	m.Lock()
-	defer m.Unlock()
 	b := metrics.Block()
 	defer b.Done()
+	m.Lock()
+	defer m.Unlock()
 
 	m.cache.DeletePod(podSandbox.GetId())
 	return nil
@@ -400,13 +563,15 @@ func (p *nriPlugin) CreateContainer(ctx context.Context, pod *api.PodSandbox, co
 	p.dump(in, event, pod, container)
 	defer func() {
 		p.dump(out, event, adjust, updates, retErr)
+		p.record(&record.Record{Event: event, Pod: pod, Container: container,
+			Adjustment: adjust, Updates: updates}, retErr)
 	}()
 
 	m := p.resmgr
-	m.Lock()
-	defer m.Unlock()
 	b := metrics.Block()
 	defer b.Done()
+	m.Lock()
+	defer m.Unlock()
 
 	c, err := m.cache.InsertContainer(container)
 	if err != nil {
@@ -424,8 +589,18 @@ func (p *nriPlugin) CreateContainer(ctx context.Context, pod *api.PodSandbox, co
 
 	if err := m.policy.AllocateResources(c); err != nil {
 		c.UpdateState(cache.ContainerStateStale)
+		m.postPodEvent(&events.Pod{
+			Namespace: pod.GetNamespace(),
+			Name:      pod.GetName(),
+			UID:       pod.GetUid(),
+			Type:      events.Warning,
+			Reason:    events.AllocationFailed,
+			Message: fmt.Sprintf("container %s: failed to allocate resources: %v",
+				container.GetName(), err),
+		})
 		return nil, nil, fmt.Errorf("failed to allocate resources: %w", err)
 	}
+	span.SetAttributes(decisionSpanTags(c.GetDecision())...)
 
 	c.InsertMount(&cache.Mount{
 		Destination: "/.nri-resource-policy",
@@ -473,13 +648,14 @@ func (p *nriPlugin) StartContainer(ctx context.Context, pod *api.PodSandbox, con
 	p.dump(in, event, pod, container)
 	defer func() {
 		p.dump(out, event, retErr)
+		p.record(&record.Record{Event: event, Pod: pod, Container: container}, retErr)
 	}()
 
 	m := p.resmgr
-	m.Lock()
-	defer m.Unlock()
 	b := metrics.Block()
 	defer b.Done()
+	m.Lock()
+	defer m.Unlock()
 
 	c, ok := m.cache.LookupContainer(container.Id)
 	if !ok {
@@ -521,13 +697,15 @@ func (p *nriPlugin) UpdateContainer(ctx context.Context, pod *api.PodSandbox, co
 	p.dump(in, event, pod, container, res)
 	defer func() {
 		p.dump(out, event, updates, retErr)
+		p.record(&record.Record{Event: event, Pod: pod, Container: container,
+			Resources: res, Updates: updates}, retErr)
 	}()
 
 	m := p.resmgr
-	m.Lock()
-	defer m.Unlock()
 	b := metrics.Block()
 	defer b.Done()
+	m.Lock()
+	defer m.Unlock()
 
 	c, ok := m.cache.LookupContainer(container.Id)
 	if !ok {
@@ -565,6 +743,7 @@ func (p *nriPlugin) UpdateContainer(ctx context.Context, pod *api.PodSandbox, co
 		if err := m.policy.UpdateResources(c); err != nil {
 			return nil, fmt.Errorf("failed to update resources: %w", err)
 		}
+		span.SetAttributes(decisionSpanTags(c.GetDecision())...)
 	}
 
 	return p.getPendingUpdates(nil), nil
@@ -585,13 +764,15 @@ func (p *nriPlugin) StopContainer(ctx context.Context, pod *api.PodSandbox, cont
 	p.dump(in, event, pod, container)
 	defer func() {
 		p.dump(out, event, updates, retErr)
+		p.record(&record.Record{Event: event, Pod: pod, Container: container,
+			Updates: updates}, retErr)
 	}()
 
 	m := p.resmgr
-	m.Lock()
-	defer m.Unlock()
 	b := metrics.Block()
 	defer b.Done()
+	m.Lock()
+	defer m.Unlock()
 
 	c, ok := m.cache.LookupContainer(container.Id)
 	if !ok {
@@ -625,23 +806,28 @@ func (p *nriPlugin) RemoveContainer(ctx context.Context, pod *api.PodSandbox, co
 	p.dump(in, event, pod, container)
 	defer func() {
 		p.dump(out, event, retErr)
+		p.record(&record.Record{Event: event, Pod: pod, Container: container}, retErr)
 	}()
 
 	m := p.resmgr
-	m.Lock()
-	defer m.Unlock()
 	b := metrics.Block()
 	defer b.Done()
+	m.Lock()
+	defer m.Unlock()
 
 	m.cache.DeleteContainer(container.Id)
 	return nil
 }
 
-func (p *nriPlugin) updateContainers() (retErr error) {
-	// Notes: must be called with p.resmgr lock held.
+// blockMetrics blocks metrics collection until the returned block is done.
+// It must be called before taking the resource manager lock, since metrics
+// collectors take that lock while collection is blocked.
+func blockMetrics() *metrics.MetricsBlock {
+	return metrics.Block()
+}
 
-	b := metrics.Block()
-	defer b.Done()
+func (p *nriPlugin) updateContainers() (retErr error) {
+	// Notes: must be called with metrics blocked and p.resmgr lock held.
 
 	updates := p.getPendingUpdates(nil)
 
@@ -649,6 +835,7 @@ func (p *nriPlugin) updateContainers() (retErr error) {
 	p.dump(out, event, updates)
 	defer func() {
 		p.dump(in, event, retErr)
+		p.record(&record.Record{Event: event, Updates: updates}, retErr)
 	}()
 
 	_, err := p.stub.UpdateContainers(updates)
@@ -688,6 +875,10 @@ func (p *nriPlugin) getPendingUpdates(skip *api.Container) []*api.ContainerUpdat
 			}
 			updates = append(updates, u)
 
+			// Resource data is exported at creation, refresh it
+			// to reflect any changes in the assigned resources.
+			m.policy.ExportResourceData(c)
+
 			for _, ctrl := range c.GetPending() {
 				c.ClearPending(ctrl)
 			}
@@ -1009,6 +1200,12 @@ const (
 	SpanTagPodName        = "pod.name"
 	SpanTagCtrID          = "container.id"
 	SpanTagCtrName        = "container.name"
+	SpanTagDecisionPolicy = "decision.policy"
+	SpanTagDecisionChoice = "decision.choice"
+	SpanTagDecisionReason = "decision.reason"
+	SpanTagDecisionCands  = "decision.candidates"
+	SpanTagDecisionExprs  = "decision.expressions"
+	SpanTagDecisionHints  = "decision.hints"
 )
 
 func podSpanTags(pod *api.PodSandbox) []tracing.KeyValue {
@@ -1028,6 +1225,33 @@ func containerSpanTags(pod *api.PodSandbox, ctr *api.Container) []tracing.KeyVal
 	)
 }
 
+func decisionSpanTags(d *cache.Decision) []tracing.KeyValue {
+	if d == nil {
+		return nil
+	}
+
+	candidates := make([]string, 0, len(d.Candidates))
+	for _, c := range d.Candidates {
+		cand := c.Name
+		if c.Score != "" {
+			cand += " (" + c.Score + ")"
+		}
+		if c.Reason != "" {
+			cand += ": " + c.Reason
+		}
+		candidates = append(candidates, cand)
+	}
+
+	return []tracing.KeyValue{
+		tracing.Attribute(SpanTagDecisionPolicy, d.Policy),
+		tracing.Attribute(SpanTagDecisionChoice, d.Choice),
+		tracing.Attribute(SpanTagDecisionReason, d.Reason),
+		tracing.Attribute(SpanTagDecisionCands, candidates),
+		tracing.Attribute(SpanTagDecisionExprs, d.Expressions),
+		tracing.Attribute(SpanTagDecisionHints, d.Hints),
+	}
+}
+
 // runPostAllocateHooks runs the necessary hooks after allocating resources for some containers.
 func (p *nriPlugin) runPostAllocateHooks(method string, created cache.Container) error {
 	m := p.resmgr
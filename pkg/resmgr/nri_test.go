@@ -0,0 +1,131 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resmgr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/containers/nri-plugins/pkg/resmgr/cache"
+)
+
+func TestAdoptContainers(t *testing.T) {
+	m := newIntrospectionTestResmgr(t, &mockPolicy{name: "test"})
+	p := &nriPlugin{resmgr: m}
+
+	ctr0, ok := m.cache.LookupContainer("ctr0-id")
+	require.True(t, ok)
+	ctr1, ok := m.cache.LookupContainer("ctr1-id")
+	require.True(t, ok)
+
+	reported := []*api.Container{
+		{
+			Id:           "ctr0-id",
+			PodSandboxId: "pod0",
+			Name:         "ctr0",
+			State:        api.ContainerState_CONTAINER_RUNNING,
+			Linux: &api.LinuxContainer{
+				Resources: &api.LinuxResources{
+					Cpu: &api.LinuxCPU{Cpus: "2-3", Mems: "1"},
+				},
+			},
+		},
+		{
+			Id:           "ctr1-id",
+			PodSandboxId: "pod0",
+			Name:         "ctr1",
+			State:        api.ContainerState_CONTAINER_RUNNING,
+			Linux: &api.LinuxContainer{
+				Resources: &api.LinuxResources{
+					Cpu: &api.LinuxCPU{},
+				},
+			},
+		},
+	}
+
+	// Refreshing containers keeps the cached cpusets.
+	m.cache.RefreshContainers(reported)
+	require.Equal(t, "0-1", ctr0.GetCpusetCpus())
+	require.Equal(t, "0", ctr0.GetCpusetMems())
+
+	// The cpusets reported by the runtime replace the cached ones for adoption.
+	m.cache.RefreshCpusets(reported)
+	require.Equal(t, "2-3", ctr0.GetCpusetCpus())
+	require.Equal(t, "1", ctr0.GetCpusetMems())
+	require.Equal(t, "", ctr1.GetCpusetCpus())
+
+	stale := &api.Container{Id: "stale-id", PodSandboxId: "pod0", Name: "stale"}
+	staleCtr, err := m.cache.InsertContainer(stale)
+	require.NoError(t, err)
+
+	running := []cache.Container{ctr0, ctr1}
+	allocate, release := p.adoptContainers(running, append(running, staleCtr), nil, false)
+	require.Equal(t, []cache.Container{ctr1}, allocate)
+	require.Equal(t, []cache.Container{ctr1, staleCtr}, release)
+}
+
+func TestImportKubeletState(t *testing.T) {
+	pol := &mockPolicy{name: "test"}
+	m := newIntrospectionTestResmgr(t, pol)
+	p := &nriPlugin{resmgr: m}
+
+	saved := opt
+	defer func() { opt = saved }()
+	opt.HostRoot = t.TempDir()
+	opt.KubeletDir = "/var/lib/kubelet"
+
+	dir := filepath.Join(opt.HostRoot, opt.KubeletDir)
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cpu_manager_state"),
+		[]byte(`{"policyName":"static","entries":{"uid0":{"ctr0":"4-5"}}}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "memory_manager_state"),
+		[]byte(`{"policyName":"Static","entries":{"uid0":{"ctr0":[{"numaAffinity":[1]}]}}}`), 0o644))
+
+	ctr0, ok := m.cache.LookupContainer("ctr0-id")
+	require.True(t, ok)
+	ctr1, ok := m.cache.LookupContainer("ctr1-id")
+	require.True(t, ok)
+
+	running := []cache.Container{ctr0, ctr1}
+	imported := p.importKubeletState(running)
+	require.Len(t, imported, 1)
+	require.Contains(t, imported, "ctr0-id")
+	require.Equal(t, "4-5", imported["ctr0-id"].CPUs.String())
+	require.Equal(t, "1", imported["ctr0-id"].Mems.String())
+
+	// Cpusets are not set before they are adopted.
+	require.Equal(t, "0-1", ctr0.GetCpusetCpus())
+	require.Equal(t, "0", ctr0.GetCpusetMems())
+
+	// Rejected imported cpusets are not set.
+	pol.reject = "4-5"
+	allocate, release := p.adoptContainers(running, running, imported, true)
+	require.ElementsMatch(t, []cache.Container{ctr0, ctr1}, allocate)
+	require.ElementsMatch(t, []cache.Container{ctr0, ctr1}, release)
+	require.Equal(t, "0-1", ctr0.GetCpusetCpus())
+	require.Equal(t, "0", ctr0.GetCpusetMems())
+
+	// Only containers with imported cpusets are adopted.
+	pol.reject = ""
+	allocate, release = p.adoptContainers(running, running, imported, true)
+	require.Equal(t, []cache.Container{ctr1}, allocate)
+	require.Equal(t, []cache.Container{ctr1}, release)
+	require.Equal(t, "4-5", ctr0.GetCpusetCpus())
+	require.Equal(t, "1", ctr0.GetCpusetMems())
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/containers/nri-plugins/pkg/resmgr/cache"
 	"github.com/containers/nri-plugins/pkg/resmgr/events"
+	"github.com/containers/nri-plugins/pkg/resmgr/introspect"
 	"github.com/prometheus/client_golang/prometheus"
 
 	logger "github.com/containers/nri-plugins/pkg/log"
@@ -76,6 +77,31 @@ type CreateFn func(*BackendOptions) Backend
 // SendEventFn is the type for a function to send events back to the resource manager.
 type SendEventFn func(interface{}) error
 
+// SendPodEvent sends an event about the pod of a container up to the resource
+// manager, to be posted as a Kubernetes Event.
+func (o *BackendOptions) SendPodEvent(c cache.Container, eventType, reason, format string, args ...interface{}) {
+	if o == nil || o.SendEvent == nil {
+		return
+	}
+
+	pod, ok := c.GetPod()
+	if !ok {
+		return
+	}
+
+	e := &events.Pod{
+		Namespace: pod.GetNamespace(),
+		Name:      pod.GetName(),
+		UID:       pod.GetUID(),
+		Type:      eventType,
+		Reason:    reason,
+		Message:   "container " + c.GetName() + ": " + fmt.Sprintf(format, args...),
+	}
+	if err := o.SendEvent(e); err != nil {
+		log.Warn("failed to send %s event for %s: %v", reason, c.PrettyName(), err)
+	}
+}
+
 const (
 	// ExportedResources is the basename of the file container resources are exported to.
 	ExportedResources = "resources.sh"
@@ -121,6 +147,29 @@ type Backend interface {
 	GetTopologyZones() []*TopologyZone
 }
 
+// Introspector is an optional interface for backends to expose their pools,
+// or balloons, and the containers assigned to them for introspection.
+type Introspector interface {
+	// GetPools returns the current pools of the policy.
+	GetPools() []*introspect.Pool
+}
+
+// BalloonIntrospector is an optional interface for backends which run
+// containers in balloons to expose their balloons for introspection.
+type BalloonIntrospector interface {
+	// GetBalloons returns the current balloons of the policy.
+	GetBalloons() []*introspect.Pool
+}
+
+// Adopter is an optional interface for backends which can take the current
+// resources of running containers as their allocation when restarted.
+type Adopter interface {
+	// AdoptResources takes the current cpuset of a running container as its
+	// allocation. It fails if the cpuset is not valid for the container with
+	// the current configuration.
+	AdoptResources(cache.Container) error
+}
+
 // Policy is the exposed interface for container resource allocations decision making.
 type Policy interface {
 	// ActivePolicy returns the name of the policy backend in use.
@@ -145,6 +194,15 @@ type Policy interface {
 	ExportResourceData(cache.Container)
 	// GetTopologyZones returns the policy/pool data for 'topology zone' CRDs.
 	GetTopologyZones() []*TopologyZone
+	// GetPools returns the pools of the active policy. The returned boolean
+	// is false if the policy does not support introspection.
+	GetPools() ([]*introspect.Pool, bool)
+	// GetBalloons returns the balloons of the active policy. The returned
+	// boolean is false if the policy has no balloons.
+	GetBalloons() ([]*introspect.Pool, bool)
+	// AdoptResources takes the current cpuset of a running container as its
+	// allocation, if the active policy supports it.
+	AdoptResources(cache.Container) error
 }
 
 // Metrics is the interface we expect policy-specific metrics to implement.
@@ -171,29 +229,37 @@ const (
 	ReservedCPUsAttribute = "reserved cpuset"
 	// IsolatedCPUsAttribute is the attribute name for the assignable isolated CPU set
 	IsolatedCPUsAttribute = "isolated cpuset"
+	// CPUsetAttribute is the attribute name for the CPU set of a zone
+	CPUsetAttribute = "cpuset"
+	// BalloonTypeAttribute is the attribute name for the type of a balloon
+	BalloonTypeAttribute = "balloon type"
+	// CPUClassAttribute is the attribute name for the CPU class of a zone
+	CPUClassAttribute = "cpu class"
+	// SharedIdleCPUsAttribute is the attribute name for the shared idle CPU set
+	SharedIdleCPUsAttribute = "shared idle cpuset"
 )
 
 // TopologyZone provides policy-/pool-specific data for 'node resource topology' CRs.
 type TopologyZone struct {
-	Name       string
-	Parent     string
-	Type       string
-	Resources  []*ZoneResource
-	Attributes []*ZoneAttribute
+	Name       string           `json:"name"`
+	Parent     string           `json:"parent,omitempty"`
+	Type       string           `json:"type"`
+	Resources  []*ZoneResource  `json:"resources,omitempty"`
+	Attributes []*ZoneAttribute `json:"attributes,omitempty"`
 }
 
 // ZoneResource is a resource available in some TopologyZone.
 type ZoneResource struct {
-	Name        string
-	Capacity    resource.Quantity
-	Allocatable resource.Quantity
-	Available   resource.Quantity
+	Name        string            `json:"name"`
+	Capacity    resource.Quantity `json:"capacity"`
+	Allocatable resource.Quantity `json:"allocatable"`
+	Available   resource.Quantity `json:"available"`
 }
 
 // ZoneAttribute represents additional, policy-specific information about a zone.
 type ZoneAttribute struct {
-	Name  string
-	Value string
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
 // Policy instance/state.
@@ -324,3 +390,28 @@ func (p *policy) ExportResourceData(c cache.Container) {
 func (p *policy) GetTopologyZones() []*TopologyZone {
 	return p.active.GetTopologyZones()
 }
+
+// GetPools returns the pools of the active policy, if it supports introspection.
+func (p *policy) GetPools() ([]*introspect.Pool, bool) {
+	if i, ok := p.active.(Introspector); ok {
+		return i.GetPools(), true
+	}
+	return nil, false
+}
+
+// GetBalloons returns the balloons of the active policy, if it has any.
+func (p *policy) GetBalloons() ([]*introspect.Pool, bool) {
+	if i, ok := p.active.(BalloonIntrospector); ok {
+		return i.GetBalloons(), true
+	}
+	return nil, false
+}
+
+// AdoptResources takes the current cpuset of a running container as its allocation.
+func (p *policy) AdoptResources(c cache.Container) error {
+	if a, ok := p.active.(Adopter); ok {
+		return a.AdoptResources(c)
+	}
+	return policyError("policy %s does not support adopting container resources",
+		p.active.Name())
+}
@@ -0,0 +1,309 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package record implements recording of NRI requests and the responses
+// given to them by a resource management plugin.
+//
+// A recording is a file of JSON objects, one per line. The first object
+// is a Header, identifying the version of the recording format and the
+// plugin which produced it. Each subsequent object is a Record of a single
+// NRI request, or an unsolicited container update, with the response to it.
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/nri/pkg/api"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// Version is the current version of the recording format.
+	Version = "v1"
+)
+
+// Header identifies a recording.
+type Header struct {
+	// Version is the version of the recording format.
+	Version string `json:"version"`
+	// Plugin is the name of the NRI plugin which made the recording.
+	Plugin string `json:"plugin,omitempty"`
+	// Policy is the name of the active policy during the recording.
+	Policy string `json:"policy,omitempty"`
+	// StateDir is the state directory of the plugin. Adjustments might
+	// refer to paths under this directory.
+	StateDir string `json:"stateDir,omitempty"`
+	// Time is the time the recording was started.
+	Time time.Time `json:"time"`
+}
+
+// Record is a single recorded NRI request and the response to it.
+type Record struct {
+	// Time is the time the request was processed.
+	Time time.Time `json:"time"`
+	// Event is the name of the NRI request.
+	Event string `json:"event"`
+	// Runtime is the name of the runtime, for Configure.
+	Runtime string `json:"runtime,omitempty"`
+	// RuntimeVersion is the version of the runtime, for Configure.
+	RuntimeVersion string `json:"runtimeVersion,omitempty"`
+	// Pods are the existing pods, for Synchronize.
+	Pods []*api.PodSandbox `json:"pods,omitempty"`
+	// Containers are the existing containers, for Synchronize.
+	Containers []*api.Container `json:"containers,omitempty"`
+	// Pod is the pod the request is for.
+	Pod *api.PodSandbox `json:"pod,omitempty"`
+	// Container is the container the request is for.
+	Container *api.Container `json:"container,omitempty"`
+	// Resources are the requested new resources, for UpdateContainer.
+	Resources *api.LinuxResources `json:"resources,omitempty"`
+	// Adjustment is the returned container adjustment.
+	Adjustment *api.ContainerAdjustment `json:"adjustment,omitempty"`
+	// Updates are the returned, or unsolicited, container updates.
+	Updates []*api.ContainerUpdate `json:"updates,omitempty"`
+	// Error is the returned error, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// Writer writes a recording to a file.
+type Writer struct {
+	sync.Mutex
+	path string
+	f    *os.File
+	enc  *json.Encoder
+}
+
+// Create creates a recording file with the given header.
+func Create(path string, hdr *Header) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording %s: %w", path, err)
+	}
+
+	w := &Writer{
+		path: path,
+		f:    f,
+		enc:  json.NewEncoder(f),
+	}
+
+	h := *hdr
+	h.Version = Version
+	if h.Time.IsZero() {
+		h.Time = time.Now()
+	}
+
+	if err := w.enc.Encode(&h); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write recording header to %s: %w", path, err)
+	}
+
+	return w, nil
+}
+
+// Write appends a record to the recording.
+func (w *Writer) Write(r *Record) error {
+	if w == nil {
+		return nil
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	if w.f == nil {
+		return fmt.Errorf("recording %s already closed", w.path)
+	}
+
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	return w.enc.Encode(r)
+}
+
+// Close closes the recording.
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	if w.f == nil {
+		return nil
+	}
+
+	err := w.f.Close()
+	w.f = nil
+
+	return err
+}
+
+// Read reads a recording from the given file.
+func Read(path string) (*Header, []*Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer f.Close()
+
+	hdr, records, err := Decode(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read recording %s: %w", path, err)
+	}
+
+	return hdr, records, nil
+}
+
+// Decode decodes a recording.
+func Decode(r io.Reader) (*Header, []*Record, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	hdr := &Header{}
+	if err := dec.Decode(hdr); err != nil {
+		return nil, nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if hdr.Version != Version {
+		return nil, nil, fmt.Errorf("unsupported recording version %q (expected %q)",
+			hdr.Version, Version)
+	}
+
+	records := []*Record{}
+	for {
+		r := &Record{}
+		if err := dec.Decode(r); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, nil, fmt.Errorf("invalid record #%d: %w", len(records)+1, err)
+		}
+		records = append(records, r)
+	}
+
+	return hdr, records, nil
+}
+
+// Diff compares the responses of a recorded and a replayed request. It
+// returns the differences in a human-readable form, or an empty string
+// if the responses are identical. Updates are compared regardless of
+// their order.
+func Diff(recorded, replayed *Record) string {
+	diff := &strings.Builder{}
+
+	if recorded.Error != replayed.Error {
+		fmt.Fprintf(diff, "error:\n- %q\n+ %q\n", recorded.Error, replayed.Error)
+	}
+
+	diffObjects(diff, "adjustment", recorded.Adjustment, replayed.Adjustment)
+	diffObjects(diff, "updates", sortedUpdates(recorded.Updates), sortedUpdates(replayed.Updates))
+
+	return diff.String()
+}
+
+func sortedUpdates(updates []*api.ContainerUpdate) []*api.ContainerUpdate {
+	if len(updates) == 0 {
+		return nil
+	}
+	sorted := make([]*api.ContainerUpdate, len(updates))
+	copy(sorted, updates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].GetContainerId() < sorted[j].GetContainerId()
+	})
+	return sorted
+}
+
+func diffObjects(diff *strings.Builder, name string, recorded, replayed interface{}) {
+	a, b := dumpLines(recorded), dumpLines(replayed)
+	lines := diffLines(a, b)
+	if lines == nil {
+		return
+	}
+
+	fmt.Fprintf(diff, "%s:\n", name)
+	for _, l := range lines {
+		diff.WriteString(l)
+		diff.WriteByte('\n')
+	}
+}
+
+func dumpLines(obj interface{}) []string {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return []string{fmt.Sprintf("<failed to marshal %T: %v>", obj, err)}
+	}
+	s := strings.TrimSuffix(string(data), "\n")
+	if s == "null" || s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// diffLines returns a line-based diff of a and b, or nil if they are
+// identical. Common lines are prefixed with two spaces, lines only in
+// a with '- ', and lines only in b with '+ '.
+func diffLines(a, b []string) []string {
+	if slices.Equal(a, b) {
+		return nil
+	}
+
+	// longest common subsequence lengths of the suffixes of a and b
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := []string{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "- "+a[i])
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, "+ "+b[j])
+	}
+
+	return lines
+}
@@ -0,0 +1,146 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package record
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/require"
+)
+
+func cpusetUpdate(id, cpus string) *api.ContainerUpdate {
+	u := &api.ContainerUpdate{ContainerId: id}
+	u.SetLinuxCPUSetCPUs(cpus)
+	return u
+}
+
+func TestRecordAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nri.rec")
+
+	w, err := Create(path, &Header{Plugin: "90-test", Policy: "test", StateDir: "/state"})
+	require.NoError(t, err)
+
+	pod := &api.PodSandbox{Id: "pod0", Name: "pod0", Namespace: "default"}
+	ctr := &api.Container{Id: "ctr0", PodSandboxId: "pod0", Name: "ctr0"}
+	adjust := &api.ContainerAdjustment{}
+	adjust.SetLinuxCPUSetCPUs("0-1")
+
+	records := []*Record{
+		{Event: "RunPodSandbox", Pod: pod},
+		{Event: "CreateContainer", Pod: pod, Container: ctr, Adjustment: adjust,
+			Updates: []*api.ContainerUpdate{cpusetUpdate("ctr1", "2-3")}},
+		{Event: "StartContainer", Pod: pod, Container: ctr, Error: "failure"},
+	}
+	for _, r := range records {
+		require.NoError(t, w.Write(r))
+	}
+	require.NoError(t, w.Close())
+	require.Error(t, w.Write(records[0]), "write after close")
+
+	hdr, read, err := Read(path)
+	require.NoError(t, err)
+	require.Equal(t, Version, hdr.Version)
+	require.Equal(t, "90-test", hdr.Plugin)
+	require.Equal(t, "test", hdr.Policy)
+	require.Equal(t, "/state", hdr.StateDir)
+	require.False(t, hdr.Time.IsZero())
+
+	require.Len(t, read, len(records))
+	for i, r := range read {
+		require.Equal(t, records[i].Event, r.Event)
+		require.Equal(t, records[i].Error, r.Error)
+		require.Equal(t, records[i].Pod.GetId(), r.Pod.GetId())
+		require.Equal(t, records[i].Container.GetId(), r.Container.GetId())
+		require.False(t, r.Time.IsZero())
+		require.Empty(t, Diff(records[i], r))
+	}
+	require.Equal(t, "0-1", read[1].Adjustment.GetLinux().GetResources().GetCpu().GetCpus())
+}
+
+func TestReadUnsupportedVersion(t *testing.T) {
+	_, _, err := Decode(strings.NewReader(`{"version":"v0"}` + "\n"))
+	require.ErrorContains(t, err, "unsupported recording version")
+
+	_, _, err = Decode(strings.NewReader(`{"version":"v1"}` + "\n" + `{"event":`))
+	require.ErrorContains(t, err, "invalid record #1")
+}
+
+func TestDiff(t *testing.T) {
+	adjust := func(cpus string) *api.ContainerAdjustment {
+		a := &api.ContainerAdjustment{}
+		a.SetLinuxCPUSetCPUs(cpus)
+		return a
+	}
+
+	recorded := &Record{
+		Event:      "CreateContainer",
+		Adjustment: adjust("0-1"),
+		Updates: []*api.ContainerUpdate{
+			cpusetUpdate("ctr1", "2-3"),
+			cpusetUpdate("ctr2", "4-5"),
+		},
+	}
+
+	same := &Record{
+		Event:      "CreateContainer",
+		Adjustment: adjust("0-1"),
+		Updates: []*api.ContainerUpdate{
+			cpusetUpdate("ctr2", "4-5"),
+			cpusetUpdate("ctr1", "2-3"),
+		},
+	}
+	require.Empty(t, Diff(recorded, same), "updates in different order")
+
+	other := &Record{
+		Event:      "CreateContainer",
+		Adjustment: adjust("0-2"),
+		Updates: []*api.ContainerUpdate{
+			cpusetUpdate("ctr1", "2-3"),
+		},
+		Error: "failure",
+	}
+	require.Equal(t, `error:
+- ""
++ "failure"
+adjustment:
+  linux:
+    resources:
+      cpu:
+-       cpus: 0-1
++       cpus: 0-2
+updates:
+  - container_id: ctr1
+    linux:
+      resources:
+        cpu:
+          cpus: 2-3
+- - container_id: ctr2
+-   linux:
+-     resources:
+-       cpu:
+-         cpus: 4-5
+`, Diff(recorded, other))
+}
+
+func TestDiffLines(t *testing.T) {
+	require.Nil(t, diffLines([]string{"a", "b"}, []string{"a", "b"}))
+	require.Equal(t,
+		[]string{"  a", "- b", "+ x", "  c", "+ d"},
+		diffLines([]string{"a", "b", "c"}, []string{"a", "x", "c", "d"}),
+	)
+}
@@ -16,14 +16,17 @@ package resmgr
 
 import (
 	"fmt"
+	"net/http"
 	"sync"
 
 	//	"time"
 
 	"github.com/containers/nri-plugins/pkg/agent"
+	"github.com/containers/nri-plugins/pkg/cgroupstats"
 	"github.com/containers/nri-plugins/pkg/healthz"
 	"github.com/containers/nri-plugins/pkg/instrumentation"
 	logger "github.com/containers/nri-plugins/pkg/log"
+	"github.com/containers/nri-plugins/pkg/metrics"
 	"github.com/containers/nri-plugins/pkg/pidfile"
 	"github.com/containers/nri-plugins/pkg/resmgr/cache"
 	"github.com/containers/nri-plugins/pkg/resmgr/control"
@@ -59,7 +62,10 @@ type resmgr struct {
 	events  chan interface{} // channel for delivering events
 	stop    chan interface{} // channel for signalling shutdown to goroutines
 	nri     *nriPlugin       // NRI plugins, if we're running as such
+	drift   *driftDetector   // cpuset drift detection
 	running bool
+	replayC chan error   // result of replaying an NRI recording
+	actions *http.Server // server for debugging actions, if enabled
 }
 
 const (
@@ -86,10 +92,34 @@ func NewResourceManager(backend policy.Backend, agt *agent.Agent) (ResourceManag
 		log.Warn("WARNING: this flag will be removed in a future release")
 	}
 
+	switch opt.RestartMode {
+	case RestartModeReallocate:
+		if opt.Stateless {
+			return nil, resmgrError("stateless mode requires restart mode %q",
+				RestartModeAdopt)
+		}
+	case RestartModeAdopt:
+	default:
+		return nil, resmgrError("invalid restart mode %q, expected %q or %q",
+			opt.RestartMode, RestartModeReallocate, RestartModeAdopt)
+	}
+
+	// Without saved state every start would look like a first one, importing
+	// possibly stale kubelet state over the cpusets we have set since.
+	if opt.Stateless && opt.ImportKubelet {
+		return nil, resmgrError("stateless mode can't be used with kubelet state import")
+	}
+
 	m := &resmgr{
 		agent: agt,
 	}
 
+	if err := m.setupReplay(); err != nil {
+		return nil, err
+	}
+
+	agt.SetStateDir(opt.StateDir)
+
 	if err := m.setupCache(); err != nil {
 		return nil, err
 	}
@@ -114,6 +144,10 @@ func NewResourceManager(backend policy.Backend, agt *agent.Agent) (ResourceManag
 	}
 
 	m.setupHealthCheck()
+	m.setupIntrospection()
+	m.setupCgroupStats()
+	m.setupAllocationMetrics()
+	m.setupDriftDetection()
 
 	return m, nil
 }
@@ -125,6 +159,11 @@ func (m *resmgr) Start() error {
 	if err != nil {
 		return err
 	}
+
+	if m.replayC != nil {
+		return m.finishReplay()
+	}
+
 	return nil
 }
 
@@ -191,10 +230,22 @@ func (m *resmgr) start(cfg cfgapi.ResmgrConfig) error {
 		return err
 	}
 
+	m.drift.configure(mCfg.Control.Drift)
+
 	if err := m.startEventProcessing(); err != nil {
 		return err
 	}
 
+	if err := m.startActions(); err != nil {
+		return err
+	}
+
+	if m.replayC != nil {
+		log.Info("up and running, replaying %s", opt.NriReplay)
+		go m.replay()
+		return nil
+	}
+
 	if err := pidfile.Remove(); err != nil {
 		return resmgrError("failed to remove stale/old PID file: %v", err)
 	}
@@ -211,17 +262,40 @@ func (m *resmgr) start(cfg cfgapi.ResmgrConfig) error {
 func (m *resmgr) Stop() {
 	log.Info("shutting down...")
 
+	m.stopActions()
+
+	m.Lock()
+	m.drift.configure(nil)
+	if err := m.cache.Flush(); err != nil {
+		log.Errorf("failed to flush cache: %v", err)
+	}
+	m.nri.stop()
+	m.Unlock()
+
+	// Stop the agent last, without holding the lock, to let Start return.
+	m.agent.Stop()
+}
+
+// flushCache writes any pending save of the cache to disk.
+func (m *resmgr) flushCache() {
 	m.Lock()
 	defer m.Unlock()
 
-	m.nri.stop()
+	if err := m.cache.Flush(); err != nil {
+		log.Errorf("failed to flush cache: %v", err)
+	}
 }
 
 // setupCache creates a cache and reloads its last saved state if found.
 func (m *resmgr) setupCache() error {
 	var err error
 
-	options := cache.Options{CacheDir: opt.StateDir}
+	options := cache.Options{
+		CacheDir:  opt.StateDir,
+		Stateless: opt.Stateless,
+		SaveDelay: opt.StateSaveDelay,
+		Locker:    m.RLocker(),
+	}
 	if m.cache, err = cache.NewCache(options); err != nil {
 		return resmgrError("failed to create cache: %v", err)
 	}
@@ -257,6 +331,21 @@ func (m *resmgr) setupHealthCheck() {
 	healthz.Setup(mux)
 }
 
+// setupCgroupStats registers the cgroup statistics collector for containers.
+func (m *resmgr) setupCgroupStats() {
+	err := metrics.Register(
+		"stats",
+		cgroupstats.NewCollector(m.cache, m.RLocker()),
+		metrics.WithGroup("cgroup"),
+		metrics.WithCollectorOptions(
+			metrics.WithoutNamespace(),
+		),
+	)
+	if err != nil {
+		log.Error("failed to register cgroup/stats collector: %v", err)
+	}
+}
+
 // setupControllers sets up the resource controllers.
 func (m *resmgr) setupControllers() error {
 	var err error
@@ -270,6 +359,11 @@ func (m *resmgr) setupControllers() error {
 
 // startControllers start the resource controllers.
 func (m *resmgr) startControllers() error {
+	if m.replayC != nil {
+		log.Info("not starting resource controllers for replay")
+		return nil
+	}
+
 	cfg := m.cfg.CommonConfig()
 	if err := m.control.StartStopControllers(&cfg.Control); err != nil {
 		return resmgrError("failed to start resource controllers: %v", err)
@@ -295,12 +389,23 @@ func (m *resmgr) reconfigure(cfg cfgapi.ResmgrConfig) error {
 		if err := logger.Configure(&mCfg.Log); err != nil {
 			log.Warnf("failed to configure logger: %v", err)
 		}
+		// Restarting instrumentation stops the metrics gatherer, which waits
+		// for any collection in progress, and collectors take our lock.
 		if err := instrumentation.Reconfigure(&mCfg.Instrumentation); err != nil {
 			return err
 		}
-		if err := m.control.StartStopControllers(&mCfg.Control); err != nil {
-			log.Warnf("failed to restart controllers: %v", err)
+
+		b := blockMetrics()
+		defer b.Done()
+		m.Lock()
+		defer m.Unlock()
+
+		if m.replayC == nil {
+			if err := m.control.StartStopControllers(&mCfg.Control); err != nil {
+				log.Warnf("failed to restart controllers: %v", err)
+			}
 		}
+		m.drift.configure(mCfg.Control.Drift)
 
 		err := m.policy.Reconfigure(cfg.PolicyConfig())
 		if err != nil {
@@ -315,13 +420,12 @@ func (m *resmgr) reconfigure(cfg cfgapi.ResmgrConfig) error {
 		return nil
 	}
 
-	m.Lock()
-	defer m.Unlock()
-
 	log.Infof("activating new configuration...")
 	err := apply(cfg)
 	if err == nil {
+		m.Lock()
 		m.cfg = cfg
+		m.Unlock()
 		return nil
 	}
 
@@ -0,0 +1,40 @@
+// Copyright The NRI Plugins Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestartMode(t *testing.T) {
+	saved := opt
+	defer func() { opt = saved }()
+
+	opt.RestartMode = "keep"
+	_, err := NewResourceManager(nil, nil)
+	require.ErrorContains(t, err, "invalid restart mode")
+
+	opt.RestartMode = RestartModeReallocate
+	opt.Stateless = true
+	_, err = NewResourceManager(nil, nil)
+	require.ErrorContains(t, err, "stateless mode requires restart mode")
+
+	opt.RestartMode = RestartModeAdopt
+	opt.ImportKubelet = true
+	_, err = NewResourceManager(nil, nil)
+	require.ErrorContains(t, err, "can't be used with kubelet state import")
+}
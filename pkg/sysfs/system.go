@@ -181,6 +181,7 @@ type Node interface {
 	Distance() []int
 	DistanceFrom(id idset.ID) int
 	MemoryInfo() (*MemInfo, error)
+	HugePages() ([]*HugePageInfo, error)
 	GetMemoryType() MemoryType
 	HasNormalMemory() bool
 }
@@ -290,6 +291,13 @@ type MemInfo struct {
 	MemUsed  uint64
 }
 
+// HugePageInfo contains data about hugepages of a single size on a NUMA node.
+type HugePageInfo struct {
+	Size  uint64 // page size in bytes
+	Total uint64 // number of pages (nr_hugepages)
+	Free  uint64 // number of free pages (free_hugepages)
+}
+
 // CacheType specifies a cache type.
 type CacheType int
 
@@ -1453,6 +1461,44 @@ func (n *node) MemoryInfo() (*MemInfo, error) {
 	return buf, nil
 }
 
+// HugePages returns hugepage info for the node, sorted by page size.
+func (n *node) HugePages() ([]*HugePageInfo, error) {
+	dir := filepath.Join(n.path, "hugepages")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, sysfsError(dir, "failed to read hugepages: %v", err)
+	}
+
+	pages := []*HugePageInfo{}
+	for _, e := range entries {
+		size, ok := strings.CutPrefix(e.Name(), "hugepages-")
+		if !ok || !strings.HasSuffix(size, "kB") {
+			continue
+		}
+		kB, err := strconv.ParseUint(strings.TrimSuffix(size, "kB"), 10, 64)
+		if err != nil {
+			return nil, sysfsError(dir, "invalid hugepage size %q: %v", e.Name(), err)
+		}
+
+		hp := &HugePageInfo{Size: kB * 1024}
+		path := filepath.Join(dir, e.Name())
+		if _, err := readSysfsEntry(path, "nr_hugepages", &hp.Total); err != nil {
+			return nil, err
+		}
+		if _, err := readSysfsEntry(path, "free_hugepages", &hp.Free); err != nil {
+			return nil, err
+		}
+		pages = append(pages, hp)
+	}
+
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Size < pages[j].Size })
+
+	return pages, nil
+}
+
 // GetMemoryType returns the memory type for this node.
 func (n *node) GetMemoryType() MemoryType {
 	return n.memoryType